@@ -5,7 +5,7 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/gorilla/mux"
+	"github.com/jedielson/bookstore/cmd/web/flags"
 	"github.com/jedielson/bookstore/pkg/api"
 	"github.com/jedielson/bookstore/pkg/database"
 	"github.com/urfave/cli/v2"
@@ -14,35 +14,26 @@ import (
 func Run(c *cli.Context) error {
 
 	fmt.Printf("Starting api...\n")
-	manager := database.NewDbManager("bookstore.db")
-	authorsRepository := database.NewAuthorsRepository(manager)
-	booksRepository := database.NewBooksRepository(manager)
-
-	err := manager.InitDb()
+	keys, err := flags.APIKeys(c)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	database.Migrate(manager.GetDB())
-
+	repos, closeStorage, err := openStorage(c)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	r := mux.NewRouter()
-	api.NewAuthorsApi(r, authorsRepository)
-	api.NewBooksApi(r, booksRepository)
+	if size := c.Int(flags.CacheSizeFlag.Name); size > 0 {
+		repos = database.NewCachedRepos(repos, database.NewCache(size, c.Duration(flags.CacheTTLFlag.Name)))
+	}
 
-	r.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(200)
+	r := api.NewRouter(repos, api.RouterOptions{
+		StrictPreconditions: c.Bool(flags.StrictIfMatchFlag.Name),
+		APIKeys:             keys,
 	})
 
 	log.Fatal(http.ListenAndServe(":8081", r))
 
-	err = manager.Close()
-	if err != nil {
-		panic(err)
-	}
-
-	return nil
+	return closeStorage()
 }
@@ -0,0 +1,32 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/jedielson/bookstore/cmd/web/flags"
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/ucli"
+	"github.com/urfave/cli/v2"
+)
+
+// openStorage returns the repositories selected by the storage flag and a
+// function that releases them.
+func openStorage(c *cli.Context) (database.Repos, func() error, error) {
+	switch storage := c.String(flags.StorageFlag.Name); storage {
+	case flags.StorageMemory:
+		fmt.Printf("Using in-memory storage, data is lost on exit\n")
+		return database.NewMemoryRepos(database.NewMemoryStore()), func() error { return nil }, nil
+
+	case flags.StorageSql:
+		manager := ucli.NewDbManager(c)
+		if err := manager.InitDb(); err != nil {
+			return database.Repos{}, nil, err
+		}
+
+		database.Migrate(manager.GetDB())
+		return database.NewRepos(manager), manager.Close, nil
+
+	default:
+		return database.Repos{}, nil, fmt.Errorf("unknown storage %q, use %s or %s", storage, flags.StorageSql, flags.StorageMemory)
+	}
+}
@@ -1,13 +1,70 @@
 package flags
 
-import "github.com/urfave/cli/v2"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	StorageSql    = "sql"
+	StorageMemory = "memory"
+)
 
 var (
-	SqlDsnFlag = &cli.StringFlag{
-		Name:     "sql-dsn",
-		Usage:    "dsn to use for connecting database",
-		Value:    "lol",
-		EnvVars:  []string{"BOOKSTORE_SQL_DSN"},
+	StorageFlag = &cli.StringFlag{
+		Name:     "storage",
+		Usage:    "where to keep books and authors: sql or memory",
+		Value:    StorageSql,
+		EnvVars:  []string{"BOOKSTORE_STORAGE"},
+		Required: false,
+	}
+
+	CacheSizeFlag = &cli.IntFlag{
+		Name:     "cache-size",
+		Usage:    "how many books and listings to cache, 0 disables the cache",
+		Value:    1000,
+		EnvVars:  []string{"BOOKSTORE_CACHE_SIZE"},
+		Required: false,
+	}
+
+	CacheTTLFlag = &cli.DurationFlag{
+		Name:     "cache-ttl",
+		Usage:    "how long a cached book or listing is served",
+		Value:    10 * time.Second,
+		EnvVars:  []string{"BOOKSTORE_CACHE_TTL"},
+		Required: false,
+	}
+
+	APIKeyFlag = &cli.StringSliceFlag{
+		Name:     "api-key",
+		Usage:    "key=tenant pair granting access to the catalogue of a tenant, may be repeated; without it the X-Tenant-Id header is trusted",
+		EnvVars:  []string{"BOOKSTORE_API_KEYS"},
+		Required: false,
+	}
+
+	StrictIfMatchFlag = &cli.BoolFlag{
+		Name:     "strict-if-match",
+		Usage:    "reject PUT, PATCH and DELETE without an If-Match header with 428",
+		Value:    false,
+		EnvVars:  []string{"BOOKSTORE_STRICT_IF_MATCH"},
 		Required: false,
 	}
 )
+
+// APIKeys parses the key=tenant pairs of the api key flag.
+func APIKeys(c *cli.Context) (map[string]string, error) {
+	keys := map[string]string{}
+	for _, pair := range c.StringSlice(APIKeyFlag.Name) {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return nil, fmt.Errorf("api key %q is invalid, use key=tenant", pair)
+		}
+
+		keys[parts[0]] = parts[1]
+	}
+
+	return keys, nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/jedielson/bookstore/cmd/web/actions"
 	"github.com/jedielson/bookstore/cmd/web/flags"
+	"github.com/jedielson/bookstore/pkg/ucli"
 	"github.com/urfave/cli/v2"
 )
 
@@ -26,9 +27,7 @@ func main() {
 		Usage:   AppUsage,
 		Version: AppVersion,
 		Action:  actions.Run,
-		Flags: []cli.Flag{
-			flags.SqlDsnFlag,
-		},
+		Flags:   append(ucli.SqlFlags, flags.StorageFlag, flags.CacheSizeFlag, flags.CacheTTLFlag, flags.APIKeyFlag, flags.StrictIfMatchFlag),
 	}
 
 	err := app.Run(os.Args)
@@ -0,0 +1,77 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/urfave/cli/v2"
+)
+
+var errFileRequired = errors.New("the backup file is required")
+
+func Backup(c *cli.Context) error {
+	file := c.Args().First()
+	if len(file) == 0 {
+		return errFileRequired
+	}
+
+	manager, err := openDatabase(c)
+	if err != nil {
+		return err
+	}
+	defer manager.Close()
+
+	// write to a temporary file first, so that a failed backup never
+	// replaces a good one
+	tmp := file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	counts, err := database.Backup(tenantContext(c), manager.GetDB(), f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, file); err != nil {
+		return err
+	}
+
+	fmt.Printf("backed up %d authors, %d books and %d links to %s\n", counts.Authors, counts.Books, counts.AuthorBooks, file)
+	return nil
+}
+
+func Restore(c *cli.Context) error {
+	file := c.Args().First()
+	if len(file) == 0 {
+		return errFileRequired
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	manager, err := openDatabase(c)
+	if err != nil {
+		return err
+	}
+	defer manager.Close()
+
+	counts, err := database.Restore(tenantContext(c), manager.GetDB(), f)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("restored %d authors, %d books and %d links from %s\n", counts.Authors, counts.Books, counts.AuthorBooks, file)
+	return nil
+}
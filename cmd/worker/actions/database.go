@@ -0,0 +1,37 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jedielson/bookstore/cmd/worker/flags"
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/ucli"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"github.com/urfave/cli/v2"
+)
+
+func openDatabase(c *cli.Context) (database.DBManager, error) {
+	manager := ucli.NewDbManager(c)
+	if err := manager.InitDb(); err != nil {
+		return nil, err
+	}
+
+	database.Migrate(manager.GetDB())
+	return manager, nil
+}
+
+// ValidateTenant rejects a tenant flag the api would not accept either, before
+// any command runs.
+func ValidateTenant(c *cli.Context) error {
+	if tenant := c.String(flags.TenantFlag.Name); !uctx.ValidTenant(tenant) {
+		return fmt.Errorf("tenant %q is invalid", tenant)
+	}
+
+	return nil
+}
+
+// tenantContext scopes the work of a command to the tenant flag.
+func tenantContext(c *cli.Context) context.Context {
+	return uctx.WithTenant(c.Context, c.String(flags.TenantFlag.Name))
+}
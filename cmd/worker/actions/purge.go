@@ -0,0 +1,39 @@
+package actions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jedielson/bookstore/cmd/worker/flags"
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/urfave/cli/v2"
+)
+
+func Purge(c *cli.Context) error {
+
+	age, err := flags.ParseAge(c.String(flags.OlderThanFlag.Name))
+	if err != nil {
+		return err
+	}
+
+	manager, err := openDatabase(c)
+	if err != nil {
+		return err
+	}
+	defer manager.Close()
+
+	before := time.Now().Add(-age)
+
+	books, err := database.NewBooksRepository(manager).Purge(tenantContext(c), before)
+	if err != nil {
+		return err
+	}
+
+	authors, err := database.NewAuthorsRepository(manager).Purge(tenantContext(c), before)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d books and %d authors deleted before %s\n", books, authors, before.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,89 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jedielson/bookstore/cmd/worker/flags"
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/relay"
+	"github.com/urfave/cli/v2"
+)
+
+func Relay(c *cli.Context) error {
+
+	sink, consumer, err := openSink(c)
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+
+	manager, err := openDatabase(c)
+	if err != nil {
+		return err
+	}
+	defer manager.Close()
+
+	r := &relay.Relay{
+		Outbox:    database.NewOutboxRepository(manager),
+		Sink:      sink,
+		Consumer:  consumer,
+		BatchSize: c.Int(flags.EventBatchSizeFlag.Name),
+	}
+
+	ctx, stop := context.WithCancel(tenantContext(c))
+	defer stop()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	go func() {
+		select {
+		case <-signals:
+			stop()
+		case <-ctx.Done():
+		}
+	}()
+
+	if c.Bool(flags.FollowFlag.Name) {
+		return r.Follow(ctx, c.Duration(flags.PollIntervalFlag.Name))
+	}
+
+	delivered, err := r.Drain(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "relayed %d events to %s\n", delivered, consumer)
+	return nil
+}
+
+// openSink opens the sink of the sink flag and returns it with the name of its
+// consumer.
+func openSink(c *cli.Context) (relay.Sink, string, error) {
+	consumer := c.String(flags.ConsumerFlag.Name)
+
+	switch c.String(flags.SinkFlag.Name) {
+	case flags.SinkStdout:
+		if consumer == "" {
+			consumer = flags.SinkStdout
+		}
+		return relay.NewStdoutSink(), consumer, nil
+
+	case flags.SinkFile:
+		path := c.String(flags.SinkFileFlag.Name)
+		if consumer == "" {
+			consumer = flags.SinkFile + ":" + path
+		}
+
+		sink, err := relay.NewFileSink(path)
+		return sink, consumer, err
+
+	default:
+		return nil, "", fmt.Errorf("sink %q is invalid, use %s or %s", c.String(flags.SinkFlag.Name), flags.SinkStdout, flags.SinkFile)
+	}
+}
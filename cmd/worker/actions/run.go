@@ -2,13 +2,14 @@ package actions
 
 import (
 	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/ucli"
 	"github.com/jedielson/bookstore/pkg/ucsv"
 	"github.com/urfave/cli/v2"
 )
 
 func Run(c *cli.Context) error {
 
-	manager := database.NewDbManager("bookstore.db")
+	manager := ucli.NewDbManager(c)
 	err := manager.InitDb()
 	if err != nil {
 		panic(err)
@@ -22,7 +23,7 @@ func Run(c *cli.Context) error {
 
 	//file := "../../input.csv"
 	file := "./input.csv"
-	ucsv.ReadFile(file, manager)
+	ucsv.ReadFile(tenantContext(c), file, manager)
 
 	err = manager.Close()
 	if err != nil {
@@ -0,0 +1,32 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/jedielson/bookstore/cmd/worker/flags"
+	"github.com/jedielson/bookstore/pkg/useed"
+	"github.com/urfave/cli/v2"
+)
+
+func Seed(c *cli.Context) error {
+
+	manager, err := openDatabase(c)
+	if err != nil {
+		return err
+	}
+	defer manager.Close()
+
+	counts, err := useed.Seed(tenantContext(c), manager.GetDB(), useed.Options{
+		Authors:   c.Int(flags.AuthorsFlag.Name),
+		Books:     c.Int(flags.BooksFlag.Name),
+		Seed:      c.Int64(flags.SeedFlag.Name),
+		BatchSize: c.Int(flags.BatchSizeFlag.Name),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("seeded %d authors and %d books\n", counts.Authors, counts.Books)
+	return nil
+}
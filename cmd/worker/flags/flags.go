@@ -1,13 +1,123 @@
 package flags
 
-import "github.com/urfave/cli/v2"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"github.com/urfave/cli/v2"
+)
+
+var TenantFlag = &cli.StringFlag{
+	Name:     "tenant",
+	Usage:    "tenant whose catalogue the commands work on",
+	Value:    uctx.DefaultTenant,
+	EnvVars:  []string{"BOOKSTORE_TENANT"},
+	Required: false,
+}
+
+var OlderThanFlag = &cli.StringFlag{
+	Name:     "older-than",
+	Usage:    "purge rows deleted longer ago than this age, e.g. 30d or 12h",
+	Value:    "30d",
+	Required: false,
+}
 
 var (
-	SqlDsnFlag = &cli.StringFlag{
-		Name:     "sql-dsn",
-		Usage:    "dsn to use for connecting database",
-		Value:    "lol",
-		EnvVars:  []string{"BOOKSTORE_SQL_DSN"},
+	AuthorsFlag = &cli.IntFlag{
+		Name:     "authors",
+		Usage:    "how many authors to generate",
+		Value:    100,
+		Required: false,
+	}
+
+	BooksFlag = &cli.IntFlag{
+		Name:     "books",
+		Usage:    "how many books to generate",
+		Value:    1000,
+		Required: false,
+	}
+
+	SeedFlag = &cli.Int64Flag{
+		Name:     "seed",
+		Usage:    "seed of the generator, the same seed generates the same data",
+		Value:    1,
+		Required: false,
+	}
+
+	BatchSizeFlag = &cli.IntFlag{
+		Name:     "batch-size",
+		Usage:    "how many rows to insert per statement",
+		Value:    500,
+		Required: false,
+	}
+)
+
+// ParseAge parses a duration that, besides the units of time.ParseDuration,
+// accepts whole days such as "30d".
+func ParseAge(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("age %q is invalid", s)
+		}
+
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	age, err := time.ParseDuration(s)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("age %q is invalid", s)
+	}
+
+	return age, nil
+}
+
+const (
+	SinkStdout = "stdout"
+	SinkFile   = "file"
+)
+
+var (
+	SinkFlag = &cli.StringFlag{
+		Name:     "sink",
+		Usage:    "where to deliver the events: stdout or file",
+		Value:    SinkStdout,
+		Required: false,
+	}
+
+	SinkFileFlag = &cli.StringFlag{
+		Name:     "file",
+		Usage:    "file the file sink appends the events to",
+		Value:    "events.jsonl",
+		Required: false,
+	}
+
+	ConsumerFlag = &cli.StringFlag{
+		Name:     "consumer",
+		Usage:    "name the delivery offset is tracked under, defaults to the sink",
+		Required: false,
+	}
+
+	EventBatchSizeFlag = &cli.IntFlag{
+		Name:     "batch-size",
+		Usage:    "how many events to deliver at once",
+		Value:    100,
+		Required: false,
+	}
+
+	FollowFlag = &cli.BoolFlag{
+		Name:     "follow",
+		Usage:    "keep relaying new events instead of exiting once the outbox is drained",
+		Required: false,
+	}
+
+	PollIntervalFlag = &cli.DurationFlag{
+		Name:     "poll-interval",
+		Usage:    "how often to look for new events when following",
+		Value:    time.Second,
 		Required: false,
 	}
 )
@@ -6,6 +6,7 @@ import (
 
 	"github.com/jedielson/bookstore/cmd/worker/actions"
 	"github.com/jedielson/bookstore/cmd/worker/flags"
+	"github.com/jedielson/bookstore/pkg/ucli"
 	"github.com/urfave/cli/v2"
 )
 
@@ -24,9 +25,54 @@ func main() {
 		Name:    AppName,
 		Usage:   AppUsage,
 		Version: AppVersion,
+		Before:  actions.ValidateTenant,
 		Action:  actions.Run,
-		Flags: []cli.Flag{
-			flags.SqlDsnFlag,
+		Flags:   append(ucli.SqlFlags, flags.TenantFlag),
+		Commands: []*cli.Command{
+			{
+				Name:   "purge",
+				Usage:  "hard-deletes books and authors that are in the trash for too long",
+				Action: actions.Purge,
+				Flags: []cli.Flag{
+					flags.OlderThanFlag,
+				},
+			},
+			{
+				Name:      "backup",
+				Usage:     "writes authors, books and their links to a JSON Lines file",
+				ArgsUsage: "<file>",
+				Action:    actions.Backup,
+			},
+			{
+				Name:      "restore",
+				Usage:     "loads a backup into an empty database",
+				ArgsUsage: "<file>",
+				Action:    actions.Restore,
+			},
+			{
+				Name:   "seed",
+				Usage:  "inserts generated authors and books for load tests and demos",
+				Action: actions.Seed,
+				Flags: []cli.Flag{
+					flags.AuthorsFlag,
+					flags.BooksFlag,
+					flags.SeedFlag,
+					flags.BatchSizeFlag,
+				},
+			},
+			{
+				Name:   "relay",
+				Usage:  "delivers the events of the outbox, in order, to a sink",
+				Action: actions.Relay,
+				Flags: []cli.Flag{
+					flags.SinkFlag,
+					flags.SinkFileFlag,
+					flags.ConsumerFlag,
+					flags.EventBatchSizeFlag,
+					flags.FollowFlag,
+					flags.PollIntervalFlag,
+				},
+			},
 		},
 	}
 
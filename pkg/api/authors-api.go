@@ -1,29 +1,297 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/jedielson/bookstore/pkg/database"
-	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
 	"github.com/jedielson/bookstore/pkg/uweb"
 )
 
 func NewAuthorsApi(r *mux.Router, repository database.AuthorsRepository) {
 
 	r.HandleFunc("/authors", GetAuthors(repository)).Methods("GET")
+	r.HandleFunc("/authors/trash", GetAuthorsTrash(repository)).Methods(http.MethodGet)
+	r.HandleFunc("/authors/{id}", GetAuthor(repository)).Methods(http.MethodGet)
+
+	r.HandleFunc("/authors", CreateAuthor(repository)).Methods(http.MethodPost)
+	r.HandleFunc("/authors/{id}", UpdateAuthor(repository)).Methods(http.MethodPut)
+	r.HandleFunc("/authors/{id}", PatchAuthor(repository)).Methods(http.MethodPatch)
+	r.HandleFunc("/authors/{id}", DeleteAuthor(repository)).Methods(http.MethodDelete)
+	r.HandleFunc("/authors/{id}/restore", RestoreAuthor(repository)).Methods(http.MethodPost)
+	r.HandleFunc("/authors/{id}/merge", MergeAuthor(repository)).Methods(http.MethodPost)
+	r.HandleFunc("/authors/{id}/history", GetAuthorHistory(repository)).Methods(http.MethodGet)
 }
 
 func GetAuthors(repository database.AuthorsRepository) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		name, limit, offset := uweb.BindGetAuthorsRequest(r)
-		authors := repository.GetAll(name, limit, offset)
-		if authors == nil {
-			authors = []domain.Author{}
+		request := uweb.BindGetAuthorsRequest(r)
+
+		p, err := bindPaging(r, request.Limit, request.Offset, request.Query, database.AuthorSortFields)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		request.After = p.After
+		request.Sort = p.Sort
+		authors, err := repository.GetAll(r.Context(), request)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		writePage(w, r, p, NewAuthorViews(authors), len(authors),
+			func() database.Cursor { return database.AuthorCursor(authors[len(authors)-1], p.Sort) },
+			func() (int64, error) { return repository.Count(r.Context(), request) })
+	}
+}
+
+func GetAuthor(repository database.AuthorsRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := uweb.BindAuthorId(r, uweb.Path, IdError)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		author, err := repository.GetAuthor(r.Context(), id)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		uweb.SetETag(w, author.Version)
+		uweb.ToJson(w, NewAuthorView(author))
+	}
+}
+
+func CreateAuthor(repository database.AuthorsRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		author, err := uweb.BindAuthorRequest(r)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		id, err := repository.Create(r.Context(), author)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		created, err := repository.GetAuthor(uctx.WithReadYourWrites(r.Context()), int(id))
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		uweb.SetETag(w, created.Version)
+		uweb.ToCreated(w, fmt.Sprintf("/authors/%d", id), NewAuthorView(created))
+	}
+}
+
+func UpdateAuthor(repository database.AuthorsRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := uweb.BindAuthorId(r, uweb.Path, IdError)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		version, err := uweb.BindIfMatch(r)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		author, err := uweb.BindAuthorRequest(r)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		author.Version = version
+		writeAuthorUpdate(w, r, repository.Update(r.Context(), id, author))
+	}
+}
+
+// PatchAuthor updates the fields given in the body and keeps the others.
+// Without If-Match the author is updated only if it did not change since it
+// was read, so a concurrent write is reported instead of overwritten.
+func PatchAuthor(repository database.AuthorsRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := uweb.BindAuthorId(r, uweb.Path, IdError)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		version, err := uweb.BindIfMatch(r)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		patch, err := uweb.BindAuthorPatch(r)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		author, err := repository.GetAuthor(uctx.WithReadYourWrites(r.Context()), id)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		if version == 0 {
+			version = author.Version
+		}
+
+		if patch.Name != nil {
+			author.Name = strings.TrimSpace(*patch.Name)
+		}
+
+		if err := uweb.Validate(uweb.AuthorRequest{Name: author.Name}); err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		author.Version = version
+		writeAuthorUpdate(w, r, repository.Update(r.Context(), id, author))
+	}
+}
+
+func writeAuthorUpdate(w http.ResponseWriter, r *http.Request, err error) {
+	if err != nil {
+		uweb.ToProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteAuthor rejects authors that still have books with 409, unless the
+// cascade=unlink query removes them from their books first.
+func DeleteAuthor(repository database.AuthorsRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := uweb.BindAuthorId(r, uweb.Path, IdError)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		unlink, err := uweb.BindCascade(r)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		version, err := uweb.BindIfMatch(r)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		writeAuthorUpdate(w, r, repository.Delete(r.Context(), id, version, unlink))
+	}
+}
+
+func GetAuthorsTrash(repository database.AuthorsRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		authors, err := repository.GetTrash(r.Context(), uweb.BindPageRequest(r))
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		uweb.ToJson(w, NewAuthorViews(authors))
+	}
+}
+
+func RestoreAuthor(repository database.AuthorsRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := uweb.BindAuthorId(r, uweb.Path, IdError)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		err = repository.Restore(r.Context(), id)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		restored, err := repository.GetAuthor(uctx.WithReadYourWrites(r.Context()), id)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		uweb.SetETag(w, restored.Version)
+		uweb.ToJson(w, NewAuthorView(restored))
+	}
+}
+
+// MergeAuthor hands the books of the author over to the author named in the
+// body and deletes it.
+func MergeAuthor(repository database.AuthorsRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := uweb.BindAuthorId(r, uweb.Path, IdError)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		into, err := uweb.BindMergeRequest(r)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		writeAuthorUpdate(w, r, repository.Merge(r.Context(), id, into))
+	}
+}
+
+func GetAuthorHistory(repository database.AuthorsRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := uweb.BindAuthorId(r, uweb.Path, IdError)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		entries, err := repository.GetHistory(r.Context(), id)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
 		}
 
-		uweb.ToJson(w, authors)
+		uweb.ToJson(w, NewHistoryViews(entries))
 	}
 }
@@ -3,16 +3,20 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
 	"github.com/jedielson/bookstore/pkg/database"
 	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uweb"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
 )
 
 type AuthorsApiHandlerSuite struct {
@@ -45,12 +49,30 @@ func (s *AuthorsApiHandlerSuite) TestIfUrlInvalidShouldReturn404() {
 	s.Assert().Equal(http.StatusNotFound, s.res.Code)
 }
 
+func (s *AuthorsApiHandlerSuite) TestShouldReturn500IfListingFails() {
+
+	// arrange
+	s.repo.
+		On("GetAll", mock.Anything, mock.Anything).
+		Return(nil, errors.New("database is locked"))
+
+	s.req = httptest.NewRequest(http.MethodGet, "/authors", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusInternalServerError, s.res.Code)
+	s.Assert().Equal(uweb.ProblemContentType, s.res.Header().Get("Content-Type"))
+}
+
 func (s *AuthorsApiHandlerSuite) TestShouldReturn200IfReturnedNil() {
 
 	// arrange
 	s.repo.
-		On("GetAll", mock.Anything, mock.Anything, mock.Anything).
-		Return(nil)
+		On("GetAll", mock.Anything, mock.Anything).
+		Return(nil, nil)
 
 	s.req = httptest.NewRequest(http.MethodGet, "/authors", nil)
 
@@ -62,15 +84,15 @@ func (s *AuthorsApiHandlerSuite) TestShouldReturn200IfReturnedNil() {
 
 	s.repo.AssertExpectations(s.T())
 	s.Assert().Equal(http.StatusOK, s.res.Code)
-	s.Assert().JSONEq("[]", result)
+	s.Assert().JSONEq(`{"data": [], "limit": 1000, "offset": 0, "links": {}}`, result)
 }
 
 func (s *AuthorsApiHandlerSuite) TestShouldReturn200IfNotReturnedData() {
 
 	// arrange
 	s.repo.
-		On("GetAll", mock.Anything, mock.Anything, mock.Anything).
-		Return([]domain.Author{})
+		On("GetAll", mock.Anything, mock.Anything).
+		Return([]domain.Author{}, nil)
 
 	s.req = httptest.NewRequest(http.MethodGet, "/authors", nil)
 
@@ -82,7 +104,7 @@ func (s *AuthorsApiHandlerSuite) TestShouldReturn200IfNotReturnedData() {
 
 	s.repo.AssertExpectations(s.T())
 	s.Assert().Equal(http.StatusOK, s.res.Code)
-	s.Assert().JSONEq("[]", result)
+	s.Assert().JSONEq(`{"data": [], "limit": 1000, "offset": 0, "links": {}}`, result)
 }
 
 func (s *AuthorsApiHandlerSuite) TestShouldReturn200IfReturnedData() {
@@ -96,8 +118,8 @@ func (s *AuthorsApiHandlerSuite) TestShouldReturn200IfReturnedData() {
 		}}
 
 	s.repo.
-		On("GetAll", mock.Anything, mock.Anything, mock.Anything).
-		Return(authors)
+		On("GetAll", mock.Anything, mock.Anything).
+		Return(authors, nil)
 
 	s.req = httptest.NewRequest(http.MethodGet, "/authors", nil)
 
@@ -105,7 +127,202 @@ func (s *AuthorsApiHandlerSuite) TestShouldReturn200IfReturnedData() {
 	s.router.ServeHTTP(s.res, s.req)
 
 	// assert
-	var result []domain.Author
+	var result struct{ Data []AuthorView }
+	err := json.Unmarshal(s.res.Body.Bytes(), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal(NewAuthorViews(authors), result.Data)
+}
+
+func (s *AuthorsApiHandlerSuite) TestShouldReturnNextCursorIfPageIsFull() {
+
+	// arrange
+	authors := []domain.Author{{Name: "Teste"}}
+	authors[0].ID = 7
+
+	s.repo.
+		On("GetAll", mock.Anything, mock.Anything).
+		Return(authors, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/authors?limit=1", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal(database.AuthorCursor(authors[0], nil).String(), s.res.Header().Get(uweb.NextCursorHeader))
+}
+
+func (s *AuthorsApiHandlerSuite) TestShouldPassTheSortToRepository() {
+
+	// arrange
+	s.repo.
+		On("GetAll", mock.Anything, mock.MatchedBy(func(r database.GetAuthorsRequest) bool {
+			return r.Sort.String() == "-name"
+		})).
+		Return([]domain.Author{}, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/authors?sort=-name", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+}
+
+func (s *AuthorsApiHandlerSuite) TestShouldReturn400IfSortIsNotAllowed() {
+
+	// arrange
+	s.req = httptest.NewRequest(http.MethodGet, "/authors?sort=publication_year", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "GetAll", mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
+}
+
+func (s *AuthorsApiHandlerSuite) TestShouldReturn400IfCursorIsInvalid() {
+
+	// arrange
+	s.req = httptest.NewRequest(http.MethodGet, "/authors?after=notACursor", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "GetAll", mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
+}
+
+func (s *AuthorsApiHandlerSuite) TestGetAuthorsTrashShouldReturn200() {
+
+	// arrange
+	s.repo.
+		On("GetTrash", mock.Anything, database.PageRequest{Limit: 1000, Offset: 0}).
+		Return(nil, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/authors/trash", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().JSONEq("[]", s.res.Body.String())
+}
+
+func (s *AuthorsApiHandlerSuite) TestRestoreAuthorShouldReturn200WithTheAuthor() {
+
+	// arrange
+	author := domain.Author{Name: "Restored", Version: 3}
+	author.ID = 1
+
+	s.repo.
+		On("Restore", mock.Anything, 1).
+		Return(nil)
+	s.repo.
+		On("GetAuthor", mock.Anything, 1).
+		Return(author, nil)
+
+	s.req = httptest.NewRequest(http.MethodPost, "/authors/1/restore", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result AuthorView
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &result))
+
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal(`"3"`, s.res.Header().Get(uweb.ETagHeader))
+	s.Assert().Equal(NewAuthorView(author), result)
+}
+
+func (s *AuthorsApiHandlerSuite) TestRestoreAuthorShouldReturn404IfNotInTrash() {
+
+	// arrange
+	s.repo.
+		On("Restore", mock.Anything, 1).
+		Return(gorm.ErrRecordNotFound)
+
+	s.req = httptest.NewRequest(http.MethodPost, "/authors/1/restore", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNotFound, s.res.Code)
+}
+
+func (s *AuthorsApiHandlerSuite) TestMergeAuthorShouldReturn204() {
+
+	// arrange
+	s.repo.
+		On("Merge", mock.Anything, 1, 2).
+		Return(nil)
+
+	s.req = httptest.NewRequest(http.MethodPost, "/authors/1/merge", strings.NewReader(`{"into": 2}`))
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNoContent, s.res.Code)
+}
+
+func (s *AuthorsApiHandlerSuite) TestMergeAuthorShouldReturn422IfIntoIsMissingOrItself() {
+
+	// arrange
+	s.repo.
+		On("Merge", mock.Anything, 1, 1).
+		Return(database.ErrMergeIntoItself)
+
+	for _, body := range []string{`{}`, `{"into": 1}`} {
+		s.res = httptest.NewRecorder()
+		s.req = httptest.NewRequest(http.MethodPost, "/authors/1/merge", strings.NewReader(body))
+
+		// act
+		s.router.ServeHTTP(s.res, s.req)
+
+		// assert
+		s.Assert().Equal(http.StatusUnprocessableEntity, s.res.Code, body)
+	}
+	s.repo.AssertNumberOfCalls(s.T(), "Merge", 1)
+}
+
+func (s *AuthorsApiHandlerSuite) TestGetAuthorHistoryShouldReturn200() {
+
+	// arrange
+	s.repo.
+		On("GetHistory", mock.Anything, 1).
+		Return([]domain.History{
+			{
+				Action: database.ActionDelete,
+				Before: `{"Name":"Teste"}`,
+			},
+		}, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/authors/1/history", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result []HistoryView
 	err := json.Unmarshal(s.res.Body.Bytes(), &result)
 	if err != nil {
 		log.Fatal(err)
@@ -113,7 +330,239 @@ func (s *AuthorsApiHandlerSuite) TestShouldReturn200IfReturnedData() {
 
 	s.repo.AssertExpectations(s.T())
 	s.Assert().Equal(http.StatusOK, s.res.Code)
-	s.Assert().Equal(authors, result)
+	s.Require().Len(result, 1)
+	s.Assert().Equal([]FieldChange{{Field: "Name", Before: "Teste", After: nil}}, result[0].Changes)
+}
+
+func (s *AuthorsApiHandlerSuite) TestGetAuthorShouldReturn200WithETag() {
+
+	// arrange
+	author := domain.Author{Name: "Brian Kernighan", Version: 3}
+	author.ID = 1
+
+	s.repo.
+		On("GetAuthor", mock.Anything, 1).
+		Return(author, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/authors/1", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result AuthorView
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &result))
+
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal(`"3"`, s.res.Header().Get(uweb.ETagHeader))
+	s.Assert().Equal(NewAuthorView(author), result)
+}
+
+func (s *AuthorsApiHandlerSuite) TestGetAuthorShouldReturn404IfNotFound() {
+
+	// arrange
+	s.repo.
+		On("GetAuthor", mock.Anything, 1).
+		Return(nil, gorm.ErrRecordNotFound)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/authors/1", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNotFound, s.res.Code)
+}
+
+func (s *AuthorsApiHandlerSuite) TestCreateAuthorShouldReturn201WithLocation() {
+
+	// arrange
+	s.repo.
+		On("Create", mock.Anything, domain.Author{Name: "Brian Kernighan"}).
+		Return(uint(7), nil)
+
+	created := domain.Author{Name: "Brian Kernighan", Version: 1}
+	created.ID = 7
+	s.repo.
+		On("GetAuthor", mock.Anything, 7).
+		Return(created, nil)
+
+	s.req = httptest.NewRequest(http.MethodPost, "/authors", strings.NewReader(`{"Name": " Brian Kernighan "}`))
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result AuthorView
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &result))
+
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusCreated, s.res.Code)
+	s.Assert().Equal("/authors/7", s.res.Header().Get("Location"))
+	s.Assert().Equal(`"1"`, s.res.Header().Get(uweb.ETagHeader))
+	s.Assert().Equal(NewAuthorView(created), result)
+}
+
+func (s *AuthorsApiHandlerSuite) TestCreateAuthorShouldReturn422IfNameIsMissing() {
+
+	// arrange
+	s.req = httptest.NewRequest(http.MethodPost, "/authors", strings.NewReader(`{"Name": "  "}`))
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var problem uweb.Problem
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &problem))
+
+	s.repo.AssertNotCalled(s.T(), "Create", mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusUnprocessableEntity, s.res.Code)
+	s.Assert().Equal(uweb.CodeValidationFailed, problem.Code)
+	s.Assert().Equal([]uweb.FieldError{{Field: "Name", Code: uweb.RuleRequired, Message: "Name is required"}}, problem.Errors)
+}
+
+func (s *AuthorsApiHandlerSuite) TestUpdateAuthorShouldReturn204() {
+
+	// arrange
+	s.repo.
+		On("Update", mock.Anything, 1, domain.Author{Name: "Brian Kernighan", Version: 2}).
+		Return(nil)
+
+	s.req = httptest.NewRequest(http.MethodPut, "/authors/1", strings.NewReader(`{"Name": "Brian Kernighan"}`))
+	s.req.Header.Set(uweb.IfMatchHeader, `"2"`)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNoContent, s.res.Code)
+}
+
+func (s *AuthorsApiHandlerSuite) TestUpdateAuthorShouldReturn412IfVersionChanged() {
+
+	// arrange
+	s.repo.
+		On("Update", mock.Anything, 1, mock.Anything).
+		Return(database.ErrVersionMismatch)
+
+	s.req = httptest.NewRequest(http.MethodPut, "/authors/1", strings.NewReader(`{"Name": "Brian Kernighan"}`))
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusPreconditionFailed, s.res.Code)
+}
+
+func (s *AuthorsApiHandlerSuite) TestUpdateAuthorShouldReturn404IfNotFound() {
+
+	// arrange
+	s.repo.
+		On("Update", mock.Anything, 1, mock.Anything).
+		Return(gorm.ErrRecordNotFound)
+
+	s.req = httptest.NewRequest(http.MethodPut, "/authors/1", strings.NewReader(`{"Name": "Brian Kernighan"}`))
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNotFound, s.res.Code)
+}
+
+func (s *AuthorsApiHandlerSuite) TestPatchAuthorShouldKeepMissingFieldsAndTheReadVersion() {
+
+	// arrange
+	author := domain.Author{Name: "Brian Kernigan", Version: 4}
+	author.ID = 1
+
+	renamed := author
+	renamed.Name = "Brian Kernighan"
+
+	s.repo.
+		On("GetAuthor", mock.Anything, 1).
+		Return(author, nil)
+	s.repo.
+		On("Update", mock.Anything, 1, renamed).
+		Return(nil)
+
+	s.req = httptest.NewRequest(http.MethodPatch, "/authors/1", strings.NewReader(`{"Name": "Brian Kernighan"}`))
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNoContent, s.res.Code)
+}
+
+func (s *AuthorsApiHandlerSuite) TestPatchAuthorShouldReturn404IfNotFound() {
+
+	// arrange
+	s.repo.
+		On("GetAuthor", mock.Anything, 1).
+		Return(nil, gorm.ErrRecordNotFound)
+
+	s.req = httptest.NewRequest(http.MethodPatch, "/authors/1", strings.NewReader(`{}`))
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "Update", mock.Anything, mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusNotFound, s.res.Code)
+}
+
+func (s *AuthorsApiHandlerSuite) TestDeleteAuthorShouldReturn409IfItHasBooks() {
+
+	// arrange
+	s.repo.
+		On("Delete", mock.Anything, 1, uint(0), false).
+		Return(database.ErrAuthorHasBooks)
+
+	s.req = httptest.NewRequest(http.MethodDelete, "/authors/1", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusConflict, s.res.Code)
+}
+
+func (s *AuthorsApiHandlerSuite) TestDeleteAuthorShouldUnlinkOnCascade() {
+
+	// arrange
+	s.repo.
+		On("Delete", mock.Anything, 1, uint(0), true).
+		Return(nil)
+
+	s.req = httptest.NewRequest(http.MethodDelete, "/authors/1?cascade=unlink", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNoContent, s.res.Code)
+}
+
+func (s *AuthorsApiHandlerSuite) TestDeleteAuthorShouldReturn400IfCascadeIsInvalid() {
+
+	// arrange
+	s.req = httptest.NewRequest(http.MethodDelete, "/authors/1?cascade=books", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
 }
 
 func TestAuthorsApiHandlerSuite(t *testing.T) {
@@ -1,75 +1,109 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/jedielson/bookstore/pkg/database"
-	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
 	"github.com/jedielson/bookstore/pkg/uweb"
 )
 
 func NewBooksApi(r *mux.Router, repository database.BooksRepository) {
 
 	r.HandleFunc("/books", GetBooks(repository)).Methods(http.MethodGet)
+	r.HandleFunc("/books/trash", GetBooksTrash(repository)).Methods(http.MethodGet)
 	r.HandleFunc("/books/{id}", GetBook(repository)).Methods(http.MethodGet)
 
 	r.HandleFunc("/books", CreateBook(repository)).Methods(http.MethodPost)
 	r.HandleFunc("/books/{id}", UpdateBook(repository)).Methods(http.MethodPut)
+	r.HandleFunc("/books/{id}", PatchBook(repository)).Methods(http.MethodPatch)
 	r.HandleFunc("/books/{id}", DeleteBook(repository)).Methods(http.MethodDelete)
+	r.HandleFunc("/books/{id}/restore", RestoreBook(repository)).Methods(http.MethodPost)
+	r.HandleFunc("/books/{id}/history", GetBookHistory(repository)).Methods(http.MethodGet)
 }
 
 func GetBooks(repository database.BooksRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		getAllRequest := uweb.BindGetBooksRequest(r)
+		listBooks(w, r, repository, uweb.BindGetBooksRequest(r))
+	}
+}
 
-		books := repository.GetAll(getAllRequest)
-		if books == nil {
-			books = []domain.Book{}
-		}
+// listBooks writes a page of the books matching getAllRequest, after the
+// cursor of the request if any.
+func listBooks(w http.ResponseWriter, r *http.Request, repository database.BooksRepository, getAllRequest database.GetAllRequest) {
+	p, err := bindPaging(r, getAllRequest.Limit, getAllRequest.Offset, getAllRequest.Query, database.BookSortFields)
+	if err != nil {
+		uweb.ToProblem(w, r, err)
+		return
+	}
 
-		uweb.ToJson(w, books)
+	getAllRequest.After = p.After
+	getAllRequest.Sort = p.Sort
+	books, err := repository.GetAll(r.Context(), getAllRequest)
+	if err != nil {
+		uweb.ToProblem(w, r, err)
+		return
 	}
+
+	writePage(w, r, p, NewBookViews(books), len(books),
+		func() database.Cursor { return database.BookCursor(books[len(books)-1], p.Sort) },
+		func() (int64, error) { return repository.Count(r.Context(), getAllRequest) })
 }
 
 const IdError = "id is invalid"
 
+const CursorError = "after can not be combined with q unless sort is given"
+
+const CursorSortError = "after points into a listing with another sort"
+
 func GetBook(repository database.BooksRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		id, err := uweb.BindBookId(r, uweb.Path, IdError)
 		if err != nil {
-			uweb.ToJson(w, nil, err)
+			uweb.ToProblem(w, r, err)
 			return
 		}
 
-		book, err := repository.GetBook(id)
+		book, err := repository.GetBook(r.Context(), id, uweb.BindExpand(r))
 		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
+			uweb.ToProblem(w, r, err)
 			return
 		}
 
-		uweb.ToJson(w, book)
+		uweb.SetETag(w, book.Version)
+		uweb.ToJson(w, NewBookView(book))
 	}
 }
 
+// CreateBook answers 201 with the Location and the representation of the
+// created book, read back with its authors.
 func CreateBook(repository database.BooksRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		book, errors := uweb.BindCreateBookRequest(r)
+		book, err := uweb.BindCreateBookRequest(r)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
 
-		if errors != nil {
-			uweb.ToJson(w, nil, errors)
+		id, err := repository.Create(r.Context(), book)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
 			return
 		}
 
-		id, err := repository.Create(book)
+		created, err := repository.GetBook(uctx.WithReadYourWrites(r.Context()), int(id), database.ExpandAuthors)
 		if err != nil {
-			uweb.ToJson(w, nil, err)
+			uweb.ToProblem(w, r, err)
+			return
 		}
 
-		uweb.ToJson(w, id)
+		uweb.SetETag(w, created.Version)
+		uweb.ToCreated(w, fmt.Sprintf("/books/%d", id), NewBookView(created))
 	}
 }
 
@@ -78,23 +112,76 @@ func UpdateBook(repository database.BooksRepository) http.HandlerFunc {
 
 		id, err := uweb.BindBookId(r, uweb.Path, IdError)
 		if err != nil {
-			uweb.ToJson(w, nil, err)
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		version, err := uweb.BindIfMatch(r)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		book, err := uweb.BindUpdateBookRequest(r)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		book.Version = version
+		err = repository.Update(r.Context(), id, book)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
 			return
 		}
 
-		book, errors := uweb.BindCreateBookRequest(r)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// PatchBook changes a book with a merge patch or a JSON Patch and validates
+// the result before saving it. Like PatchAuthor, without If-Match the book is
+// updated only if it did not change since it was read.
+func PatchBook(repository database.BooksRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(uweb.AcceptPatchHeader, uweb.AcceptPatch)
+
+		id, err := uweb.BindBookId(r, uweb.Path, IdError)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
 
-		if errors != nil {
-			uweb.ToJson(w, nil, errors)
+		version, err := uweb.BindIfMatch(r)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
 		}
 
-		err = repository.Update(id, book)
+		current, err := repository.GetBook(uctx.WithReadYourWrites(r.Context()), id, database.ExpandNone)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
 
+		book, err := uweb.BindBookPatch(r, current)
 		if err != nil {
-			uweb.ToJson(w, nil, err)
+			uweb.ToProblem(w, r, err)
+			return
 		}
 
-		uweb.ToJson(w, id)
+		if version == 0 {
+			version = current.Version
+		}
+
+		book.Version = version
+		err = repository.Update(r.Context(), id, book)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
@@ -102,17 +189,78 @@ func DeleteBook(repository database.BooksRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := uweb.BindBookId(r, uweb.Path, IdError)
 		if err != nil {
-			uweb.ToJson(w, nil, err)
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		version, err := uweb.BindIfMatch(r)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
 			return
 		}
 
-		err = repository.Delete(id)
+		err = repository.Delete(r.Context(), id, version)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func GetBooksTrash(repository database.BooksRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 
+		books, err := repository.GetTrash(r.Context(), uweb.BindPageRequest(r))
 		if err != nil {
-			uweb.ToJson(w, nil, err)
+			uweb.ToProblem(w, r, err)
+			return
 		}
 
-		uweb.ToJson(w, id)
+		uweb.ToJson(w, NewBookViews(books))
+	}
+}
+
+func RestoreBook(repository database.BooksRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uweb.BindBookId(r, uweb.Path, IdError)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		err = repository.Restore(r.Context(), id)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		restored, err := repository.GetBook(uctx.WithReadYourWrites(r.Context()), id, database.ExpandAuthors)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		uweb.SetETag(w, restored.Version)
+		uweb.ToJson(w, NewBookView(restored))
+	}
+}
+
+func GetBookHistory(repository database.BooksRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uweb.BindBookId(r, uweb.Path, IdError)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		entries, err := repository.GetHistory(r.Context(), id)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
 
+		uweb.ToJson(w, NewHistoryViews(entries))
 	}
 }
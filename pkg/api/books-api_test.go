@@ -3,18 +3,23 @@ package api
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/jedielson/bookstore/pkg/database"
 	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uweb"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
 )
 
 type BooksApiHandlerSuite struct {
@@ -56,11 +61,29 @@ func (s *BooksApiHandlerSuite) TestIfUrlInvalidShouldReturn404() {
 	s.Assert().Equal(http.StatusNotFound, s.res.Code)
 }
 
+func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn500IfListingFails() {
+	// arrange
+	s.repo.
+		On("GetAll", mock.Anything, mock.Anything).
+		Return(nil, errors.New("database is locked"))
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.repo.AssertNotCalled(s.T(), "Count", mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusInternalServerError, s.res.Code)
+	s.Assert().Equal(uweb.ProblemContentType, s.res.Header().Get("Content-Type"))
+}
+
 func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn200IfReturnedNil() {
 	// arrange
 	s.repo.
-		On("GetAll", mock.Anything).
-		Return(nil)
+		On("GetAll", mock.Anything, mock.Anything).
+		Return(nil, nil)
 
 	s.req = httptest.NewRequest(http.MethodGet, "/books", nil)
 
@@ -72,15 +95,15 @@ func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn200IfReturnedNil() {
 
 	s.repo.AssertExpectations(s.T())
 	s.Assert().Equal(http.StatusOK, s.res.Code)
-	s.Assert().JSONEq("[]", result)
+	s.Assert().JSONEq(`{"data": [], "limit": 1000, "offset": 0, "links": {}}`, result)
 }
 
 func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn200IfNotReturnedData() {
 
 	// arrange
 	s.repo.
-		On("GetAll", mock.Anything, mock.Anything, mock.Anything).
-		Return([]domain.Book{})
+		On("GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]domain.Book{}, nil)
 
 	s.req = httptest.NewRequest(http.MethodGet, "/books", nil)
 
@@ -92,7 +115,7 @@ func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn200IfNotReturnedData() {
 
 	s.repo.AssertExpectations(s.T())
 	s.Assert().Equal(http.StatusOK, s.res.Code)
-	s.Assert().JSONEq("[]", result)
+	s.Assert().JSONEq(`{"data": [], "limit": 1000, "offset": 0, "links": {}}`, result)
 }
 
 func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn200IfReturnedData() {
@@ -110,8 +133,8 @@ func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn200IfReturnedData() {
 		}}
 
 	s.repo.
-		On("GetAll", mock.Anything, mock.Anything, mock.Anything).
-		Return(books)
+		On("GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(books, nil)
 
 	s.req = httptest.NewRequest(http.MethodGet, "/books", nil)
 
@@ -119,7 +142,7 @@ func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn200IfReturnedData() {
 	s.router.ServeHTTP(s.res, s.req)
 
 	// assert
-	var result []domain.Book
+	var result struct{ Data []domain.Book }
 	err := json.Unmarshal(s.res.Body.Bytes(), &result)
 	if err != nil {
 		log.Fatal(err)
@@ -127,14 +150,248 @@ func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn200IfReturnedData() {
 
 	s.repo.AssertExpectations(s.T())
 	s.Assert().Equal(http.StatusOK, s.res.Code)
-	s.Assert().Equal(books, result)
+	s.Assert().Equal(books, result.Data)
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksShouldReturnNextCursorIfPageIsFull() {
+	// arrange
+	books := []domain.Book{{Name: "Book 1"}, {Name: "Book 2"}}
+	books[1].ID = 2
+
+	s.repo.
+		On("GetAll", mock.Anything, mock.Anything).
+		Return(books, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books?limit=2", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal(database.BookCursor(books[1], nil).String(), s.res.Header().Get(uweb.NextCursorHeader))
+
+	var page uweb.Page
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &page))
+	s.Assert().Equal(database.BookCursor(books[1], nil).String(), page.NextCursor)
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksShouldLinkTheNextAndPreviousPages() {
+	// arrange
+	s.repo.
+		On("GetAll", mock.Anything, mock.MatchedBy(func(r database.GetAllRequest) bool { return r.Limit == 2 && r.Offset == 3 })).
+		Return([]domain.Book{{Name: "Book 4"}, {Name: "Book 5"}}, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books?edition=1&limit=2&offset=3", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result uweb.Page
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &result))
+
+	s.repo.AssertExpectations(s.T())
+	s.repo.AssertNotCalled(s.T(), "Count", mock.Anything, mock.Anything)
+	s.Assert().Nil(result.Total)
+	s.Assert().Equal(2, result.Limit)
+	s.Assert().Equal(3, *result.Offset)
+	s.Assert().Equal(uweb.PageLinks{Next: "/books?edition=1&limit=2&offset=5", Prev: "/books?edition=1&limit=2&offset=1"}, result.Links)
+	s.Assert().Equal(`</books?edition=1&limit=2&offset=5>; rel="next", </books?edition=1&limit=2&offset=1>; rel="prev"`, s.res.Header().Get(uweb.LinkHeader))
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksShouldCountTheTotalOnDemand() {
+	// arrange
+	s.repo.
+		On("GetAll", mock.Anything, mock.Anything).
+		Return([]domain.Book{{Name: "Book 1"}, {Name: "Book 2"}}, nil)
+	s.repo.
+		On("Count", mock.Anything, mock.MatchedBy(func(r database.GetAllRequest) bool { return r.Edition == "1" })).
+		Return(int64(2), nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books?edition=1&limit=2&total=true", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result uweb.Page
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &result))
+
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(int64(2), *result.Total)
+	s.Assert().Empty(result.Links.Next)
+	s.Assert().Empty(s.res.Header().Get(uweb.LinkHeader))
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksShouldLinkTheNextPageAfterTheCursor() {
+	// arrange
+	cursor := database.Cursor{Keys: []interface{}{"Book 1"}, ID: 1}
+	books := []domain.Book{{Name: "Book 2"}}
+	books[0].ID = 2
+
+	s.repo.
+		On("GetAll", mock.Anything, mock.Anything).
+		Return(books, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books?limit=1&after="+cursor.String(), nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result uweb.Page
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &result))
+
+	s.Assert().Nil(result.Offset)
+	s.Assert().Equal(cursor.String(), result.Cursor)
+	s.Assert().Equal("/books?after="+database.BookCursor(books[0], nil).String()+"&limit=1", result.Links.Next)
+	s.Assert().Empty(result.Links.Prev)
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn400IfTotalIsInvalid() {
+	s.req = httptest.NewRequest(http.MethodGet, "/books?total=maybe", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "GetAll", mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksShouldNotReturnNextCursorOnLastPage() {
+	// arrange
+	s.repo.
+		On("GetAll", mock.Anything, mock.Anything).
+		Return([]domain.Book{{Name: "Book 1"}}, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books?limit=2", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Empty(s.res.Header().Get(uweb.NextCursorHeader))
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksShouldPassCursorToRepository() {
+	// arrange
+	cursor := database.BookCursor(domain.Book{Name: "Book 1"}, nil)
+	cursor.ID = 1
+
+	s.repo.
+		On("GetAll", mock.Anything, mock.MatchedBy(func(r database.GetAllRequest) bool {
+			return r.After != nil && r.After.ID == cursor.ID && r.After.Keys[0] == cursor.Keys[0]
+		})).
+		Return([]domain.Book{}, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books?after="+cursor.String(), nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn400IfCursorIsInvalid() {
+	s.req = httptest.NewRequest(http.MethodGet, "/books?after=notACursor", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "GetAll", mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn400IfCursorKeysAreNotScalars() {
+	after := base64.RawURLEncoding.EncodeToString([]byte(`{"k":[{"x":1}],"id":1}`))
+	s.req = httptest.NewRequest(http.MethodGet, "/books?after="+after, nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "GetAll", mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksShouldPassTheSortAndCursorToRepository() {
+	// arrange
+	sort := database.Sort{{Field: "publication_year", Desc: true}, {Field: "name"}}
+	last := domain.Book{Name: "Book 2", PublicationYear: 2015}
+	last.ID = 2
+	cursor := database.BookCursor(domain.Book{Name: "Book 1", PublicationYear: 2020}, sort)
+	cursor.ID = 1
+
+	s.repo.
+		On("GetAll", mock.Anything, mock.MatchedBy(func(r database.GetAllRequest) bool {
+			return r.Sort.String() == sort.String() && r.After != nil && r.After.ID == cursor.ID
+		})).
+		Return([]domain.Book{last}, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books?q=book&limit=1&sort=-publication_year,name&after="+cursor.String(), nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal(database.BookCursor(last, sort).String(), s.res.Header().Get(uweb.NextCursorHeader))
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn400IfSortIsNotAllowed() {
+	for _, sort := range []string{"id", "-name,name", "name,,edition"} {
+		// arrange
+		s.res = httptest.NewRecorder()
+		s.req = httptest.NewRequest(http.MethodGet, "/books?sort="+sort, nil)
+
+		// act
+		s.router.ServeHTTP(s.res, s.req)
+
+		// assert
+		s.repo.AssertNotCalled(s.T(), "GetAll", mock.Anything, mock.Anything)
+		s.Assert().Equal(http.StatusBadRequest, s.res.Code, sort)
+	}
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn400IfCursorIsOfAnotherSort() {
+	cursor := database.BookCursor(domain.Book{Name: "Book 1"}, nil)
+	cursor.ID = 1
+	s.req = httptest.NewRequest(http.MethodGet, "/books?sort=-name&after="+cursor.String(), nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "GetAll", mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksShouldReturn400IfCursorIsCombinedWithSearch() {
+	cursor := database.BookCursor(domain.Book{Name: "Book 1"}, nil)
+	cursor.ID = 1
+	s.req = httptest.NewRequest(http.MethodGet, "/books?q=book&after="+cursor.String(), nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "GetAll", mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
 }
 
 func (s *BooksApiHandlerSuite) TestGetBookShouldReturn404IfBookDoesNotExist() {
 	// arrange
 	s.repo.
-		On("GetBook", mock.Anything).
-		Return(nil, errors.New("Book not found"))
+		On("GetBook", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, gorm.ErrRecordNotFound)
 
 	s.req = httptest.NewRequest(http.MethodGet, "/books/1", nil)
 	vars := map[string]string{
@@ -149,13 +406,14 @@ func (s *BooksApiHandlerSuite) TestGetBookShouldReturn404IfBookDoesNotExist() {
 	// assert
 	s.repo.AssertExpectations(s.T())
 	s.Assert().Equal(http.StatusNotFound, s.res.Code)
+	s.Assert().Equal(uweb.ProblemContentType, s.res.Header().Get("Content-Type"))
 }
 
-//se retornar alguma coisa deve ser 200
+// se retornar alguma coisa deve ser 200
 func (s *BooksApiHandlerSuite) TestGetBookShouldReturn200IfBookExists() {
 	// arrange
 	s.repo.
-		On("GetBook", mock.Anything).
+		On("GetBook", mock.Anything, mock.Anything, mock.Anything).
 		Return(domain.Book{}, nil)
 
 	s.req = httptest.NewRequest(http.MethodGet, "/books/1", nil)
@@ -174,11 +432,84 @@ func (s *BooksApiHandlerSuite) TestGetBookShouldReturn200IfBookExists() {
 	s.Assert().Equal(http.StatusOK, s.res.Code)
 }
 
+func (s *BooksApiHandlerSuite) TestGetBookShouldEmbedAuthorsIfExpanded() {
+	// arrange
+	book := domain.Book{
+		Name: "Fluent Python",
+		Authors: []*domain.Author{
+			{Name: "Luciano Ramalho"},
+		},
+	}
+
+	s.repo.
+		On("GetBook", mock.Anything, 1, database.ExpandAuthors).
+		Return(book, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books/1?expand=authors", nil)
+	vars := map[string]string{
+		"id": "1",
+	}
+
+	s.req = mux.SetURLVars(s.req, vars)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result BookView
+	err := json.Unmarshal(s.res.Body.Bytes(), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal([]AuthorSummary{{Name: "Luciano Ramalho"}}, result.Authors)
+}
+
+func (s *BooksApiHandlerSuite) TestGetBookShouldNotEmbedAuthorsByDefault() {
+	// arrange
+	s.repo.
+		On("GetBook", mock.Anything, 1, database.ExpandNone).
+		Return(domain.Book{}, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books/1", nil)
+	vars := map[string]string{
+		"id": "1",
+	}
+
+	s.req = mux.SetURLVars(s.req, vars)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().NotContains(s.res.Body.String(), "Authors")
+}
+
+func (s *BooksApiHandlerSuite) TestGetBookShouldReturnVersionAsETag() {
+	// arrange
+	s.repo.
+		On("GetBook", mock.Anything, 1, mock.Anything).
+		Return(domain.Book{Version: 3}, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books/1", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal(`"3"`, s.res.Header().Get("ETag"))
+}
+
 func (s *BooksApiHandlerSuite) TestGetBookShouldReturn400IfIdIsInvalid() {
 
 	// arrange
 	s.repo.
-		On("GetBook", mock.Anything).
+		On("GetBook", mock.Anything, mock.Anything, mock.Anything).
 		Return(domain.Book{}, nil)
 
 	s.req = httptest.NewRequest(http.MethodGet, "/books/-1", nil)
@@ -196,32 +527,60 @@ func (s *BooksApiHandlerSuite) TestGetBookShouldReturn400IfIdIsInvalid() {
 
 }
 
-func (s *BooksApiHandlerSuite) TestPostBookShouldReturn200() {
+func (s *BooksApiHandlerSuite) postBook() {
+	s.req = httptest.NewRequest(http.MethodPost, "/books", strings.NewReader(`{"Name": "Ronaldo", "Edition": "1", "PublicationYear": 2020, "Authors": [{"ID": 3}]}`))
+	s.req.Header.Set("Content-Type", "application/json")
+}
+
+func (s *BooksApiHandlerSuite) TestPostBookShouldReturn201WithTheCreatedBook() {
 	// arrange
+	created := domain.Book{Name: "Ronaldo", Edition: "1", PublicationYear: 2020, Version: 1}
+	created.ID = 7
+
 	s.repo.
-		On("Create", mock.Anything).
-		Return(0, nil)
+		On("Create", mock.Anything, mock.Anything).
+		Return(uint(7), nil)
+	s.repo.
+		On("GetBook", mock.Anything, 7, database.ExpandAuthors).
+		Return(created, nil)
 
-	var book = domain.Book{
-		Name:            "Ronaldo",
-		Edition:         "1",
-		PublicationYear: 2020,
-	}
-	var jsonBytes, _ = json.Marshal(book)
-	s.req = httptest.NewRequest(http.MethodPost, "/books", bytes.NewBuffer(jsonBytes))
-	s.req.Header.Set("Content-Type", "application/json")
+	s.postBook()
 
 	// act
 	s.router.ServeHTTP(s.res, s.req)
 
 	// assert
-	s.Assert().Equal(http.StatusOK, s.res.Code)
+	var result BookView
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &result))
+
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusCreated, s.res.Code)
+	s.Assert().Equal("/books/7", s.res.Header().Get("Location"))
+	s.Assert().Equal(`"1"`, s.res.Header().Get(uweb.ETagHeader))
+	s.Assert().Equal(NewBookView(created), result)
+}
+
+func (s *BooksApiHandlerSuite) TestPostBookShouldReturn422IfAuthorIsUnknown() {
+	// arrange
+	s.repo.
+		On("Create", mock.Anything, mock.Anything).
+		Return(0, database.ErrAuthorNotFound)
+
+	s.req = httptest.NewRequest(http.MethodPost, "/books", strings.NewReader(`{"Name": "Ronaldo", "Authors": [{"ID": 42}]}`))
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "GetBook", mock.Anything, mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusUnprocessableEntity, s.res.Code)
+	s.Assert().Empty(s.res.Header().Get("Location"))
 }
 
 func (s *BooksApiHandlerSuite) TestPostBookShouldReturnIfBodyIsInvalid400() {
 	// arrange
 	s.repo.
-		On("Create", mock.Anything).
+		On("Create", mock.Anything, mock.Anything).
 		Return(0, nil)
 
 	s.req = httptest.NewRequest(http.MethodPost, "/books", nil)
@@ -234,32 +593,57 @@ func (s *BooksApiHandlerSuite) TestPostBookShouldReturnIfBodyIsInvalid400() {
 	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
 }
 
-func (s *BooksApiHandlerSuite) TestPostBookShouldReturn400IfNotCreate() {
+func (s *BooksApiHandlerSuite) TestPostBookShouldReturn422WithEveryInvalidField() {
+	// arrange
+	s.req = httptest.NewRequest(http.MethodPost, "/books", strings.NewReader(`{"Name": "", "Edition": "0", "PublicationYear": 2020}`))
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var problem uweb.Problem
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &problem))
+
+	s.repo.AssertNotCalled(s.T(), "Create", mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusUnprocessableEntity, s.res.Code)
+	s.Assert().Equal(uweb.CodeValidationFailed, problem.Code)
+	s.Require().Len(problem.Errors, 3)
+	s.Assert().Equal([]string{"Name", "Edition", "Authors"}, []string{problem.Errors[0].Field, problem.Errors[1].Field, problem.Errors[2].Field})
+}
+
+func (s *BooksApiHandlerSuite) TestPutBookShouldReturn422IfPublicationYearIsInTheFuture() {
+	// arrange
+	s.req = httptest.NewRequest(http.MethodPut, "/books/1", strings.NewReader(`{"Name": "Ronaldo", "Edition": "1", "PublicationYear": 3000}`))
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "Update", mock.Anything, mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusUnprocessableEntity, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestPostBookShouldReturn500IfNotCreate() {
 	// arrange
 	s.repo.
-		On("Create", mock.Anything).
+		On("Create", mock.Anything, mock.Anything).
 		Return(0, errors.New("Some Error"))
 
-	var book = domain.Book{
-		Name:            "Ronaldo",
-		Edition:         "1",
-		PublicationYear: 2020,
-	}
-	var jsonBytes, _ = json.Marshal(book)
-	s.req = httptest.NewRequest(http.MethodPost, "/books", bytes.NewBuffer(jsonBytes))
-	s.req.Header.Set("Content-Type", "application/json")
+	s.postBook()
 
 	// act
 	s.router.ServeHTTP(s.res, s.req)
 
 	// assert
-	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
+	s.repo.AssertNotCalled(s.T(), "GetBook", mock.Anything, mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusInternalServerError, s.res.Code)
+	s.Assert().Equal(uweb.ProblemContentType, s.res.Header().Get("Content-Type"))
 }
 
-func (s *BooksApiHandlerSuite) TestPutBookShouldReturn200() {
+func (s *BooksApiHandlerSuite) TestPutBookShouldReturn204() {
 	// arrange
 	s.repo.
-		On("Update", mock.Anything, mock.Anything).
+		On("Update", mock.Anything, mock.Anything, mock.Anything).
 		Return(nil)
 
 	var book = domain.Book{
@@ -280,13 +664,30 @@ func (s *BooksApiHandlerSuite) TestPutBookShouldReturn200() {
 	s.router.ServeHTTP(s.res, s.req)
 
 	// assert
-	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal(http.StatusNoContent, s.res.Code)
+	s.Assert().Empty(s.res.Body.String())
+}
+
+func (s *BooksApiHandlerSuite) TestPutBookShouldReturn404IfBookDoesNotExist() {
+	// arrange
+	s.repo.
+		On("Update", mock.Anything, 1, mock.Anything).
+		Return(gorm.ErrRecordNotFound)
+
+	s.putBook("")
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNotFound, s.res.Code)
 }
 
 func (s *BooksApiHandlerSuite) TestPutBookShouldReturn400IfHasNoBody() {
 	// arrange
 	s.repo.
-		On("Update", mock.Anything, mock.Anything).
+		On("Update", mock.Anything, mock.Anything, mock.Anything).
 		Return(nil)
 
 	s.req = httptest.NewRequest(http.MethodPut, "/books/1", nil)
@@ -301,13 +702,14 @@ func (s *BooksApiHandlerSuite) TestPutBookShouldReturn400IfHasNoBody() {
 	s.router.ServeHTTP(s.res, s.req)
 
 	// assert
+	s.repo.AssertNotCalled(s.T(), "Update", mock.Anything, mock.Anything, mock.Anything)
 	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
 }
 
 func (s *BooksApiHandlerSuite) TestPutBookShouldReturn400IfIdIsLessThanZero() {
 	// arrange
 	s.repo.
-		On("Update", mock.Anything, mock.Anything).
+		On("Update", mock.Anything, mock.Anything, mock.Anything).
 		Return(nil)
 
 	s.req = httptest.NewRequest(http.MethodPut, "/books/0", nil)
@@ -325,10 +727,10 @@ func (s *BooksApiHandlerSuite) TestPutBookShouldReturn400IfIdIsLessThanZero() {
 	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
 }
 
-func (s *BooksApiHandlerSuite) TestPutBookShouldReturn400UpdateFails() {
+func (s *BooksApiHandlerSuite) TestPutBookShouldReturn500UpdateFails() {
 	// arrange
 	s.repo.
-		On("Update", mock.Anything, mock.Anything).
+		On("Update", mock.Anything, mock.Anything, mock.Anything).
 		Return(errors.New(""))
 
 	var book = domain.Book{
@@ -349,13 +751,199 @@ func (s *BooksApiHandlerSuite) TestPutBookShouldReturn400UpdateFails() {
 	s.router.ServeHTTP(s.res, s.req)
 
 	// assert
-	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
+	s.Assert().Equal(http.StatusInternalServerError, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) putBook(ifMatch string) {
+	var book = domain.Book{
+		Name:            "Ronaldo",
+		Edition:         "1",
+		PublicationYear: 2020,
+	}
+	var jsonBytes, _ = json.Marshal(book)
+	s.req = httptest.NewRequest(http.MethodPut, "/books/1", bytes.NewBuffer(jsonBytes))
+	s.req.Header.Set("Content-Type", "application/json")
+	if len(ifMatch) > 0 {
+		s.req.Header.Set("If-Match", ifMatch)
+	}
+}
+
+func (s *BooksApiHandlerSuite) TestPutBookShouldPassIfMatchVersionToRepository() {
+	// arrange
+	s.repo.
+		On("Update", mock.Anything, 1, mock.MatchedBy(func(b domain.Book) bool { return b.Version == 3 })).
+		Return(nil)
+
+	s.putBook(`"3"`)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNoContent, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestPutBookShouldReturn412IfVersionDoesNotMatch() {
+	// arrange
+	s.repo.
+		On("Update", mock.Anything, mock.Anything, mock.Anything).
+		Return(database.ErrVersionMismatch)
+
+	s.putBook(`"2"`)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.Assert().Equal(http.StatusPreconditionFailed, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestPutBookShouldReturn428WithoutIfMatchInStrictMode() {
+	// arrange
+	s.router.Use(uweb.StrictPreconditions)
+	s.putBook("")
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "Update", mock.Anything, mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusPreconditionRequired, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestPatchBookShouldMergeThePatchAtTheReadVersion() {
+	// arrange
+	book := domain.Book{Name: "Some name", Edition: "1", PublicationYear: 2020, Version: 4}
+	book.ID = 1
+
+	s.repo.
+		On("GetBook", mock.Anything, 1, database.ExpandNone).
+		Return(book, nil)
+	s.repo.
+		On("Update", mock.Anything, 1, domain.Book{Name: "Some name", Edition: "2", PublicationYear: 2020, Version: 4}).
+		Return(nil)
+
+	s.req = httptest.NewRequest(http.MethodPatch, "/books/1", strings.NewReader(`{"Edition": "2"}`))
+	s.req.Header.Set("Content-Type", uweb.MergePatchContentType)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNoContent, s.res.Code)
+	s.Assert().Equal(uweb.AcceptPatch, s.res.Header().Get(uweb.AcceptPatchHeader))
+}
+
+func (s *BooksApiHandlerSuite) TestPatchBookShouldApplyAJSONPatchAtTheGivenVersion() {
+	// arrange
+	book := domain.Book{Name: "Some name", Edition: "1", PublicationYear: 2020, Version: 4}
+
+	s.repo.
+		On("GetBook", mock.Anything, 1, database.ExpandNone).
+		Return(book, nil)
+	s.repo.
+		On("Update", mock.Anything, 1, domain.Book{Name: "Other name", Edition: "1", PublicationYear: 2020, Version: 3}).
+		Return(database.ErrVersionMismatch)
+
+	s.req = httptest.NewRequest(http.MethodPatch, "/books/1", strings.NewReader(`[{"op": "replace", "path": "/Name", "value": "Other name"}]`))
+	s.req.Header.Set("Content-Type", uweb.JSONPatchContentType)
+	s.req.Header.Set(uweb.IfMatchHeader, `"3"`)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusPreconditionFailed, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestPatchBookShouldReturn422IfThePatchedBookIsInvalid() {
+	// arrange
+	s.repo.
+		On("GetBook", mock.Anything, 1, database.ExpandNone).
+		Return(domain.Book{Name: "Some name", Edition: "1", PublicationYear: 2020}, nil)
+
+	s.req = httptest.NewRequest(http.MethodPatch, "/books/1", strings.NewReader(`{"Name": null}`))
+	s.req.Header.Set("Content-Type", uweb.MergePatchContentType)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "Update", mock.Anything, mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusUnprocessableEntity, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestPatchBookShouldReturn415ForOtherMediaTypes() {
+	// arrange
+	s.repo.
+		On("GetBook", mock.Anything, 1, database.ExpandNone).
+		Return(domain.Book{Name: "Some name", Edition: "1", PublicationYear: 2020}, nil)
+
+	s.req = httptest.NewRequest(http.MethodPatch, "/books/1", strings.NewReader(`{"Edition": "2"}`))
+	s.req.Header.Set("Content-Type", "application/json")
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "Update", mock.Anything, mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusUnsupportedMediaType, s.res.Code)
+	s.Assert().Equal(uweb.AcceptPatch, s.res.Header().Get(uweb.AcceptPatchHeader))
+}
+
+func (s *BooksApiHandlerSuite) TestPatchBookShouldReturn404IfNotFound() {
+	// arrange
+	s.repo.
+		On("GetBook", mock.Anything, 1, database.ExpandNone).
+		Return(domain.Book{}, gorm.ErrRecordNotFound)
+
+	s.req = httptest.NewRequest(http.MethodPatch, "/books/1", strings.NewReader(`{}`))
+	s.req.Header.Set("Content-Type", uweb.MergePatchContentType)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.Assert().Equal(http.StatusNotFound, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestDeleteBookShouldReturn412IfVersionDoesNotMatch() {
+	// arrange
+	s.repo.
+		On("Delete", mock.Anything, 1, uint(2)).
+		Return(database.ErrVersionMismatch)
+
+	s.req = httptest.NewRequest(http.MethodDelete, "/books/1", nil)
+	s.req.Header.Set("If-Match", `"2"`)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusPreconditionFailed, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestDeleteBookShouldReturn428WithoutIfMatchInStrictMode() {
+	// arrange
+	s.router.Use(uweb.StrictPreconditions)
+	s.req = httptest.NewRequest(http.MethodDelete, "/books/1", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "Delete", mock.Anything, mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusPreconditionRequired, s.res.Code)
 }
 
 func (s *BooksApiHandlerSuite) TestDeleteBookShouldReturn400IfIdIsLessThanZero() {
 	// arrange
 	s.repo.
-		On("Delete", mock.Anything).
+		On("Delete", mock.Anything, mock.Anything, mock.Anything).
 		Return(nil)
 
 	s.req = httptest.NewRequest(http.MethodDelete, "/books/0", nil)
@@ -373,10 +961,10 @@ func (s *BooksApiHandlerSuite) TestDeleteBookShouldReturn400IfIdIsLessThanZero()
 	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
 }
 
-func (s *BooksApiHandlerSuite) TestDeleteBookShouldReturn400DeleteFails() {
+func (s *BooksApiHandlerSuite) TestDeleteBookShouldReturn500DeleteFails() {
 	// arrange
 	s.repo.
-		On("Delete", mock.Anything).
+		On("Delete", mock.Anything, mock.Anything, mock.Anything).
 		Return(errors.New("Some error"))
 
 	s.req = httptest.NewRequest(http.MethodDelete, "/books/1", nil)
@@ -391,13 +979,13 @@ func (s *BooksApiHandlerSuite) TestDeleteBookShouldReturn400DeleteFails() {
 	s.router.ServeHTTP(s.res, s.req)
 
 	// assert
-	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
+	s.Assert().Equal(http.StatusInternalServerError, s.res.Code)
 }
 
-func (s *BooksApiHandlerSuite) TestDeleteBookShouldReturn200() {
+func (s *BooksApiHandlerSuite) TestDeleteBookShouldReturn204() {
 	// arrange
 	s.repo.
-		On("Delete", mock.Anything).
+		On("Delete", mock.Anything, mock.Anything, mock.Anything).
 		Return(nil)
 
 	s.req = httptest.NewRequest(http.MethodDelete, "/books/1", nil)
@@ -412,7 +1000,187 @@ func (s *BooksApiHandlerSuite) TestDeleteBookShouldReturn200() {
 	s.router.ServeHTTP(s.res, s.req)
 
 	// assert
+	s.Assert().Equal(http.StatusNoContent, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestDeleteBookShouldReturn404IfBookDoesNotExist() {
+	// arrange
+	s.repo.
+		On("Delete", mock.Anything, 42, uint(0)).
+		Return(gorm.ErrRecordNotFound)
+
+	s.req = httptest.NewRequest(http.MethodDelete, "/books/42", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNotFound, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksTrashShouldReturn500IfListingFails() {
+	// arrange
+	s.repo.
+		On("GetTrash", mock.Anything, mock.Anything).
+		Return(nil, errors.New("database is locked"))
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books/trash", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusInternalServerError, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestGetBookHistoryShouldReturn500IfReadFails() {
+	// arrange
+	s.repo.
+		On("GetHistory", mock.Anything, 1).
+		Return(nil, errors.New("database is locked"))
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books/1/history", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusInternalServerError, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestGetBooksTrashShouldReturnDeletedBooks() {
+	// arrange
+	book := domain.Book{Name: "Book 1"}
+	book.DeletedAt = gorm.DeletedAt{Time: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+
+	s.repo.
+		On("GetTrash", mock.Anything, database.PageRequest{Limit: 10, Offset: 0}).
+		Return([]domain.Book{book}, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books/trash?limit=10", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result []BookView
+	err := json.Unmarshal(s.res.Body.Bytes(), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Require().Len(result, 1)
+	s.Assert().Equal(book.DeletedAt.Time, *result[0].DeletedAt)
+}
+
+func (s *BooksApiHandlerSuite) TestRestoreBookShouldReturn200WithTheBook() {
+	// arrange
+	book := domain.Book{Name: "Restored", Version: 3}
+	book.ID = 1
+
+	s.repo.
+		On("Restore", mock.Anything, 1).
+		Return(nil)
+	s.repo.
+		On("GetBook", mock.Anything, 1, database.ExpandAuthors).
+		Return(book, nil)
+
+	s.req = httptest.NewRequest(http.MethodPost, "/books/1/restore", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result BookView
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &result))
+
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal(`"3"`, s.res.Header().Get(uweb.ETagHeader))
+	s.Assert().Equal(NewBookView(book), result)
+}
+
+func (s *BooksApiHandlerSuite) TestRestoreBookShouldReturn404IfNotInTrash() {
+	// arrange
+	s.repo.
+		On("Restore", mock.Anything, 1).
+		Return(gorm.ErrRecordNotFound)
+
+	s.req = httptest.NewRequest(http.MethodPost, "/books/1/restore", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNotFound, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestRestoreBookShouldReturn400IfIdIsInvalid() {
+	s.req = httptest.NewRequest(http.MethodPost, "/books/0/restore", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "Restore", mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
+}
+
+func (s *BooksApiHandlerSuite) TestGetBookHistoryShouldReturnFieldLevelDiff() {
+	// arrange
+	s.repo.
+		On("GetHistory", mock.Anything, 1).
+		Return([]domain.History{
+			{
+				Action: database.ActionCreate,
+				After:  `{"Name":"Fluent Python","Edition":"1"}`,
+			},
+			{
+				Action: database.ActionUpdate,
+				Actor:  "librarian",
+				Before: `{"Name":"Fluent Python","Edition":"1"}`,
+				After:  `{"Name":"Fluent Python","Edition":"2"}`,
+			},
+		}, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books/1/history", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result []HistoryView
+	err := json.Unmarshal(s.res.Body.Bytes(), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s.repo.AssertExpectations(s.T())
 	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Require().Len(result, 2)
+	s.Assert().Equal([]FieldChange{
+		{Field: "Edition", Before: nil, After: "1"},
+		{Field: "Name", Before: nil, After: "Fluent Python"},
+	}, result[0].Changes)
+	s.Assert().Equal([]FieldChange{{Field: "Edition", Before: "1", After: "2"}}, result[1].Changes)
+	s.Assert().Equal("librarian", result[1].Actor)
+}
+
+func (s *BooksApiHandlerSuite) TestGetBookHistoryShouldReturn400IfIdIsInvalid() {
+	s.req = httptest.NewRequest(http.MethodGet, "/books/abc/history", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "GetHistory", mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
 }
 
 func TestBooksApiHandlerSuite(t *testing.T) {
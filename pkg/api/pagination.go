@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/uweb"
+)
+
+// paging is how a page of a listing was asked for.
+type paging struct {
+	Limit  int
+	Offset int
+	After  *database.Cursor
+	Sort   database.Sort
+	Total  bool
+
+	// Cursors tells whether the listing is in the order of its sort, which
+	// cursors point into. Searches ranked by relevance are not.
+	Cursors bool
+}
+
+// bindPaging reads how a page of a listing sorted by one of fields is asked
+// for.
+func bindPaging(r *http.Request, limit int, offset int, query string, fields []string) (paging, error) {
+	sort, err := uweb.BindSort(r, fields)
+	if err != nil {
+		return paging{}, err
+	}
+
+	after, err := uweb.BindCursor(r)
+	if err != nil {
+		return paging{}, err
+	}
+
+	ranked := len(query) > 0 && len(sort) == 0
+	if after != nil && ranked {
+		return paging{}, uweb.InvalidParameter("after", CursorError)
+	}
+
+	if after != nil && !after.Fits(sort) {
+		return paging{}, uweb.InvalidParameter("after", CursorSortError)
+	}
+
+	total, err := uweb.BindTotal(r)
+	if err != nil {
+		return paging{}, err
+	}
+
+	return paging{Limit: limit, Offset: offset, After: after, Sort: sort, Total: total, Cursors: !ranked}, nil
+}
+
+// writePage writes data, the rows of a page, in the page envelope. The links
+// continue the way the page was asked for: after the cursor of its last row,
+// given by last, or by offset. Cursors only point forward, so pages after one
+// have no previous link. count is only called when the total is asked for.
+func writePage(w http.ResponseWriter, r *http.Request, p paging, data interface{}, rows int, last func() database.Cursor, count func() (int64, error)) {
+	page := uweb.Page{Data: data, Limit: p.Limit}
+	more := rows > 0 && rows == p.Limit
+
+	if p.After != nil {
+		page.Cursor = p.After.String()
+	} else {
+		offset := p.Offset
+		page.Offset = &offset
+	}
+
+	if p.Total {
+		total, err := count()
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		page.Total = &total
+		if p.After == nil {
+			more = int64(p.Offset+rows) < total
+		}
+	}
+
+	if more && p.Cursors {
+		page.NextCursor = last().String()
+		w.Header().Set(uweb.NextCursorHeader, page.NextCursor)
+	}
+
+	switch {
+	case more && p.After != nil:
+		page.Links.Next = uweb.PageLink(r, "after", last().String())
+	case more:
+		page.Links.Next = uweb.PageLink(r, "offset", strconv.Itoa(p.Offset+p.Limit))
+	}
+
+	if p.After == nil && p.Offset > 0 {
+		prev := p.Offset - p.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		page.Links.Prev = uweb.PageLink(r, "offset", strconv.Itoa(prev))
+	}
+
+	uweb.ToPage(w, page)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/uweb"
+)
+
+// NewRelationsApi serves the links between books and authors as
+// sub-resources of both.
+func NewRelationsApi(r *mux.Router, books database.BooksRepository, authors database.AuthorsRepository) {
+
+	r.HandleFunc("/authors/{id}/books", GetAuthorBooks(books, authors)).Methods(http.MethodGet)
+	r.HandleFunc("/books/{id}/authors", GetBookAuthors(books)).Methods(http.MethodGet)
+	r.HandleFunc("/books/{id}/authors/{authorId}", AddBookAuthor(books)).Methods(http.MethodPut)
+	r.HandleFunc("/books/{id}/authors/{authorId}", RemoveBookAuthor(books)).Methods(http.MethodDelete)
+}
+
+// GetAuthorBooks lists the books of an author with the filters and pagination
+// of GET /books.
+func GetAuthorBooks(books database.BooksRepository, authors database.AuthorsRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := uweb.BindAuthorId(r, uweb.Path, IdError)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		if _, err := authors.GetAuthor(r.Context(), id); err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		getAllRequest := uweb.BindGetBooksRequest(r)
+		getAllRequest.Author = id
+		listBooks(w, r, books, getAllRequest)
+	}
+}
+
+func GetBookAuthors(repository database.BooksRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := uweb.BindBookId(r, uweb.Path, IdError)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		authors, err := repository.GetAuthors(r.Context(), id)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		uweb.ToJson(w, NewAuthorViews(authors))
+	}
+}
+
+// AddBookAuthor links an author to a book. It is idempotent, and If-Match
+// applies to the version of the book.
+func AddBookAuthor(repository database.BooksRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		changeBookAuthors(w, r, repository.AddAuthor)
+	}
+}
+
+// RemoveBookAuthor unlinks an author from a book. If-Match applies to the
+// version of the book.
+func RemoveBookAuthor(repository database.BooksRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		changeBookAuthors(w, r, repository.RemoveAuthor)
+	}
+}
+
+func changeBookAuthors(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, id int, authorID int, version uint) error) {
+	id, err := uweb.BindBookId(r, uweb.Path, IdError)
+	if err != nil {
+		uweb.ToProblem(w, r, err)
+		return
+	}
+
+	authorID, err := uweb.BindLinkedAuthorId(r, IdError)
+	if err != nil {
+		uweb.ToProblem(w, r, err)
+		return
+	}
+
+	version, err := uweb.BindIfMatch(r)
+	if err != nil {
+		uweb.ToProblem(w, r, err)
+		return
+	}
+
+	err = change(r.Context(), id, authorID, version)
+	if errors.Is(err, database.ErrAuthorNotFound) {
+		// the author is part of the path here, not of the payload
+		err = &uweb.Error{Status: http.StatusNotFound, Code: uweb.CodeAuthorNotFound, Detail: err.Error()}
+	}
+
+	if err != nil {
+		uweb.ToProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
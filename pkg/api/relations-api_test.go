@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uweb"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type RelationsApiHandlerSuite struct {
+	suite.Suite
+
+	router *mux.Router
+
+	req *http.Request
+	res *httptest.ResponseRecorder
+
+	books   *database.BooksRepositoryMock
+	authors *database.AuthorsRepositoryMock
+}
+
+func (s *RelationsApiHandlerSuite) SetupTest() {
+	s.books = database.NewBooksRepositoryMock()
+	s.authors = database.NewAuthorsRepositoryMock()
+	s.res = httptest.NewRecorder()
+	s.router = mux.NewRouter()
+	NewRelationsApi(s.router, s.books, s.authors)
+}
+
+func (s *RelationsApiHandlerSuite) TestGetAuthorBooksShouldFilterByTheAuthor() {
+
+	// arrange
+	s.authors.
+		On("GetAuthor", mock.Anything, 3).
+		Return(domain.Author{}, nil)
+	s.books.
+		On("GetAll", mock.Anything, database.GetAllRequest{Author: 3, Edition: "2", Limit: 10}).
+		Return([]domain.Book{{Name: "The C Programming Language"}}, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/authors/3/books?edition=2&limit=10&author=7", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result struct{ Data []BookView }
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &result))
+
+	s.books.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Require().Len(result.Data, 1)
+	s.Assert().Equal("The C Programming Language", result.Data[0].Name)
+}
+
+func (s *RelationsApiHandlerSuite) TestGetAuthorBooksShouldReturn404IfAuthorIsUnknown() {
+
+	// arrange
+	s.authors.
+		On("GetAuthor", mock.Anything, 3).
+		Return(nil, gorm.ErrRecordNotFound)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/authors/3/books", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.books.AssertNotCalled(s.T(), "GetAll", mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusNotFound, s.res.Code)
+}
+
+func (s *RelationsApiHandlerSuite) TestGetBookAuthorsShouldReturn200() {
+
+	// arrange
+	author := domain.Author{Name: "Brian Kernighan", Version: 1}
+	author.ID = 3
+
+	s.books.
+		On("GetAuthors", mock.Anything, 1).
+		Return([]domain.Author{author}, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books/1/authors", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result []AuthorView
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &result))
+
+	s.books.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal([]AuthorView{NewAuthorView(author)}, result)
+}
+
+func (s *RelationsApiHandlerSuite) TestGetBookAuthorsShouldReturn404IfBookIsUnknown() {
+
+	// arrange
+	s.books.
+		On("GetAuthors", mock.Anything, 1).
+		Return(nil, gorm.ErrRecordNotFound)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/books/1/authors", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.books.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNotFound, s.res.Code)
+}
+
+func (s *RelationsApiHandlerSuite) TestAddBookAuthorShouldReturn204() {
+
+	// arrange
+	s.books.
+		On("AddAuthor", mock.Anything, 1, 3, uint(2)).
+		Return(nil)
+
+	s.req = httptest.NewRequest(http.MethodPut, "/books/1/authors/3", nil)
+	s.req.Header.Set(uweb.IfMatchHeader, `"2"`)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.books.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNoContent, s.res.Code)
+}
+
+func (s *RelationsApiHandlerSuite) TestAddBookAuthorShouldReturn404IfAuthorIsUnknown() {
+
+	// arrange
+	s.books.
+		On("AddAuthor", mock.Anything, 1, 3, uint(0)).
+		Return(database.ErrAuthorNotFound)
+
+	s.req = httptest.NewRequest(http.MethodPut, "/books/1/authors/3", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.books.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNotFound, s.res.Code)
+}
+
+func (s *RelationsApiHandlerSuite) TestRemoveBookAuthorShouldReturn412IfBookChanged() {
+
+	// arrange
+	s.books.
+		On("RemoveAuthor", mock.Anything, 1, 3, uint(1)).
+		Return(database.ErrVersionMismatch)
+
+	s.req = httptest.NewRequest(http.MethodDelete, "/books/1/authors/3", nil)
+	s.req.Header.Set(uweb.IfMatchHeader, `"1"`)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.books.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusPreconditionFailed, s.res.Code)
+}
+
+func (s *RelationsApiHandlerSuite) TestRemoveBookAuthorShouldReturn400IfAuthorIdIsInvalid() {
+
+	// arrange
+	s.req = httptest.NewRequest(http.MethodDelete, "/books/1/authors/0", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.books.AssertNotCalled(s.T(), "RemoveAuthor", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
+}
+
+func TestRelationsApiHandlerSuite(t *testing.T) {
+	suite.Run(t, new(RelationsApiHandlerSuite))
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/uweb"
+)
+
+type RouterOptions struct {
+	// StrictPreconditions rejects writes without an If-Match header.
+	StrictPreconditions bool
+
+	// APIKeys maps the API keys to their tenants. When empty, the tenant is
+	// taken from the X-Tenant-Id header.
+	APIKeys map[string]string
+}
+
+// NewRouter serves every api over repos, with the middlewares they rely on.
+// /status is left out of them, so that health checks need no API key.
+func NewRouter(repos database.Repos, opts RouterOptions) *mux.Router {
+	r := mux.NewRouter()
+
+	r.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	apis := r.PathPrefix("/").Subrouter()
+	apis.Use(uweb.RequestContext)
+	apis.Use(uweb.Tenancy(opts.APIKeys))
+	if opts.StrictPreconditions {
+		apis.Use(uweb.StrictPreconditions)
+	}
+
+	NewAuthorsApi(apis, repos.Authors)
+	NewBooksApi(apis, repos.Books)
+	NewStatsApi(apis, repos.Stats)
+	NewRelationsApi(apis, repos.Books, repos.Authors)
+
+	r.NotFoundHandler = http.HandlerFunc(uweb.NotFound)
+	r.MethodNotAllowedHandler = http.HandlerFunc(uweb.MethodNotAllowed)
+
+	return r
+}
@@ -0,0 +1,355 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/api"
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/testkit"
+	"github.com/jedielson/bookstore/pkg/uweb"
+	"github.com/stretchr/testify/suite"
+)
+
+type RouterIntegrationSuite struct {
+	suite.Suite
+
+	app *testkit.App
+}
+
+func (s *RouterIntegrationSuite) SetupTest() {
+	s.app = testkit.NewApp(s.T())
+}
+
+func (s *RouterIntegrationSuite) TestCreatedBookShouldBeReadWithItsAuthors() {
+	// arrange
+	pike := testkit.AnAuthor().Named("Rob Pike").Create(s.T(), s.app.Manager)
+	body := map[string]interface{}{
+		"Name":            "The Practice of Programming",
+		"Edition":         "1",
+		"PublicationYear": 1999,
+		"Authors":         []map[string]interface{}{{"ID": pike.ID}},
+	}
+
+	// act
+	created := s.app.Do(s.T(), http.MethodPost, "/books", body)
+	location := created.Header.Get("Location")
+	view := api.BookView{}
+	testkit.Decode(s.T(), created, &view)
+	res := s.app.Do(s.T(), http.MethodGet, location+"?expand=authors", nil)
+
+	// assert
+	s.Assert().Equal(fmt.Sprintf("/books/%d", view.ID), location)
+	s.Require().Len(view.Authors, 1)
+	s.Assert().Equal("Rob Pike", view.Authors[0].Name)
+
+	s.Require().Equal(http.StatusOK, res.StatusCode)
+	s.Assert().Equal(`"1"`, res.Header.Get(uweb.ETagHeader))
+
+	book := api.BookView{}
+	testkit.Decode(s.T(), res, &book)
+	s.Assert().Equal("The Practice of Programming", book.Name)
+	s.Require().Len(book.Authors, 1)
+	s.Assert().Equal("Rob Pike", book.Authors[0].Name)
+}
+
+func (s *RouterIntegrationSuite) TestBooksShouldBeWalkedWithCursors() {
+	// arrange
+	for _, name := range []string{"C", "A", "B", "E", "D"} {
+		testkit.ABook().Named(name).Create(s.T(), s.app.Manager)
+	}
+	testkit.ABook().Named("Deleted").Deleted().Create(s.T(), s.app.Manager)
+
+	names := []string{}
+	path := "/books?limit=2"
+
+	// act
+	for len(path) > 0 {
+		res := s.app.Do(s.T(), http.MethodGet, path, nil)
+		s.Require().Equal(http.StatusOK, res.StatusCode)
+
+		page := struct {
+			Data  []api.BookView
+			Links uweb.PageLinks
+		}{}
+		testkit.Decode(s.T(), res, &page)
+		for _, b := range page.Data {
+			names = append(names, b.Name)
+		}
+
+		cursor := strings.Contains(path, "after=")
+		path = ""
+		if next := res.Header.Get(uweb.NextCursorHeader); len(next) > 0 {
+			path = "/books?limit=2&after=" + next
+		}
+
+		// Pages after a cursor link on to the next cursor, not to an offset.
+		if cursor && len(path) > 0 {
+			s.Assert().Equal("/books?after="+res.Header.Get(uweb.NextCursorHeader)+"&limit=2", page.Links.Next)
+		}
+	}
+
+	// assert
+	s.Assert().Equal([]string{"A", "B", "C", "D", "E"}, names)
+}
+
+func (s *RouterIntegrationSuite) TestSortedBooksShouldBeWalkedWithCursors() {
+	// arrange
+	for _, b := range []struct {
+		name string
+		year int
+	}{{"C", 2015}, {"A", 1988}, {"B", 2015}, {"E", 2001}, {"D", 2015}} {
+		testkit.ABook().Named(b.name).PublishedIn(b.year).Create(s.T(), s.app.Manager)
+	}
+
+	names := []string{}
+	path := "/books?limit=2&sort=-publication_year,name"
+
+	// act
+	for len(path) > 0 {
+		res := s.app.Do(s.T(), http.MethodGet, path, nil)
+		s.Require().Equal(http.StatusOK, res.StatusCode)
+
+		page := struct {
+			Data       []api.BookView
+			NextCursor string `json:"next_cursor"`
+		}{}
+		testkit.Decode(s.T(), res, &page)
+		for _, b := range page.Data {
+			names = append(names, b.Name)
+		}
+
+		path = ""
+		if len(page.NextCursor) > 0 {
+			path = "/books?limit=2&sort=-publication_year,name&after=" + page.NextCursor
+		}
+	}
+
+	// assert
+	s.Assert().Equal([]string{"B", "C", "D", "E", "A"}, names)
+}
+
+func (s *RouterIntegrationSuite) TestStaleUpdateShouldFailAndHistoryShouldNameTheActor() {
+	// arrange
+	book := testkit.ABook().Named("Draft").Create(s.T(), s.app.Manager)
+	path := fmt.Sprintf("/books/%d", book.ID)
+	body := domain.Book{Name: "Final", Edition: "2", PublicationYear: 2020}
+
+	// act
+	updated := s.app.Do(s.T(), http.MethodPut, path, body, uweb.IfMatchHeader, `"1"`, uweb.ActorHeader, "editor")
+	stale := s.app.Do(s.T(), http.MethodPut, path, body, uweb.IfMatchHeader, `"1"`)
+	history := s.app.Do(s.T(), http.MethodGet, path+"/history", nil)
+
+	// assert
+	s.Assert().Equal(http.StatusNoContent, updated.StatusCode)
+	s.Assert().Equal(http.StatusPreconditionFailed, stale.StatusCode)
+
+	entries := []api.HistoryView{}
+	testkit.Decode(s.T(), history, &entries)
+	s.Require().Len(entries, 1)
+	s.Assert().Equal("editor", entries[0].Actor)
+	s.Assert().NotEmpty(entries[0].RequestID)
+}
+
+func (s *RouterIntegrationSuite) TestDeletedBookShouldBeRestoredFromTrash() {
+	// arrange
+	book := testkit.ABook().Create(s.T(), s.app.Manager)
+	path := fmt.Sprintf("/books/%d", book.ID)
+
+	// act
+	removed := s.app.Do(s.T(), http.MethodDelete, path, nil)
+	deleted := s.app.Do(s.T(), http.MethodGet, path, nil)
+	again := s.app.Do(s.T(), http.MethodDelete, path, nil)
+	trash := s.app.Do(s.T(), http.MethodGet, "/books/trash", nil)
+	restored := s.app.Do(s.T(), http.MethodPost, path+"/restore", nil)
+	found := s.app.Do(s.T(), http.MethodGet, path, nil)
+
+	// assert
+	s.Assert().Equal(http.StatusNoContent, removed.StatusCode)
+	s.Assert().Equal(http.StatusNotFound, deleted.StatusCode)
+	s.Assert().Equal(http.StatusNotFound, again.StatusCode)
+
+	books := []api.BookView{}
+	testkit.Decode(s.T(), trash, &books)
+	s.Require().Len(books, 1)
+	s.Assert().Equal(book.Name, books[0].Name)
+
+	s.Assert().Equal(http.StatusOK, restored.StatusCode)
+	s.Assert().Equal(found.Header.Get(uweb.ETagHeader), restored.Header.Get(uweb.ETagHeader))
+	s.Assert().Equal(http.StatusOK, found.StatusCode)
+}
+
+func (s *RouterIntegrationSuite) TestBookShouldBePatched() {
+	// arrange
+	book := testkit.ABook().Named("Draft").Create(s.T(), s.app.Manager)
+	path := fmt.Sprintf("/books/%d", book.ID)
+
+	// act
+	merged := s.app.Do(s.T(), http.MethodPatch, path, map[string]interface{}{"Edition": "2"}, "Content-Type", uweb.MergePatchContentType)
+	patched := s.app.Do(s.T(), http.MethodPatch, path, []map[string]interface{}{
+		{"op": "test", "path": "/Edition", "value": "2"},
+		{"op": "replace", "path": "/Name", "value": "Final"},
+	}, "Content-Type", uweb.JSONPatchContentType, uweb.IfMatchHeader, `"2"`)
+	stale := s.app.Do(s.T(), http.MethodPatch, path, map[string]interface{}{"Name": "Stale"}, "Content-Type", uweb.MergePatchContentType, uweb.IfMatchHeader, `"2"`)
+	found := s.app.Do(s.T(), http.MethodGet, path, nil)
+
+	// assert
+	s.Assert().Equal(http.StatusNoContent, merged.StatusCode)
+	s.Assert().Equal(http.StatusNoContent, patched.StatusCode)
+	s.Assert().Equal(http.StatusPreconditionFailed, stale.StatusCode)
+
+	view := api.BookView{}
+	testkit.Decode(s.T(), found, &view)
+	s.Assert().Equal("Final", view.Name)
+	s.Assert().Equal("2", view.Edition)
+	s.Assert().Equal(book.PublicationYear, view.PublicationYear)
+	s.Assert().Equal(uint(3), view.Version)
+}
+
+func (s *RouterIntegrationSuite) TestStrictRouterShouldRequireIfMatch() {
+	// arrange
+	app := testkit.NewAppWithOptions(s.T(), api.RouterOptions{StrictPreconditions: true})
+	book := testkit.ABook().Create(s.T(), app.Manager)
+
+	// act
+	res := app.Do(s.T(), http.MethodDelete, fmt.Sprintf("/books/%d", book.ID), nil)
+
+	// assert
+	s.Assert().Equal(http.StatusPreconditionRequired, res.StatusCode)
+}
+
+func (s *RouterIntegrationSuite) TestTenantsShouldOnlySeeTheirCatalogue() {
+	// arrange
+	app := testkit.NewAppWithOptions(s.T(), api.RouterOptions{APIKeys: map[string]string{"key-1": "library-1", "key-2": "library-2"}})
+	author := app.Do(s.T(), http.MethodPost, "/authors", domain.Author{Name: "Local Author"}, uweb.APIKeyHeader, "key-1")
+	local := api.AuthorView{}
+	testkit.Decode(s.T(), author, &local)
+	body := map[string]interface{}{
+		"Name":            "Shared Title",
+		"Edition":         "1",
+		"PublicationYear": 2020,
+		"Authors":         []map[string]interface{}{{"ID": local.ID}},
+	}
+
+	created := app.Do(s.T(), http.MethodPost, "/books", body, uweb.APIKeyHeader, "key-1")
+	path := created.Header.Get("Location")
+
+	// act
+	anonymous := app.Do(s.T(), http.MethodGet, "/books", nil)
+	spoofed := app.Do(s.T(), http.MethodGet, "/books", nil, uweb.APIKeyHeader, "key-2", uweb.TenantHeader, "library-1")
+	foreign := app.Do(s.T(), http.MethodGet, path, nil, uweb.APIKeyHeader, "key-2")
+	overwrite := app.Do(s.T(), http.MethodPut, path, domain.Book{Name: "Hijacked", Edition: "1", PublicationYear: 2020}, uweb.APIKeyHeader, "key-2")
+	own := app.Do(s.T(), http.MethodGet, path, nil, uweb.APIKeyHeader, "key-1")
+	status := app.Do(s.T(), http.MethodGet, "/status", nil)
+
+	// assert
+	s.Assert().Equal(http.StatusUnauthorized, anonymous.StatusCode)
+
+	books := struct{ Data []api.BookView }{}
+	testkit.Decode(s.T(), spoofed, &books)
+	s.Assert().Empty(books.Data)
+
+	s.Assert().Equal(http.StatusNotFound, foreign.StatusCode)
+	s.Assert().Equal(http.StatusNotFound, overwrite.StatusCode)
+
+	book := api.BookView{}
+	testkit.Decode(s.T(), own, &book)
+	s.Assert().Equal("Shared Title", book.Name)
+	s.Assert().Equal(http.StatusOK, status.StatusCode)
+}
+
+func (s *RouterIntegrationSuite) TestMisspelledAuthorShouldBeFixedAndDeleted() {
+	// arrange
+	created := s.app.Do(s.T(), http.MethodPost, "/authors", map[string]interface{}{"Name": "Rob Pyke"})
+	s.Require().Equal(http.StatusCreated, created.StatusCode)
+	location := created.Header.Get("Location")
+	testkit.ABook().Named("The Go Programming Language").By(s.author(location)).Create(s.T(), s.app.Manager)
+
+	// act
+	patched := s.app.Do(s.T(), http.MethodPatch, location, map[string]interface{}{"Name": "Rob Pike"}, uweb.IfMatchHeader, `"1"`)
+	res := s.app.Do(s.T(), http.MethodGet, location, nil)
+	rejected := s.app.Do(s.T(), http.MethodDelete, location, nil)
+	deleted := s.app.Do(s.T(), http.MethodDelete, location+"?cascade=unlink", nil)
+	gone := s.app.Do(s.T(), http.MethodGet, location, nil)
+
+	// assert
+	s.Assert().Equal(http.StatusNoContent, patched.StatusCode)
+	s.Require().Equal(http.StatusOK, res.StatusCode)
+	s.Assert().Equal(`"2"`, res.Header.Get(uweb.ETagHeader))
+
+	author := api.AuthorView{}
+	testkit.Decode(s.T(), res, &author)
+	s.Assert().Equal("Rob Pike", author.Name)
+
+	s.Assert().Equal(http.StatusConflict, rejected.StatusCode)
+	s.Assert().Equal(http.StatusNoContent, deleted.StatusCode)
+	s.Assert().Equal(http.StatusNotFound, gone.StatusCode)
+}
+
+func (s *RouterIntegrationSuite) author(location string) domain.Author {
+	author := domain.Author{}
+	_, err := fmt.Sscanf(location, "/authors/%d", &author.ID)
+	s.Require().NoError(err)
+	return author
+}
+
+func (s *RouterIntegrationSuite) TestAuthorShouldBeAttachedAndDetached() {
+	// arrange
+	pike := testkit.AnAuthor().Named("Rob Pike").Create(s.T(), s.app.Manager)
+	kernighan := testkit.AnAuthor().Named("Brian Kernighan").Create(s.T(), s.app.Manager)
+	book := testkit.ABook().Named("The Practice of Programming").By(kernighan).Create(s.T(), s.app.Manager)
+	path := fmt.Sprintf("/books/%d/authors", book.ID)
+
+	// act
+	attached := s.app.Do(s.T(), http.MethodPut, fmt.Sprintf("%s/%d", path, pike.ID), nil)
+	authors := s.app.Do(s.T(), http.MethodGet, path, nil)
+	books := s.app.Do(s.T(), http.MethodGet, fmt.Sprintf("/authors/%d/books", pike.ID), nil)
+	detached := s.app.Do(s.T(), http.MethodDelete, fmt.Sprintf("%s/%d", path, kernighan.ID), nil)
+	after := s.app.Do(s.T(), http.MethodGet, path, nil)
+
+	// assert
+	s.Assert().Equal(http.StatusNoContent, attached.StatusCode)
+	s.Assert().Equal(http.StatusNoContent, detached.StatusCode)
+
+	views := []api.AuthorView{}
+	testkit.Decode(s.T(), authors, &views)
+	s.Assert().Equal([]string{"Brian Kernighan", "Rob Pike"}, []string{views[0].Name, views[1].Name})
+
+	bookViews := struct{ Data []api.BookView }{}
+	testkit.Decode(s.T(), books, &bookViews)
+	s.Require().Len(bookViews.Data, 1)
+	s.Assert().Equal("The Practice of Programming", bookViews.Data[0].Name)
+
+	views = []api.AuthorView{}
+	testkit.Decode(s.T(), after, &views)
+	s.Require().Len(views, 1)
+	s.Assert().Equal("Rob Pike", views[0].Name)
+}
+
+func (s *RouterIntegrationSuite) TestErrorsShouldBeProblemDetails() {
+	// act
+	missing := s.app.Do(s.T(), http.MethodGet, "/books/42", nil, uweb.RequestIDHeader, "request-42")
+	unknown := s.app.Do(s.T(), http.MethodGet, "/shelves", nil)
+	notAllowed := s.app.Do(s.T(), http.MethodPatch, "/books", nil)
+
+	// assert
+	s.Assert().Equal(uweb.ProblemContentType, missing.Header.Get("Content-Type"))
+
+	problem := uweb.Problem{}
+	testkit.Decode(s.T(), missing, &problem)
+	s.Assert().Equal(http.StatusNotFound, problem.Status)
+	s.Assert().Equal(uweb.CodeNotFound, problem.Code)
+	s.Assert().Equal("/books/42", problem.Instance)
+	s.Assert().Equal("request-42", problem.RequestID)
+
+	s.Assert().Equal(http.StatusNotFound, unknown.StatusCode)
+	s.Assert().Equal(uweb.ProblemContentType, unknown.Header.Get("Content-Type"))
+	s.Assert().Equal(http.StatusMethodNotAllowed, notAllowed.StatusCode)
+	s.Assert().Equal(uweb.ProblemContentType, notAllowed.Header.Get("Content-Type"))
+}
+
+func TestIntegrationRouterSuite(t *testing.T) {
+	suite.Run(t, new(RouterIntegrationSuite))
+}
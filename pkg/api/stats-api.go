@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/uweb"
+)
+
+const MaxTopAuthors = 100
+
+func NewStatsApi(r *mux.Router, repository database.StatsRepository) {
+
+	r.HandleFunc("/stats", GetStats(repository)).Methods(http.MethodGet)
+	r.HandleFunc("/authors/{id}/stats", GetAuthorStats(repository)).Methods(http.MethodGet)
+}
+
+func GetStats(repository database.StatsRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		top := uweb.FromQuery(r, "top", database.DefaultTopAuthors, func(i int) bool {
+			return i > 0 && i <= MaxTopAuthors
+		})
+
+		stats, err := repository.GetStats(r.Context(), top)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		uweb.ToJson(w, stats)
+	}
+}
+
+func GetAuthorStats(repository database.StatsRepository) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := uweb.BindAuthorId(r, uweb.Path, IdError)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		stats, err := repository.GetAuthorStats(r.Context(), id)
+		if err != nil {
+			uweb.ToProblem(w, r, err)
+			return
+		}
+
+		uweb.ToJson(w, stats)
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type StatsApiHandlerSuite struct {
+	suite.Suite
+
+	router *mux.Router
+
+	req *http.Request
+	res *httptest.ResponseRecorder
+
+	repo *database.StatsRepositoryMock
+}
+
+func (s *StatsApiHandlerSuite) SetupTest() {
+	s.repo = database.NewStatsRepositoryMock()
+	s.res = httptest.NewRecorder()
+	s.router = mux.NewRouter()
+	NewStatsApi(s.router, s.repo)
+}
+
+func (s *StatsApiHandlerSuite) TestGetStatsShouldReturn200() {
+
+	// arrange
+	stats := database.CatalogueStats{
+		Books:                    3,
+		Authors:                  2,
+		Decades:                  []database.DecadeStats{{Decade: 1980, Books: 3}},
+		TopAuthors:               []database.AuthorBookCount{{ID: 1, Name: "Brian Kernighan", Books: 3}},
+		AuthorsWithoutBooks:      []database.AuthorBookCount{{ID: 2, Name: "Ken Thompson"}},
+		AuthorsWithoutBooksCount: 1,
+	}
+
+	s.repo.
+		On("GetStats", mock.Anything, database.DefaultTopAuthors).
+		Return(stats, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/stats", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result database.CatalogueStats
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &result))
+
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal(stats, result)
+}
+
+func (s *StatsApiHandlerSuite) TestGetStatsShouldBindTop() {
+
+	// arrange
+	s.repo.
+		On("GetStats", mock.Anything, 3).
+		Return(database.CatalogueStats{}, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/stats?top=3", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+}
+
+func (s *StatsApiHandlerSuite) TestGetStatsShouldReturn500IfFailed() {
+
+	// arrange
+	s.repo.
+		On("GetStats", mock.Anything, mock.Anything).
+		Return(nil, errors.New("database is locked"))
+
+	s.req = httptest.NewRequest(http.MethodGet, "/stats", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusInternalServerError, s.res.Code)
+}
+
+func (s *StatsApiHandlerSuite) TestGetAuthorStatsShouldReturn200() {
+
+	// arrange
+	first, last := 1984, 2015
+	stats := database.AuthorStats{ID: 1, Name: "Brian Kernighan", Books: 3, FirstPublicationYear: &first, LastPublicationYear: &last, CoAuthors: 2}
+
+	s.repo.
+		On("GetAuthorStats", mock.Anything, 1).
+		Return(stats, nil)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/authors/1/stats", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	var result database.AuthorStats
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &result))
+
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal(stats, result)
+}
+
+func (s *StatsApiHandlerSuite) TestGetAuthorStatsShouldReturn404IfNotFound() {
+
+	// arrange
+	s.repo.
+		On("GetAuthorStats", mock.Anything, 1).
+		Return(nil, gorm.ErrRecordNotFound)
+
+	s.req = httptest.NewRequest(http.MethodGet, "/authors/1/stats", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertExpectations(s.T())
+	s.Assert().Equal(http.StatusNotFound, s.res.Code)
+}
+
+func (s *StatsApiHandlerSuite) TestGetAuthorStatsShouldReturn400IfIdIsInvalid() {
+
+	// arrange
+	s.req = httptest.NewRequest(http.MethodGet, "/authors/abc/stats", nil)
+
+	// act
+	s.router.ServeHTTP(s.res, s.req)
+
+	// assert
+	s.repo.AssertNotCalled(s.T(), "GetAuthorStats", mock.Anything, mock.Anything)
+	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
+}
+
+func TestStatsApiHandlerSuite(t *testing.T) {
+	suite.Run(t, new(StatsApiHandlerSuite))
+}
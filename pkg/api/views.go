@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+)
+
+type BookView struct {
+	ID              uint
+	Name            string
+	Edition         string
+	PublicationYear int
+	Version         uint
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       *time.Time      `json:",omitempty"`
+	Authors         []AuthorSummary `json:",omitempty"`
+}
+
+type AuthorSummary struct {
+	ID    uint
+	Name  string
+	Books []BookSummary `json:",omitempty"`
+}
+
+type BookSummary struct {
+	ID   uint
+	Name string
+}
+
+func NewBookView(b domain.Book) BookView {
+	v := BookView{
+		ID:              b.ID,
+		Name:            b.Name,
+		Edition:         b.Edition,
+		PublicationYear: b.PublicationYear,
+		Version:         b.Version,
+		CreatedAt:       b.CreatedAt,
+		UpdatedAt:       b.UpdatedAt,
+	}
+
+	if b.DeletedAt.Valid {
+		v.DeletedAt = &b.DeletedAt.Time
+	}
+
+	for _, a := range b.Authors {
+		summary := AuthorSummary{ID: a.ID, Name: a.Name}
+		for _, ab := range a.Books {
+			summary.Books = append(summary.Books, BookSummary{ID: ab.ID, Name: ab.Name})
+		}
+		v.Authors = append(v.Authors, summary)
+	}
+
+	return v
+}
+
+func NewBookViews(books []domain.Book) []BookView {
+	views := make([]BookView, 0, len(books))
+	for _, b := range books {
+		views = append(views, NewBookView(b))
+	}
+
+	return views
+}
+
+type AuthorView struct {
+	ID        uint
+	Name      string
+	Version   uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func NewAuthorView(a domain.Author) AuthorView {
+	return AuthorView{
+		ID:        a.ID,
+		Name:      a.Name,
+		Version:   a.Version,
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+	}
+}
+
+func NewAuthorViews(authors []domain.Author) []AuthorView {
+	views := make([]AuthorView, 0, len(authors))
+	for _, a := range authors {
+		views = append(views, NewAuthorView(a))
+	}
+
+	return views
+}
+
+type HistoryView struct {
+	ID        uint
+	Action    string
+	Actor     string
+	RequestID string
+	CreatedAt time.Time
+	Before    json.RawMessage `json:",omitempty"`
+	After     json.RawMessage `json:",omitempty"`
+	Changes   []FieldChange
+}
+
+type FieldChange struct {
+	Field  string
+	Before interface{}
+	After  interface{}
+}
+
+func NewHistoryViews(entries []domain.History) []HistoryView {
+	views := make([]HistoryView, 0, len(entries))
+	for _, h := range entries {
+		v := HistoryView{
+			ID:        h.ID,
+			Action:    h.Action,
+			Actor:     h.Actor,
+			RequestID: h.RequestID,
+			CreatedAt: h.CreatedAt,
+			Changes:   Diff(h.Before, h.After),
+		}
+
+		if len(h.Before) > 0 {
+			v.Before = json.RawMessage(h.Before)
+		}
+
+		if len(h.After) > 0 {
+			v.After = json.RawMessage(h.After)
+		}
+
+		views = append(views, v)
+	}
+
+	return views
+}
+
+// Diff lists the fields that differ between two JSON snapshots, in field name
+// order. An empty snapshot stands for an entity that did not exist.
+func Diff(before, after string) []FieldChange {
+	b := map[string]interface{}{}
+	a := map[string]interface{}{}
+	_ = json.Unmarshal([]byte(before), &b)
+	_ = json.Unmarshal([]byte(after), &a)
+
+	fields := []string{}
+	for field := range b {
+		fields = append(fields, field)
+	}
+
+	for field := range a {
+		if _, ok := b[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+
+	sort.Strings(fields)
+
+	changes := []FieldChange{}
+	for _, field := range fields {
+		if !reflect.DeepEqual(b[field], a[field]) {
+			changes = append(changes, FieldChange{Field: field, Before: b[field], After: a[field]})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+)
+
+type cachedAuthorsRepository struct {
+	next  AuthorsRepository
+	cache *Cache
+}
+
+// NewCachedAuthorsRepository caches the listings read from next in cache.
+// Every write through it clears the cache.
+func NewCachedAuthorsRepository(next AuthorsRepository, cache *Cache) AuthorsRepository {
+	return &cachedAuthorsRepository{
+		next:  next,
+		cache: cache,
+	}
+}
+
+func (a *cachedAuthorsRepository) GetAll(ctx context.Context, r GetAuthorsRequest) ([]domain.Author, error) {
+	if uctx.ReadYourWrites(ctx) {
+		return a.next.GetAll(ctx, r)
+	}
+
+	authors, err := a.cache.Get(ctx, tenantKey(ctx, authorsKey(r)), func(ctx context.Context) (interface{}, error) {
+		return a.next.GetAll(ctx, r)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return authors.([]domain.Author), nil
+}
+
+func (a *cachedAuthorsRepository) Count(ctx context.Context, r GetAuthorsRequest) (int64, error) {
+	if uctx.ReadYourWrites(ctx) {
+		return a.next.Count(ctx, r)
+	}
+
+	r.Limit, r.Offset, r.After, r.Sort = 0, 0, nil, nil
+	count, err := a.cache.Get(ctx, tenantKey(ctx, "count:"+authorsKey(r)), func(ctx context.Context) (interface{}, error) {
+		return a.next.Count(ctx, r)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count.(int64), nil
+}
+
+func authorsKey(r GetAuthorsRequest) string {
+	after := cursorKey(r.After)
+	r.After = nil
+	return fmt.Sprintf("authors:%+v:%s", r, after)
+}
+
+func (a *cachedAuthorsRepository) GetAuthor(ctx context.Context, id int) (domain.Author, error) {
+	if uctx.ReadYourWrites(ctx) {
+		return a.next.GetAuthor(ctx, id)
+	}
+
+	author, err := a.cache.Get(ctx, tenantKey(ctx, fmt.Sprintf("author:%d", id)), func(ctx context.Context) (interface{}, error) {
+		return a.next.GetAuthor(ctx, id)
+	})
+
+	if err != nil {
+		return domain.Author{}, err
+	}
+
+	result, _ := author.(domain.Author)
+	return result, nil
+}
+
+func (a *cachedAuthorsRepository) Create(ctx context.Context, author domain.Author) (uint, error) {
+	defer a.cache.Clear()
+	return a.next.Create(ctx, author)
+}
+
+func (a *cachedAuthorsRepository) Update(ctx context.Context, id int, author domain.Author) error {
+	defer a.cache.Clear()
+	return a.next.Update(ctx, id, author)
+}
+
+func (a *cachedAuthorsRepository) Delete(ctx context.Context, id int, version uint, unlink bool) error {
+	defer a.cache.Clear()
+	return a.next.Delete(ctx, id, version, unlink)
+}
+
+func (a *cachedAuthorsRepository) GetTrash(ctx context.Context, r PageRequest) ([]domain.Author, error) {
+	return a.next.GetTrash(ctx, r)
+}
+
+func (a *cachedAuthorsRepository) Restore(ctx context.Context, id int) error {
+	defer a.cache.Clear()
+	return a.next.Restore(ctx, id)
+}
+
+func (a *cachedAuthorsRepository) Merge(ctx context.Context, id int, into int) error {
+	defer a.cache.Clear()
+	return a.next.Merge(ctx, id, into)
+}
+
+func (a *cachedAuthorsRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	defer a.cache.Clear()
+	return a.next.Purge(ctx, before)
+}
+
+func (a *cachedAuthorsRepository) GetHistory(ctx context.Context, id int) ([]domain.History, error) {
+	return a.next.GetHistory(ctx, id)
+}
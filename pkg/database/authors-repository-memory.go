@@ -0,0 +1,284 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"gorm.io/gorm"
+)
+
+type authorsMemoryRepository struct {
+	store *MemoryStore
+}
+
+// NewAuthorsMemoryRepository returns an AuthorsRepository kept in store.
+func NewAuthorsMemoryRepository(store *MemoryStore) AuthorsRepository {
+	return &authorsMemoryRepository{
+		store: store,
+	}
+}
+
+func (a *authorsMemoryRepository) GetAll(ctx context.Context, r GetAuthorsRequest) ([]domain.Author, error) {
+	a.store.mu.RLock()
+	defer a.store.mu.RUnlock()
+
+	authors := a.find(ctx, r)
+	start, end := page(len(authors), r.Limit, r.Offset)
+	return authors[start:end], nil
+}
+
+func (a *authorsMemoryRepository) Count(ctx context.Context, r GetAuthorsRequest) (int64, error) {
+	a.store.mu.RLock()
+	defer a.store.mu.RUnlock()
+
+	r.After = nil
+	return int64(len(a.find(ctx, r))), nil
+}
+
+// find returns the authors matching r in the order of its sort, without
+// paging them. The store must be locked.
+func (a *authorsMemoryRepository) find(ctx context.Context, r GetAuthorsRequest) []domain.Author {
+	name := strings.ToLower(r.Name)
+	tenant := uctx.Tenant(ctx)
+
+	authors := []domain.Author{}
+	for _, author := range a.store.authors {
+		if author.TenantID != tenant || author.DeletedAt.Valid ||
+			!strings.Contains(strings.ToLower(author.Name), name) ||
+			!matches(author.Name, r.Query) ||
+			!r.Sort.follows(authorKeys(author, r.Sort), author.ID, r.After) {
+			continue
+		}
+
+		authors = append(authors, author)
+	}
+
+	sort.Slice(authors, r.Sort.less(
+		func(i int) []interface{} { return authorKeys(authors[i], r.Sort) },
+		func(i int) uint { return authors[i].ID }))
+
+	return authors
+}
+
+func (a *authorsMemoryRepository) Create(ctx context.Context, r domain.Author) (uint, error) {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+
+	now := time.Now()
+	author := domain.Author{
+		TenantID: uctx.Tenant(ctx),
+		Name:     r.Name,
+		Version:  1,
+	}
+	author.CreatedAt = now
+	author.UpdatedAt = now
+
+	a.store.lastAuthor++
+	author.ID = a.store.lastAuthor
+
+	if err := a.store.record(ctx, AuthorEntity, author.ID, ActionCreate, nil, snapshotAuthor(author)); err != nil {
+		return 0, err
+	}
+
+	a.store.authors[author.ID] = author
+	return author.ID, nil
+}
+
+func (a *authorsMemoryRepository) GetAuthor(ctx context.Context, id int) (domain.Author, error) {
+	a.store.mu.RLock()
+	defer a.store.mu.RUnlock()
+
+	author, ok := a.store.authors[uint(id)]
+	if !ok || author.TenantID != uctx.Tenant(ctx) || author.DeletedAt.Valid {
+		return domain.Author{}, gorm.ErrRecordNotFound
+	}
+
+	return author, nil
+}
+
+func (a *authorsMemoryRepository) Update(ctx context.Context, id int, r domain.Author) error {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+
+	author, ok := a.store.authors[uint(id)]
+	if !ok || author.TenantID != uctx.Tenant(ctx) || author.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+
+	if r.Version > 0 && r.Version != author.Version {
+		return ErrVersionMismatch
+	}
+
+	before := snapshotAuthor(author)
+
+	author.Name = r.Name
+	author.Version++
+	author.UpdatedAt = time.Now()
+
+	if err := a.store.record(ctx, AuthorEntity, author.ID, ActionUpdate, before, snapshotAuthor(author)); err != nil {
+		return err
+	}
+
+	a.store.authors[author.ID] = author
+	return nil
+}
+
+func (a *authorsMemoryRepository) Delete(ctx context.Context, id int, version uint, unlink bool) error {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+
+	author, ok := a.store.authors[uint(id)]
+	if !ok || author.TenantID != uctx.Tenant(ctx) || author.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+
+	if version > 0 && version != author.Version {
+		return ErrVersionMismatch
+	}
+
+	books := []domain.Book{}
+	for _, bookID := range a.store.authorBooks(author.ID) {
+		if b, ok := a.store.books[bookID]; ok && !b.DeletedAt.Valid {
+			books = append(books, b)
+		}
+	}
+
+	if len(books) > 0 && !unlink {
+		return ErrAuthorHasBooks
+	}
+
+	if err := a.store.record(ctx, AuthorEntity, author.ID, ActionDelete, snapshotAuthor(author), nil); err != nil {
+		return err
+	}
+
+	for _, book := range books {
+		if err := a.store.relink(ctx, book, ActionUnlink, linkSnapshot{AuthorID: author.ID}, nil); err != nil {
+			return err
+		}
+		delete(a.store.links[book.ID], author.ID)
+	}
+
+	author.DeletedAt = deletedNow()
+	a.store.authors[author.ID] = author
+	return nil
+}
+
+func (a *authorsMemoryRepository) GetTrash(ctx context.Context, r PageRequest) ([]domain.Author, error) {
+	a.store.mu.RLock()
+	defer a.store.mu.RUnlock()
+
+	tenant := uctx.Tenant(ctx)
+
+	authors := []domain.Author{}
+	for _, author := range a.store.authors {
+		if author.TenantID == tenant && author.DeletedAt.Valid {
+			authors = append(authors, author)
+		}
+	}
+
+	sort.Slice(authors, func(i, j int) bool {
+		if !authors[i].DeletedAt.Time.Equal(authors[j].DeletedAt.Time) {
+			return authors[i].DeletedAt.Time.After(authors[j].DeletedAt.Time)
+		}
+		return authors[i].ID < authors[j].ID
+	})
+
+	start, end := page(len(authors), r.Limit, r.Offset)
+	return authors[start:end], nil
+}
+
+func (a *authorsMemoryRepository) Restore(ctx context.Context, id int) error {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+
+	author, ok := a.store.authors[uint(id)]
+	if !ok || author.TenantID != uctx.Tenant(ctx) || !author.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+
+	author.DeletedAt = gorm.DeletedAt{}
+	author.Version++
+	author.UpdatedAt = time.Now()
+
+	if err := a.store.record(ctx, AuthorEntity, author.ID, ActionRestore, nil, snapshotAuthor(author)); err != nil {
+		return err
+	}
+
+	a.store.authors[author.ID] = author
+	return nil
+}
+
+// Merge moves the books of the author with id to the author into, which get a
+// new version, and deletes it. The memory store has no outbox, so nothing is
+// published.
+func (a *authorsMemoryRepository) Merge(ctx context.Context, id int, into int) error {
+	if id == into {
+		return ErrMergeIntoItself
+	}
+
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+
+	tenant := uctx.Tenant(ctx)
+
+	author, ok := a.store.authors[uint(id)]
+	if !ok || author.TenantID != tenant || author.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+
+	target, ok := a.store.authors[uint(into)]
+	if !ok || target.TenantID != tenant || target.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+
+	if err := a.store.record(ctx, AuthorEntity, author.ID, ActionDelete, snapshotAuthor(author), nil); err != nil {
+		return err
+	}
+
+	for bookID, authors := range a.store.links {
+		if authors[author.ID] {
+			delete(authors, author.ID)
+			authors[target.ID] = true
+
+			book := a.store.books[bookID]
+			book.Version++
+			book.UpdatedAt = time.Now()
+			a.store.books[bookID] = book
+		}
+	}
+
+	author.DeletedAt = deletedNow()
+	a.store.authors[author.ID] = author
+	return nil
+}
+
+func (a *authorsMemoryRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+
+	tenant := uctx.Tenant(ctx)
+
+	var purged int64
+	for id, author := range a.store.authors {
+		if author.TenantID == tenant && author.DeletedAt.Valid && author.DeletedAt.Time.Before(before) {
+			delete(a.store.authors, id)
+			for _, authors := range a.store.links {
+				delete(authors, id)
+			}
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+func (a *authorsMemoryRepository) GetHistory(ctx context.Context, id int) ([]domain.History, error) {
+	a.store.mu.RLock()
+	defer a.store.mu.RUnlock()
+
+	return a.store.entityHistory(ctx, AuthorEntity, id), nil
+}
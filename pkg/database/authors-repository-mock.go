@@ -1,6 +1,9 @@
 package database
 
 import (
+	"context"
+	"time"
+
 	"github.com/jedielson/bookstore/pkg/domain"
 	"github.com/stretchr/testify/mock"
 )
@@ -13,13 +16,99 @@ func NewAuthorsRepositoryMock() *AuthorsRepositoryMock {
 	return &AuthorsRepositoryMock{}
 }
 
-func (m *AuthorsRepositoryMock) GetAll(name string, limit int, offset int) []domain.Author {
-	args := m.Called(name, limit, offset)
+func (m *AuthorsRepositoryMock) GetAll(ctx context.Context, r GetAuthorsRequest) ([]domain.Author, error) {
+	args := m.Called(ctx, r)
+	bb, ok := args.Get(0).([]domain.Author)
+
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return bb, args.Error(1)
+}
+
+func (m *AuthorsRepositoryMock) Count(ctx context.Context, r GetAuthorsRequest) (int64, error) {
+	args := m.Called(ctx, r)
+	count, ok := args.Get(0).(int64)
+
+	if !ok {
+		count = 0
+	}
+
+	return count, args.Error(1)
+}
+
+func (m *AuthorsRepositoryMock) GetAuthor(ctx context.Context, id int) (domain.Author, error) {
+	args := m.Called(ctx, id)
+	a, ok := args.Get(0).(domain.Author)
+
+	if !ok {
+		a = domain.Author{}
+	}
+
+	return a, args.Error(1)
+}
+
+func (m *AuthorsRepositoryMock) Update(ctx context.Context, id int, author domain.Author) error {
+	args := m.Called(ctx, id, author)
+	return args.Error(0)
+}
+
+func (m *AuthorsRepositoryMock) Delete(ctx context.Context, id int, version uint, unlink bool) error {
+	args := m.Called(ctx, id, version, unlink)
+	return args.Error(0)
+}
+
+func (m *AuthorsRepositoryMock) Create(ctx context.Context, author domain.Author) (uint, error) {
+	args := m.Called(ctx, author)
+	id, ok := args.Get(0).(uint)
+
+	if !ok {
+		id = 0
+	}
+
+	return id, args.Error(1)
+}
+
+func (m *AuthorsRepositoryMock) GetTrash(ctx context.Context, r PageRequest) ([]domain.Author, error) {
+	args := m.Called(ctx, r)
 	bb, ok := args.Get(0).([]domain.Author)
 
 	if !ok {
-		return nil
+		return nil, args.Error(1)
+	}
+
+	return bb, args.Error(1)
+}
+
+func (m *AuthorsRepositoryMock) Restore(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *AuthorsRepositoryMock) Merge(ctx context.Context, id int, into int) error {
+	args := m.Called(ctx, id, into)
+	return args.Error(0)
+}
+
+func (m *AuthorsRepositoryMock) Purge(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	count, ok := args.Get(0).(int64)
+
+	if !ok {
+		count = 0
+	}
+
+	return count, args.Error(1)
+}
+
+func (m *AuthorsRepositoryMock) GetHistory(ctx context.Context, id int) ([]domain.History, error) {
+	args := m.Called(ctx, id)
+	hh, ok := args.Get(0).([]domain.History)
+
+	if !ok {
+		return nil, args.Error(1)
 	}
 
-	return bb
+	return hh, args.Error(1)
 }
@@ -1,13 +1,37 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jedielson/bookstore/pkg/domain"
+	"gorm.io/gorm"
 )
 
+type GetAuthorsRequest struct {
+	Name   string
+	Query  string
+	Limit  int
+	Offset int
+	After  *Cursor
+	Sort   Sort
+}
+
+// AuthorsRepository stores the authors.
 type AuthorsRepository interface {
-	GetAll(name string, limit int, offset int) []domain.Author
+	GetAll(ctx context.Context, r GetAuthorsRequest) ([]domain.Author, error)
+	Count(ctx context.Context, r GetAuthorsRequest) (int64, error)
+	GetAuthor(ctx context.Context, id int) (domain.Author, error)
+	Create(ctx context.Context, author domain.Author) (uint, error)
+	Update(ctx context.Context, id int, author domain.Author) error
+	Delete(ctx context.Context, id int, version uint, unlink bool) error
+	GetTrash(ctx context.Context, r PageRequest) ([]domain.Author, error)
+	Restore(ctx context.Context, id int) error
+	Merge(ctx context.Context, id int, into int) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	GetHistory(ctx context.Context, id int) ([]domain.History, error)
 }
 
 type authorsRepository struct {
@@ -20,19 +44,225 @@ func NewAuthorsRepository(m DBManager) AuthorsRepository {
 	}
 }
 
-func (a *authorsRepository) GetAll(name string, limit int, offset int) []domain.Author {
-	var records []domain.Author
-	var db = a.manager.GetDB()
+func (a *authorsRepository) db(ctx context.Context) *gorm.DB {
+	return a.manager.GetDB().WithContext(ctx)
+}
+
+// read returns the connection for queries, which may be a read-only replica.
+func (a *authorsRepository) read(ctx context.Context) *gorm.DB {
+	return a.manager.GetReadDB(ctx).WithContext(ctx)
+}
+
+func (a *authorsRepository) GetAll(ctx context.Context, r GetAuthorsRequest) ([]domain.Author, error) {
+	records := []domain.Author{}
+	var db = filterAuthors(a.read(ctx), r)
 
-	if len(name) > 0 {
-		db = db.Where("name LIKE ?", fmt.Sprintf("%%%s%%", name))
+	db = search(db, "authors", r.Query, len(r.Sort) == 0)
+	db = keyset(db, "authors", r.Sort, r.After)
+
+	err := db.Limit(r.Limit).Offset(r.Offset).Find(&records).Error
+	return records, err
+}
+
+// Count counts the authors GetAll lists for r over every page, ignoring its
+// limit, offset and cursor.
+func (a *authorsRepository) Count(ctx context.Context, r GetAuthorsRequest) (int64, error) {
+	var count int64
+
+	db, _ := match(filterAuthors(a.read(ctx), r), "authors", r.Query)
+	err := db.Model(&domain.Author{}).Count(&count).Error
+	return count, err
+}
+
+func filterAuthors(db *gorm.DB, r GetAuthorsRequest) *gorm.DB {
+	if len(r.Name) > 0 {
+		db = db.Where("authors.name LIKE ?", fmt.Sprintf("%%%s%%", r.Name))
 	}
 
-	err := db.Limit(limit).Offset(offset).Find(&records)
+	return db
+}
+
+func (a *authorsRepository) Create(ctx context.Context, r domain.Author) (uint, error) {
+	author := domain.Author{
+		Name:    r.Name,
+		Version: 1,
+	}
+
+	err := a.db(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&author).Error; err != nil {
+			return err
+		}
+
+		return record(ctx, tx, AuthorEntity, author.ID, ActionCreate, nil, snapshotAuthor(author))
+	})
+
+	return author.ID, err
+}
+
+func (a *authorsRepository) GetAuthor(ctx context.Context, id int) (domain.Author, error) {
+	author := domain.Author{}
+	err := a.read(ctx).First(&author, id).Error
+	return author, err
+}
+
+// Update renames the author with id. When r.Version is set, the author is
+// only updated if it still has that version, otherwise ErrVersionMismatch is
+// returned.
+func (a *authorsRepository) Update(ctx context.Context, id int, r domain.Author) error {
+	return a.db(ctx).Transaction(func(tx *gorm.DB) error {
+		author := domain.Author{}
+		if err := tx.First(&author, id).Error; err != nil {
+			return err
+		}
+
+		if r.Version > 0 && r.Version != author.Version {
+			return ErrVersionMismatch
+		}
+
+		before := snapshotAuthor(author)
+		author.Name = r.Name
+
+		if err := bumpVersion(tx.Model(&author), author.Version, map[string]interface{}{"name": author.Name}); err != nil {
+			return err
+		}
+
+		return record(ctx, tx, AuthorEntity, author.ID, ActionUpdate, before, snapshotAuthor(author))
+	})
+}
+
+var ErrAuthorHasBooks = errors.New("author still has books")
+
+// Delete soft-deletes the author with id. A version of 0 matches any version.
+// An author with books that are not deleted is only deleted when unlink is
+// set, in which case it is removed from them like RemoveAuthor does. The links
+// to books in the trash are kept, so that restoring both brings them back.
+func (a *authorsRepository) Delete(ctx context.Context, id int, version uint, unlink bool) error {
+	return a.db(ctx).Transaction(func(tx *gorm.DB) error {
+		author := domain.Author{}
+		if err := tx.First(&author, id).Error; err != nil {
+			return err
+		}
+
+		if version > 0 && version != author.Version {
+			return ErrVersionMismatch
+		}
+
+		books := []domain.Book{}
+		err := tx.Where("books.id IN (SELECT book_id FROM author_books WHERE author_id = ?)", id).Order("id").Find(&books).Error
+		if err != nil {
+			return err
+		}
+
+		if len(books) > 0 && !unlink {
+			return ErrAuthorHasBooks
+		}
+
+		result := tx.Where("version = ?", author.Version).Delete(&domain.Author{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrVersionMismatch
+		}
+
+		if err := record(ctx, tx, AuthorEntity, author.ID, ActionDelete, snapshotAuthor(author), nil); err != nil {
+			return err
+		}
+
+		for _, book := range books {
+			if err := tx.Where("book_id = ? AND author_id = ?", book.ID, author.ID).Delete(&domain.AuthorBook{}).Error; err != nil {
+				return err
+			}
+
+			if err := relink(ctx, tx, book, ActionUnlink, linkSnapshot{AuthorID: author.ID}, nil); err != nil {
+				return err
+			}
+		}
 
-	if err.Error != nil {
 		return nil
+	})
+}
+
+func (a *authorsRepository) GetTrash(ctx context.Context, r PageRequest) ([]domain.Author, error) {
+	authors := []domain.Author{}
+	err := trash(a.db(ctx), r, &authors)
+	return authors, err
+}
+
+func (a *authorsRepository) Restore(ctx context.Context, id int) error {
+	return a.db(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := restore(tx, &domain.Author{}, id); err != nil {
+			return err
+		}
+
+		author := domain.Author{}
+		if err := tx.First(&author, id).Error; err != nil {
+			return err
+		}
+
+		return record(ctx, tx, AuthorEntity, author.ID, ActionRestore, nil, snapshotAuthor(author))
+	})
+}
+
+var ErrMergeIntoItself = errors.New("author can not be merged into itself")
+
+// Merge moves the books of the author with id to the author into and deletes
+// it, which is published as an AuthorMerged event. The books get a new
+// version, since their authors changed.
+func (a *authorsRepository) Merge(ctx context.Context, id int, into int) error {
+	if id == into {
+		return ErrMergeIntoItself
 	}
 
-	return records
+	return a.db(ctx).Transaction(func(tx *gorm.DB) error {
+		author := domain.Author{}
+		if err := tx.First(&author, id).Error; err != nil {
+			return err
+		}
+
+		target := domain.Author{}
+		if err := tx.First(&target, into).Error; err != nil {
+			return err
+		}
+
+		books := []uint{}
+		if err := tx.Model(&domain.AuthorBook{}).Where("author_id = ?", id).Order("book_id").Pluck("book_id", &books).Error; err != nil {
+			return err
+		}
+
+		shared := tx.Model(&domain.AuthorBook{}).Select("book_id").Where("author_id = ?", into)
+		if err := tx.Where("author_id = ? AND book_id IN (?)", id, shared).Delete(&domain.AuthorBook{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&domain.AuthorBook{}).Where("author_id = ?", id).Update("author_id", into).Error; err != nil {
+			return err
+		}
+
+		if len(books) > 0 {
+			err := tx.Model(&domain.Book{}).Where("id IN ?", books).Update("version", gorm.Expr("version + 1")).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(&domain.Author{}, id).Error; err != nil {
+			return err
+		}
+
+		if err := record(ctx, tx, AuthorEntity, author.ID, ActionDelete, snapshotAuthor(author), nil); err != nil {
+			return err
+		}
+
+		return publish(ctx, tx, AuthorMerged, AuthorEntity, author.ID, AuthorMergedPayload{ID: author.ID, Into: target.ID, Books: books})
+	})
+}
+
+func (a *authorsRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purge(a.db(ctx), &domain.Author{}, "author_id", before)
+}
+
+func (a *authorsRepository) GetHistory(ctx context.Context, id int) ([]domain.History, error) {
+	return history(a.db(ctx), AuthorEntity, id)
 }
@@ -0,0 +1,318 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"gorm.io/gorm"
+)
+
+// A backup is a JSON Lines stream: a header, one line per author, book and
+// author_books link, and a footer with the counts. It does not depend on the
+// database driver, so it can be restored anywhere Migrate runs.
+const (
+	BackupFormat  = "bookstore-backup"
+	BackupVersion = 1
+)
+
+const (
+	headerLine     = "header"
+	authorLine     = "author"
+	bookLine       = "book"
+	authorBookLine = "author_book"
+	footerLine     = "footer"
+)
+
+var (
+	ErrNotEmpty      = errors.New("database is not empty")
+	ErrInvalidBackup = errors.New("backup is invalid")
+)
+
+type BackupCounts struct {
+	Authors     int64 `json:"authors"`
+	Books       int64 `json:"books"`
+	AuthorBooks int64 `json:"author_books"`
+}
+
+type backupLine struct {
+	Type string `json:"type"`
+
+	Format    string        `json:"format,omitempty"`
+	Version   int           `json:"version,omitempty"`
+	Schema    int           `json:"schema,omitempty"`
+	Tenant    string        `json:"tenant,omitempty"`
+	CreatedAt *time.Time    `json:"created_at,omitempty"`
+	Counts    *BackupCounts `json:"counts,omitempty"`
+
+	Author     *authorRecord     `json:"author,omitempty"`
+	Book       *bookRecord       `json:"book,omitempty"`
+	AuthorBook *authorBookRecord `json:"author_book,omitempty"`
+}
+
+type authorRecord struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Version   uint       `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type bookRecord struct {
+	ID              uint       `json:"id"`
+	Name            string     `json:"name"`
+	Edition         string     `json:"edition"`
+	PublicationYear int        `json:"publication_year"`
+	Version         uint       `json:"version"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+}
+
+type authorBookRecord struct {
+	AuthorID uint `json:"author_id"`
+	BookID   uint `json:"book_id"`
+}
+
+// Backup writes every author, book and link of the tenant of ctx to w,
+// soft-deleted ones included. It reads within a single transaction, so the
+// dump is consistent even while the database is being written.
+func Backup(ctx context.Context, db *gorm.DB, w io.Writer) (BackupCounts, error) {
+	counts := BackupCounts{}
+	encoder := json.NewEncoder(w)
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		header := backupLine{Type: headerLine, Format: BackupFormat, Version: BackupVersion, Schema: SchemaVersion, Tenant: uctx.Tenant(ctx), CreatedAt: &now}
+		if err := encoder.Encode(header); err != nil {
+			return err
+		}
+
+		err := eachRow(tx.Unscoped().Model(&domain.Author{}).Order("id"), func(rows *sql.Rows) error {
+			a := domain.Author{}
+			if err := tx.ScanRows(rows, &a); err != nil {
+				return err
+			}
+
+			counts.Authors++
+			return encoder.Encode(backupLine{Type: authorLine, Author: &authorRecord{
+				ID:        a.ID,
+				Name:      a.Name,
+				Version:   a.Version,
+				CreatedAt: a.CreatedAt,
+				UpdatedAt: a.UpdatedAt,
+				DeletedAt: deletedAt(a.DeletedAt),
+			}})
+		})
+
+		if err != nil {
+			return err
+		}
+
+		err = eachRow(tx.Unscoped().Model(&domain.Book{}).Order("id"), func(rows *sql.Rows) error {
+			b := domain.Book{}
+			if err := tx.ScanRows(rows, &b); err != nil {
+				return err
+			}
+
+			counts.Books++
+			return encoder.Encode(backupLine{Type: bookLine, Book: &bookRecord{
+				ID:              b.ID,
+				Name:            b.Name,
+				Edition:         b.Edition,
+				PublicationYear: b.PublicationYear,
+				Version:         b.Version,
+				CreatedAt:       b.CreatedAt,
+				UpdatedAt:       b.UpdatedAt,
+				DeletedAt:       deletedAt(b.DeletedAt),
+			}})
+		})
+
+		if err != nil {
+			return err
+		}
+
+		err = eachRow(tx.Model(&domain.AuthorBook{}).Select("author_id, book_id").Order("author_id, book_id"), func(rows *sql.Rows) error {
+			link := authorBookRecord{}
+			if err := rows.Scan(&link.AuthorID, &link.BookID); err != nil {
+				return err
+			}
+
+			counts.AuthorBooks++
+			return encoder.Encode(backupLine{Type: authorBookLine, AuthorBook: &link})
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return encoder.Encode(backupLine{Type: footerLine, Counts: &counts})
+	})
+
+	return counts, err
+}
+
+func eachRow(db *gorm.DB, fn func(rows *sql.Rows) error) error {
+	rows, err := db.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func deletedAt(d gorm.DeletedAt) *time.Time {
+	if !d.Valid {
+		return nil
+	}
+
+	return &d.Time
+}
+
+func toDeletedAt(t *time.Time) gorm.DeletedAt {
+	if t == nil {
+		return gorm.DeletedAt{}
+	}
+
+	return gorm.DeletedAt{Time: *t, Valid: true}
+}
+
+// Restore loads a backup written by Backup into the tenant of ctx, which must
+// have no rows yet. The tenant named in the header is only informational, so a
+// backup can be moved to another tenant. The ids are kept, so they must not be
+// used by another tenant. Backups of an older schema are restored too: the
+// lines they carry have not changed, and the tables added since, such as the
+// tenants, the history or the outbox, are left for Migrate to fill. Everything
+// is loaded in one transaction, which is rolled back unless the backup is
+// complete and the counts of its footer match both the lines read and the rows
+// stored.
+func Restore(ctx context.Context, db *gorm.DB, r io.Reader) (BackupCounts, error) {
+	counts := BackupCounts{}
+	decoder := json.NewDecoder(r)
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := ensureEmpty(tx); err != nil {
+			return err
+		}
+
+		header := backupLine{}
+		if err := decoder.Decode(&header); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+
+		if header.Type != headerLine || header.Format != BackupFormat || header.Version != BackupVersion {
+			return fmt.Errorf("%w: not a %s version %d", ErrInvalidBackup, BackupFormat, BackupVersion)
+		}
+
+		if header.Schema < 1 || header.Schema > SchemaVersion {
+			return fmt.Errorf("%w: schema version %d can not be restored into schema version %d", ErrInvalidBackup, header.Schema, SchemaVersion)
+		}
+
+		for {
+			line := backupLine{}
+			if err := decoder.Decode(&line); err == io.EOF {
+				return fmt.Errorf("%w: footer is missing, the backup is truncated", ErrInvalidBackup)
+			} else if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+			}
+
+			if line.Type == footerLine {
+				return verifyCounts(tx, line.Counts, counts)
+			}
+
+			if err := restoreLine(tx, line, &counts); err != nil {
+				return err
+			}
+		}
+	})
+
+	return counts, err
+}
+
+func ensureEmpty(tx *gorm.DB) error {
+	var authors, books, links int64
+
+	if err := tx.Unscoped().Model(&domain.Author{}).Count(&authors).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Unscoped().Model(&domain.Book{}).Count(&books).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&domain.AuthorBook{}).Count(&links).Error; err != nil {
+		return err
+	}
+
+	if authors+books+links > 0 {
+		return ErrNotEmpty
+	}
+
+	return nil
+}
+
+func restoreLine(tx *gorm.DB, line backupLine, counts *BackupCounts) error {
+	switch {
+	case line.Type == authorLine && line.Author != nil:
+		a := line.Author
+		author := domain.Author{Name: a.Name, Version: a.Version, CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt, DeletedAt: toDeletedAt(a.DeletedAt)}
+		author.ID = a.ID
+
+		counts.Authors++
+		return tx.Create(&author).Error
+
+	case line.Type == bookLine && line.Book != nil:
+		b := line.Book
+		book := domain.Book{Name: b.Name, Edition: b.Edition, PublicationYear: b.PublicationYear, Version: b.Version, CreatedAt: b.CreatedAt, UpdatedAt: b.UpdatedAt, DeletedAt: toDeletedAt(b.DeletedAt)}
+		book.ID = b.ID
+
+		counts.Books++
+		return tx.Create(&book).Error
+
+	case line.Type == authorBookLine && line.AuthorBook != nil:
+		counts.AuthorBooks++
+		return tx.Create(&domain.AuthorBook{AuthorID: line.AuthorBook.AuthorID, BookID: line.AuthorBook.BookID}).Error
+
+	default:
+		return fmt.Errorf("%w: unexpected %q line", ErrInvalidBackup, line.Type)
+	}
+}
+
+func verifyCounts(tx *gorm.DB, expected *BackupCounts, read BackupCounts) error {
+	if expected == nil || *expected != read {
+		return fmt.Errorf("%w: read %+v but the footer lists %+v", ErrInvalidBackup, read, expected)
+	}
+
+	stored := BackupCounts{}
+	if err := tx.Unscoped().Model(&domain.Author{}).Count(&stored.Authors).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Unscoped().Model(&domain.Book{}).Count(&stored.Books).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&domain.AuthorBook{}).Count(&stored.AuthorBooks).Error; err != nil {
+		return err
+	}
+
+	if stored != read {
+		return fmt.Errorf("%w: stored %+v but read %+v", ErrInvalidBackup, stored, read)
+	}
+
+	return nil
+}
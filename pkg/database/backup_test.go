@@ -0,0 +1,157 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"github.com/stretchr/testify/suite"
+)
+
+type BackupIntegrationSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	source DBManager
+	target DBManager
+}
+
+func (s *BackupIntegrationSuite) open(name string) DBManager {
+	manager := NewDbManager(fmt.Sprintf("file:%s-%s?mode=memory&cache=shared", s.T().Name(), name))
+	s.Require().NoError(manager.InitDb())
+	Migrate(manager.GetDB())
+	return manager
+}
+
+func (s *BackupIntegrationSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.source = s.open("source")
+	s.target = s.open("target")
+
+	repos := NewRepos(s.source)
+	author := &domain.Author{}
+	author.ID, _ = repos.Authors.Create(s.ctx, domain.Author{Name: "Rob Pike"})
+	repos.Authors.Create(s.ctx, domain.Author{Name: "Brian Kernighan"})
+
+	kept, _ := repos.Books.Create(s.ctx, domain.Book{Name: "The Go Programming Language", Authors: []*domain.Author{author}})
+	deleted, _ := repos.Books.Create(s.ctx, domain.Book{Name: "Deleted", Authors: []*domain.Author{author}})
+	s.Require().NoError(repos.Books.Update(s.ctx, int(kept), domain.Book{Name: "The Go Programming Language", Edition: "2"}))
+	s.Require().NoError(repos.Books.Delete(s.ctx, int(deleted), 0))
+}
+
+func (s *BackupIntegrationSuite) TearDownTest() {
+	s.Require().NoError(s.source.Close())
+	s.Require().NoError(s.target.Close())
+}
+
+func (s *BackupIntegrationSuite) backup() *bytes.Buffer {
+	buffer := &bytes.Buffer{}
+	_, err := Backup(s.ctx, s.source.GetDB(), buffer)
+	s.Require().NoError(err)
+	return buffer
+}
+
+func (s *BackupIntegrationSuite) TestRestoreShouldKeepIdsVersionsAndDeletedRows() {
+	// arrange
+	dump := s.backup()
+
+	// act
+	counts, err := Restore(s.ctx, s.target.GetDB(), dump)
+
+	// assert
+	s.Require().NoError(err)
+	s.Assert().Equal(BackupCounts{Authors: 2, Books: 2, AuthorBooks: 2}, counts)
+
+	books := NewBooksRepository(s.target)
+	book, err := books.GetBook(s.ctx, 1, ExpandAuthors)
+	s.Require().NoError(err)
+	s.Assert().Equal("2", book.Edition)
+	s.Assert().Equal(uint(2), book.Version)
+	s.Require().Len(book.Authors, 1)
+	s.Assert().Equal("Rob Pike", book.Authors[0].Name)
+
+	trash, err := books.GetTrash(s.ctx, PageRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(trash, 1)
+	s.Assert().Equal(uint(2), trash[0].ID)
+
+	found, err := books.GetAll(s.ctx, GetAllRequest{Query: "go"})
+	s.Require().NoError(err)
+	s.Assert().Len(found, 1)
+}
+
+func (s *BackupIntegrationSuite) TestRestoreShouldAcceptOlderSchemas() {
+	// arrange
+	dump := strings.Replace(s.backup().String(), fmt.Sprintf(`"schema":%d`, SchemaVersion), `"schema":1`, 1)
+
+	// act
+	counts, err := Restore(s.ctx, s.target.GetDB(), strings.NewReader(dump))
+
+	// assert
+	s.Require().NoError(err)
+	s.Assert().Equal(BackupCounts{Authors: 2, Books: 2, AuthorBooks: 2}, counts)
+	books, err := NewBooksRepository(s.target).GetAll(s.ctx, GetAllRequest{})
+	s.Require().NoError(err)
+	s.Assert().Len(books, 1)
+}
+
+func (s *BackupIntegrationSuite) TestRestoreShouldLoadIntoTheTenantOfTheContext() {
+	// arrange
+	theirs := uctx.WithTenant(s.ctx, "other")
+
+	// act
+	_, err := Restore(theirs, s.target.GetDB(), s.backup())
+
+	// assert
+	s.Require().NoError(err)
+	books := NewBooksRepository(s.target)
+	theirBooks, err := books.GetAll(theirs, GetAllRequest{})
+	s.Require().NoError(err)
+	myBooks, err := books.GetAll(s.ctx, GetAllRequest{})
+	s.Require().NoError(err)
+	s.Assert().Len(theirBooks, 1)
+	s.Assert().Empty(myBooks)
+}
+
+func (s *BackupIntegrationSuite) TestRestoreShouldRequireAnEmptyDatabase() {
+	// act
+	_, err := Restore(s.ctx, s.source.GetDB(), s.backup())
+
+	// assert
+	s.Assert().Equal(ErrNotEmpty, err)
+}
+
+func (s *BackupIntegrationSuite) TestRestoreShouldRejectInvalidBackups() {
+	// arrange
+	lines := strings.Split(strings.TrimSpace(s.backup().String()), "\n")
+	otherSchema := strings.Replace(lines[0], fmt.Sprintf(`"schema":%d`, SchemaVersion), `"schema":999`, 1)
+
+	dumps := []string{
+		"",
+		"not json",
+		strings.Join(lines[:len(lines)-1], "\n"),
+		strings.Join(append([]string{otherSchema}, lines[1:]...), "\n"),
+		strings.Join(append(append([]string{}, lines[:2]...), lines[3:]...), "\n"),
+	}
+
+	for _, dump := range dumps {
+		// act
+		_, err := Restore(s.ctx, s.target.GetDB(), strings.NewReader(dump))
+
+		// assert
+		s.Assert().True(errors.Is(err, ErrInvalidBackup), "%q: %v", dump, err)
+	}
+
+	var authors int64
+	s.target.GetDB().Unscoped().Model(&domain.Author{}).Count(&authors)
+	s.Assert().Zero(authors)
+}
+
+func TestIntegrationBackupSuite(t *testing.T) {
+	suite.Run(t, new(BackupIntegrationSuite))
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+)
+
+type cachedBooksRepository struct {
+	next  BooksRepository
+	cache *Cache
+}
+
+// NewCachedBooksRepository caches the books and listings read from next in
+// cache. Every write through it clears the cache, and requests that read their
+// own writes bypass it.
+func NewCachedBooksRepository(next BooksRepository, cache *Cache) BooksRepository {
+	return &cachedBooksRepository{
+		next:  next,
+		cache: cache,
+	}
+}
+
+func (i *cachedBooksRepository) GetAll(ctx context.Context, r GetAllRequest) ([]domain.Book, error) {
+	if uctx.ReadYourWrites(ctx) {
+		return i.next.GetAll(ctx, r)
+	}
+
+	books, err := i.cache.Get(ctx, tenantKey(ctx, booksKey(r)), func(ctx context.Context) (interface{}, error) {
+		return i.next.GetAll(ctx, r)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return books.([]domain.Book), nil
+}
+
+func (i *cachedBooksRepository) Count(ctx context.Context, r GetAllRequest) (int64, error) {
+	if uctx.ReadYourWrites(ctx) {
+		return i.next.Count(ctx, r)
+	}
+
+	r.Limit, r.Offset, r.After, r.Sort, r.Expand = 0, 0, nil, nil, ExpandNone
+	count, err := i.cache.Get(ctx, tenantKey(ctx, "count:"+booksKey(r)), func(ctx context.Context) (interface{}, error) {
+		return i.next.Count(ctx, r)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count.(int64), nil
+}
+
+// booksKey identifies a listing by the request, with the cursor by value.
+func booksKey(r GetAllRequest) string {
+	after := cursorKey(r.After)
+	r.After = nil
+	return fmt.Sprintf("books:%+v:%s", r, after)
+}
+
+// tenantKey keeps the entries of each tenant apart.
+func tenantKey(ctx context.Context, key string) string {
+	return uctx.Tenant(ctx) + "/" + key
+}
+
+func cursorKey(c *Cursor) string {
+	if c == nil {
+		return ""
+	}
+
+	return c.String()
+}
+
+func (i *cachedBooksRepository) GetBook(ctx context.Context, id int, expand Expand) (domain.Book, error) {
+	if uctx.ReadYourWrites(ctx) {
+		return i.next.GetBook(ctx, id, expand)
+	}
+
+	key := tenantKey(ctx, fmt.Sprintf("book:%d:%d", id, expand))
+
+	book, err := i.cache.Get(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return i.next.GetBook(ctx, id, expand)
+	})
+
+	if err != nil {
+		return domain.Book{}, err
+	}
+
+	result, _ := book.(domain.Book)
+	return result, nil
+}
+
+func (i *cachedBooksRepository) Create(ctx context.Context, book domain.Book) (uint, error) {
+	defer i.cache.Clear()
+	return i.next.Create(ctx, book)
+}
+
+func (i *cachedBooksRepository) Update(ctx context.Context, id int, book domain.Book) error {
+	defer i.cache.Clear()
+	return i.next.Update(ctx, id, book)
+}
+
+func (i *cachedBooksRepository) Delete(ctx context.Context, id int, version uint) error {
+	defer i.cache.Clear()
+	return i.next.Delete(ctx, id, version)
+}
+
+func (i *cachedBooksRepository) GetTrash(ctx context.Context, r PageRequest) ([]domain.Book, error) {
+	return i.next.GetTrash(ctx, r)
+}
+
+func (i *cachedBooksRepository) Restore(ctx context.Context, id int) error {
+	defer i.cache.Clear()
+	return i.next.Restore(ctx, id)
+}
+
+func (i *cachedBooksRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	defer i.cache.Clear()
+	return i.next.Purge(ctx, before)
+}
+
+func (i *cachedBooksRepository) GetHistory(ctx context.Context, id int) ([]domain.History, error) {
+	return i.next.GetHistory(ctx, id)
+}
+
+func (i *cachedBooksRepository) GetAuthors(ctx context.Context, id int) ([]domain.Author, error) {
+	if uctx.ReadYourWrites(ctx) {
+		return i.next.GetAuthors(ctx, id)
+	}
+
+	authors, err := i.cache.Get(ctx, tenantKey(ctx, fmt.Sprintf("book-authors:%d", id)), func(ctx context.Context) (interface{}, error) {
+		return i.next.GetAuthors(ctx, id)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	result, _ := authors.([]domain.Author)
+	return result, nil
+}
+
+func (i *cachedBooksRepository) AddAuthor(ctx context.Context, id int, authorID int, version uint) error {
+	defer i.cache.Clear()
+	return i.next.AddAuthor(ctx, id, authorID, version)
+}
+
+func (i *cachedBooksRepository) RemoveAuthor(ctx context.Context, id int, authorID int, version uint) error {
+	defer i.cache.Clear()
+	return i.next.RemoveAuthor(ctx, id, authorID, version)
+}
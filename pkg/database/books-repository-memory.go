@@ -0,0 +1,345 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"gorm.io/gorm"
+)
+
+type booksMemoryRepository struct {
+	store *MemoryStore
+}
+
+// NewBooksMemoryRepository returns a BooksRepository kept in store. Search
+// matches terms as word prefixes like FTS5 does, but results keep the
+// (name, id) order instead of being ranked.
+func NewBooksMemoryRepository(store *MemoryStore) BooksRepository {
+	return &booksMemoryRepository{
+		store: store,
+	}
+}
+
+func (i *booksMemoryRepository) GetAll(ctx context.Context, r GetAllRequest) ([]domain.Book, error) {
+	i.store.mu.RLock()
+	defer i.store.mu.RUnlock()
+
+	books := i.find(ctx, r)
+	start, end := page(len(books), r.Limit, r.Offset)
+	books = books[start:end]
+
+	for j := range books {
+		books[j] = i.store.expand(books[j], r.Expand)
+	}
+
+	return books, nil
+}
+
+func (i *booksMemoryRepository) Count(ctx context.Context, r GetAllRequest) (int64, error) {
+	i.store.mu.RLock()
+	defer i.store.mu.RUnlock()
+
+	r.After = nil
+	return int64(len(i.find(ctx, r))), nil
+}
+
+// find returns the books matching r in the order of its sort, without paging
+// them. The store must be locked.
+func (i *booksMemoryRepository) find(ctx context.Context, r GetAllRequest) []domain.Book {
+	tenant := uctx.Tenant(ctx)
+
+	books := []domain.Book{}
+	for _, b := range i.store.books {
+		if b.TenantID != tenant || b.DeletedAt.Valid ||
+			(len(r.Name) > 0 && b.Name != r.Name) ||
+			(len(r.Edition) > 0 && b.Edition != r.Edition) ||
+			(r.PublicationYear > 0 && b.PublicationYear != r.PublicationYear) ||
+			(r.Author > 0 && !i.store.links[b.ID][uint(r.Author)]) ||
+			!matches(b.Name, r.Query) ||
+			!r.Sort.follows(bookKeys(b, r.Sort), b.ID, r.After) {
+			continue
+		}
+
+		books = append(books, b)
+	}
+
+	sort.Slice(books, r.Sort.less(
+		func(i int) []interface{} { return bookKeys(books[i], r.Sort) },
+		func(i int) uint { return books[i].ID }))
+
+	return books
+}
+
+func (i *booksMemoryRepository) GetBook(ctx context.Context, id int, expand Expand) (domain.Book, error) {
+	i.store.mu.RLock()
+	defer i.store.mu.RUnlock()
+
+	b, ok := i.store.books[uint(id)]
+	if !ok || b.TenantID != uctx.Tenant(ctx) || b.DeletedAt.Valid {
+		return domain.Book{}, gorm.ErrRecordNotFound
+	}
+
+	return i.store.expand(b, expand), nil
+}
+
+func (i *booksMemoryRepository) Create(ctx context.Context, b domain.Book) (uint, error) {
+	i.store.mu.Lock()
+	defer i.store.mu.Unlock()
+
+	tenant := uctx.Tenant(ctx)
+
+	authors := map[uint]bool{}
+	for _, a := range authorRefs(b.Authors) {
+		if author, ok := i.store.authors[a.ID]; !ok || author.TenantID != tenant || author.DeletedAt.Valid {
+			return 0, ErrAuthorNotFound
+		}
+		authors[a.ID] = true
+	}
+
+	now := time.Now()
+	book := domain.Book{
+		TenantID:        tenant,
+		Name:            b.Name,
+		Edition:         b.Edition,
+		PublicationYear: b.PublicationYear,
+		Version:         1,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	i.store.lastBook++
+	book.ID = i.store.lastBook
+
+	if err := i.store.record(ctx, BookEntity, book.ID, ActionCreate, nil, snapshotBook(book)); err != nil {
+		return 0, err
+	}
+
+	i.store.books[book.ID] = book
+	i.store.links[book.ID] = authors
+	return book.ID, nil
+}
+
+func (i *booksMemoryRepository) Update(ctx context.Context, id int, b domain.Book) error {
+	i.store.mu.Lock()
+	defer i.store.mu.Unlock()
+
+	book, ok := i.store.books[uint(id)]
+	if !ok || book.TenantID != uctx.Tenant(ctx) || book.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+
+	if b.Version > 0 && b.Version != book.Version {
+		return ErrVersionMismatch
+	}
+
+	before := snapshotBook(book)
+
+	book.Name = b.Name
+	book.Edition = b.Edition
+	book.PublicationYear = b.PublicationYear
+	book.Version++
+	book.UpdatedAt = time.Now()
+
+	if err := i.store.record(ctx, BookEntity, book.ID, ActionUpdate, before, snapshotBook(book)); err != nil {
+		return err
+	}
+
+	i.store.books[book.ID] = book
+	return nil
+}
+
+func (i *booksMemoryRepository) Delete(ctx context.Context, id int, version uint) error {
+	i.store.mu.Lock()
+	defer i.store.mu.Unlock()
+
+	book, ok := i.store.books[uint(id)]
+	if !ok || book.TenantID != uctx.Tenant(ctx) || book.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+
+	if version > 0 && version != book.Version {
+		return ErrVersionMismatch
+	}
+
+	if err := i.store.record(ctx, BookEntity, book.ID, ActionDelete, snapshotBook(book), nil); err != nil {
+		return err
+	}
+
+	book.DeletedAt = deletedNow()
+	i.store.books[book.ID] = book
+	return nil
+}
+
+func (i *booksMemoryRepository) GetTrash(ctx context.Context, r PageRequest) ([]domain.Book, error) {
+	i.store.mu.RLock()
+	defer i.store.mu.RUnlock()
+
+	tenant := uctx.Tenant(ctx)
+
+	books := []domain.Book{}
+	for _, b := range i.store.books {
+		if b.TenantID == tenant && b.DeletedAt.Valid {
+			books = append(books, b)
+		}
+	}
+
+	sort.Slice(books, func(i, j int) bool {
+		if !books[i].DeletedAt.Time.Equal(books[j].DeletedAt.Time) {
+			return books[i].DeletedAt.Time.After(books[j].DeletedAt.Time)
+		}
+		return books[i].ID < books[j].ID
+	})
+
+	start, end := page(len(books), r.Limit, r.Offset)
+	return books[start:end], nil
+}
+
+func (i *booksMemoryRepository) Restore(ctx context.Context, id int) error {
+	i.store.mu.Lock()
+	defer i.store.mu.Unlock()
+
+	book, ok := i.store.books[uint(id)]
+	if !ok || book.TenantID != uctx.Tenant(ctx) || !book.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+
+	book.DeletedAt = gorm.DeletedAt{}
+	book.Version++
+	book.UpdatedAt = time.Now()
+
+	if err := i.store.record(ctx, BookEntity, book.ID, ActionRestore, nil, snapshotBook(book)); err != nil {
+		return err
+	}
+
+	i.store.books[book.ID] = book
+	return nil
+}
+
+func (i *booksMemoryRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	i.store.mu.Lock()
+	defer i.store.mu.Unlock()
+
+	tenant := uctx.Tenant(ctx)
+
+	var purged int64
+	for id, b := range i.store.books {
+		if b.TenantID == tenant && b.DeletedAt.Valid && b.DeletedAt.Time.Before(before) {
+			delete(i.store.books, id)
+			delete(i.store.links, id)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+func (i *booksMemoryRepository) GetHistory(ctx context.Context, id int) ([]domain.History, error) {
+	i.store.mu.RLock()
+	defer i.store.mu.RUnlock()
+
+	return i.store.entityHistory(ctx, BookEntity, id), nil
+}
+
+func (i *booksMemoryRepository) GetAuthors(ctx context.Context, id int) ([]domain.Author, error) {
+	i.store.mu.RLock()
+	defer i.store.mu.RUnlock()
+
+	book, ok := i.store.books[uint(id)]
+	if !ok || book.TenantID != uctx.Tenant(ctx) || book.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	authors := []domain.Author{}
+	for _, authorID := range i.store.bookAuthors(book.ID) {
+		if a, ok := i.store.authors[authorID]; ok && !a.DeletedAt.Valid {
+			authors = append(authors, a)
+		}
+	}
+
+	sort.Slice(authors, byNameAndID(
+		func(i int) string { return authors[i].Name },
+		func(i int) uint { return authors[i].ID }))
+
+	return authors, nil
+}
+
+func (i *booksMemoryRepository) AddAuthor(ctx context.Context, id int, authorID int, version uint) error {
+	i.store.mu.Lock()
+	defer i.store.mu.Unlock()
+
+	book, err := i.linkedBook(ctx, id, version)
+	if err != nil {
+		return err
+	}
+
+	author, ok := i.store.authors[uint(authorID)]
+	if !ok || author.TenantID != book.TenantID || author.DeletedAt.Valid {
+		return ErrAuthorNotFound
+	}
+
+	if i.store.links[book.ID][author.ID] {
+		return nil
+	}
+
+	if err := i.store.relink(ctx, book, ActionLink, nil, linkSnapshot{AuthorID: author.ID}); err != nil {
+		return err
+	}
+
+	if i.store.links[book.ID] == nil {
+		i.store.links[book.ID] = map[uint]bool{}
+	}
+	i.store.links[book.ID][author.ID] = true
+	return nil
+}
+
+func (i *booksMemoryRepository) RemoveAuthor(ctx context.Context, id int, authorID int, version uint) error {
+	i.store.mu.Lock()
+	defer i.store.mu.Unlock()
+
+	book, err := i.linkedBook(ctx, id, version)
+	if err != nil {
+		return err
+	}
+
+	if !i.store.links[book.ID][uint(authorID)] {
+		return gorm.ErrRecordNotFound
+	}
+
+	if err := i.store.relink(ctx, book, ActionUnlink, linkSnapshot{AuthorID: uint(authorID)}, nil); err != nil {
+		return err
+	}
+
+	delete(i.store.links[book.ID], uint(authorID))
+	return nil
+}
+
+// linkedBook returns the book whose authors are about to change, provided it
+// has the given version.
+func (i *booksMemoryRepository) linkedBook(ctx context.Context, id int, version uint) (domain.Book, error) {
+	book, ok := i.store.books[uint(id)]
+	if !ok || book.TenantID != uctx.Tenant(ctx) || book.DeletedAt.Valid {
+		return domain.Book{}, gorm.ErrRecordNotFound
+	}
+
+	if version > 0 && version != book.Version {
+		return domain.Book{}, ErrVersionMismatch
+	}
+
+	return book, nil
+}
+
+// relink bumps the version of a book whose authors changed and records the
+// change, like relink does for SQL.
+func (m *MemoryStore) relink(ctx context.Context, book domain.Book, action string, before, after interface{}) error {
+	if err := m.record(ctx, BookEntity, book.ID, action, before, after); err != nil {
+		return err
+	}
+
+	book.Version++
+	book.UpdatedAt = time.Now()
+	m.books[book.ID] = book
+	return nil
+}
@@ -1,6 +1,9 @@
 package database
 
 import (
+	"context"
+	"time"
+
 	"github.com/jedielson/bookstore/pkg/domain"
 	"github.com/stretchr/testify/mock"
 )
@@ -13,19 +16,30 @@ func NewBooksRepositoryMock() *BooksRepositoryMock {
 	return &BooksRepositoryMock{}
 }
 
-func (m *BooksRepositoryMock) GetAll(r GetAllRequest) []domain.Book {
-	args := m.Called(r)
+func (m *BooksRepositoryMock) GetAll(ctx context.Context, r GetAllRequest) ([]domain.Book, error) {
+	args := m.Called(ctx, r)
 	bb, ok := args.Get(0).([]domain.Book)
 
 	if !ok {
-		return nil
+		return nil, args.Error(1)
 	}
 
-	return bb
+	return bb, args.Error(1)
 }
 
-func (m *BooksRepositoryMock) GetBook(id int) (domain.Book, error) {
-	args := m.Called(id)
+func (m *BooksRepositoryMock) Count(ctx context.Context, r GetAllRequest) (int64, error) {
+	args := m.Called(ctx, r)
+	count, ok := args.Get(0).(int64)
+
+	if !ok {
+		count = 0
+	}
+
+	return count, args.Error(1)
+}
+
+func (m *BooksRepositoryMock) GetBook(ctx context.Context, id int, expand Expand) (domain.Book, error) {
+	args := m.Called(ctx, id, expand)
 	bb, ok := args.Get(0).(domain.Book)
 
 	if !ok {
@@ -35,8 +49,8 @@ func (m *BooksRepositoryMock) GetBook(id int) (domain.Book, error) {
 	return bb, args.Error(1)
 }
 
-func (m *BooksRepositoryMock) Create(book domain.Book) (uint, error) {
-	args := m.Called(book)
+func (m *BooksRepositoryMock) Create(ctx context.Context, book domain.Book) (uint, error) {
+	args := m.Called(ctx, book)
 	bb, ok := args.Get(0).(uint)
 
 	if !ok {
@@ -46,12 +60,71 @@ func (m *BooksRepositoryMock) Create(book domain.Book) (uint, error) {
 	return bb, args.Error(1)
 }
 
-func (m *BooksRepositoryMock) Update(id int, book domain.Book) error {
-	args := m.Called(id, book)
+func (m *BooksRepositoryMock) Update(ctx context.Context, id int, book domain.Book) error {
+	args := m.Called(ctx, id, book)
+	return args.Error(0)
+}
+
+func (m *BooksRepositoryMock) Delete(ctx context.Context, id int, version uint) error {
+	args := m.Called(ctx, id, version)
+	return args.Error(0)
+}
+
+func (m *BooksRepositoryMock) GetTrash(ctx context.Context, r PageRequest) ([]domain.Book, error) {
+	args := m.Called(ctx, r)
+	bb, ok := args.Get(0).([]domain.Book)
+
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return bb, args.Error(1)
+}
+
+func (m *BooksRepositoryMock) Restore(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *BooksRepositoryMock) Purge(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	count, ok := args.Get(0).(int64)
+
+	if !ok {
+		count = 0
+	}
+
+	return count, args.Error(1)
+}
+
+func (m *BooksRepositoryMock) GetHistory(ctx context.Context, id int) ([]domain.History, error) {
+	args := m.Called(ctx, id)
+	hh, ok := args.Get(0).([]domain.History)
+
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return hh, args.Error(1)
+}
+
+func (m *BooksRepositoryMock) GetAuthors(ctx context.Context, id int) ([]domain.Author, error) {
+	args := m.Called(ctx, id)
+	aa, ok := args.Get(0).([]domain.Author)
+
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return aa, args.Error(1)
+}
+
+func (m *BooksRepositoryMock) AddAuthor(ctx context.Context, id int, authorID int, version uint) error {
+	args := m.Called(ctx, id, authorID, version)
 	return args.Error(0)
 }
 
-func (m *BooksRepositoryMock) Delete(id int) error {
-	args := m.Called(id)
+func (m *BooksRepositoryMock) RemoveAuthor(ctx context.Context, id int, authorID int, version uint) error {
+	args := m.Called(ctx, id, authorID, version)
 	return args.Error(0)
 }
@@ -1,24 +1,42 @@
 package database
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/jedielson/bookstore/pkg/domain"
+	"gorm.io/gorm"
 )
 
 type GetAllRequest struct {
 	Name            string
+	Query           string
 	PublicationYear int
 	Edition         string
 	Author          int
 	Limit           int
 	Offset          int
+	After           *Cursor
+	Sort            Sort
+	Expand          Expand
 }
 
+// BooksRepository stores the books.
 type BooksRepository interface {
-	GetAll(r GetAllRequest) []domain.Book
-	GetBook(id int) (domain.Book, error)
-	Create(book domain.Book) (uint, error)
-	Update(id int, book domain.Book) error
-	Delete(id int) error
+	GetAll(ctx context.Context, r GetAllRequest) ([]domain.Book, error)
+	Count(ctx context.Context, r GetAllRequest) (int64, error)
+	GetBook(ctx context.Context, id int, expand Expand) (domain.Book, error)
+	Create(ctx context.Context, book domain.Book) (uint, error)
+	Update(ctx context.Context, id int, book domain.Book) error
+	Delete(ctx context.Context, id int, version uint) error
+	GetTrash(ctx context.Context, r PageRequest) ([]domain.Book, error)
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	GetHistory(ctx context.Context, id int) ([]domain.History, error)
+	GetAuthors(ctx context.Context, id int) ([]domain.Author, error)
+	AddAuthor(ctx context.Context, id int, authorID int, version uint) error
+	RemoveAuthor(ctx context.Context, id int, authorID int, version uint) error
 }
 
 type booksRepository struct {
@@ -31,58 +49,340 @@ func NewBooksRepository(d DBManager) BooksRepository {
 	}
 }
 
-func (i *booksRepository) GetAll(r GetAllRequest) []domain.Book {
-	users := []domain.Book{}
+func (i *booksRepository) db(ctx context.Context) *gorm.DB {
+	return i.manager.GetDB().WithContext(ctx)
+}
+
+// read returns the connection for queries, which may be a read-only replica.
+func (i *booksRepository) read(ctx context.Context) *gorm.DB {
+	return i.manager.GetReadDB(ctx).WithContext(ctx)
+}
+
+func (i *booksRepository) GetAll(ctx context.Context, r GetAllRequest) ([]domain.Book, error) {
+	books := []domain.Book{}
 
-	db := i.manager.GetDB()
+	db := filterBooks(i.read(ctx), r)
+	db = search(db, "books", r.Query, len(r.Sort) == 0)
+	db = keyset(db, "books", r.Sort, r.After)
 
+	err := preloadAuthors(db, r.Expand).Limit(r.Limit).Offset(r.Offset).Find(&books).Error
+	return books, err
+}
+
+// Count counts the books GetAll lists for r over every page, ignoring its
+// limit, offset and cursor.
+func (i *booksRepository) Count(ctx context.Context, r GetAllRequest) (int64, error) {
+	var count int64
+
+	db, _ := match(filterBooks(i.read(ctx), r), "books", r.Query)
+	err := db.Model(&domain.Book{}).Count(&count).Error
+	return count, err
+}
+
+func filterBooks(db *gorm.DB, r GetAllRequest) *gorm.DB {
 	if len(r.Name) > 0 {
-		db.Where("name = ?", r.Name)
+		db = db.Where("books.name = ?", r.Name)
 	}
 
 	if len(r.Edition) > 0 {
-		db.Where("edition = ?", r.Edition)
+		db = db.Where("edition = ?", r.Edition)
 	}
 
 	if r.PublicationYear > 0 {
-		db.Where("publication_year = ?", r.PublicationYear)
+		db = db.Where("publication_year = ?", r.PublicationYear)
 	}
 
-	db.Take(r.Limit)
-	db.Offset(r.Offset)
-	db.Find(&users)
-	return users
+	if r.Author > 0 {
+		db = db.Where("books.id IN (SELECT book_id FROM author_books WHERE author_id = ?)", r.Author)
+	}
+
+	return db
 }
 
-func (i *booksRepository) GetBook(id int) (domain.Book, error) {
+func (i *booksRepository) GetBook(ctx context.Context, id int, expand Expand) (domain.Book, error) {
 	b := domain.Book{}
-	err := i.manager.GetDB().First(&b).Error
+	err := preloadAuthors(i.read(ctx), expand).First(&b, id).Error
 	return b, err
 }
 
-func (i *booksRepository) Create(b domain.Book) (uint, error) {
+func (i *booksRepository) Create(ctx context.Context, b domain.Book) (uint, error) {
 	book := domain.Book{
 		Name:            b.Name,
 		Edition:         b.Edition,
 		PublicationYear: b.PublicationYear,
+		Version:         1,
+		Authors:         authorRefs(b.Authors),
 	}
 
-	err := i.manager.GetDB().Create(&book).Error
+	err := i.db(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := ensureAuthors(tx, book.Authors); err != nil {
+			return err
+		}
+
+		if err := tx.Omit("Authors.*").Create(&book).Error; err != nil {
+			return err
+		}
+
+		if err := record(ctx, tx, BookEntity, book.ID, ActionCreate, nil, snapshotBook(book)); err != nil {
+			return err
+		}
+
+		return publishBook(ctx, tx, BookCreated, book)
+	})
+
 	return book.ID, err
 }
 
-func (i *booksRepository) Update(id int, b domain.Book) error {
-	book := domain.Book{}
-	i.manager.GetDB().First(&book, id)
+// authorRefs keeps only the ids of the given authors, so saving a book links
+// existing authors without creating or updating them.
+func authorRefs(authors []*domain.Author) []*domain.Author {
+	var refs []*domain.Author
+	for _, a := range authors {
+		if a == nil || a.ID == 0 {
+			continue
+		}
 
-	book.Name = b.Name
-	book.Edition = b.Edition
-	book.PublicationYear = b.PublicationYear
+		ref := &domain.Author{}
+		ref.ID = a.ID
+		refs = append(refs, ref)
+	}
 
-	return i.manager.GetDB().Save(&book).Error
+	return refs
 }
 
-func (i *booksRepository) Delete(id int) error {
-	manager := i.manager.GetDB()
-	return manager.Delete(&domain.Book{}, id).Error
+var ErrAuthorNotFound = errors.New("author does not exist")
+
+// ensureAuthors checks that the authors exist for the tenant and are not
+// deleted, so that a book is never linked to an author of another tenant or
+// one in the trash.
+func ensureAuthors(tx *gorm.DB, authors []*domain.Author) error {
+	ids := map[uint]bool{}
+	for _, a := range authors {
+		ids[a.ID] = true
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var found int64
+	keys := make([]uint, 0, len(ids))
+	for id := range ids {
+		keys = append(keys, id)
+	}
+
+	if err := tx.Model(&domain.Author{}).Where("id IN ?", keys).Count(&found).Error; err != nil {
+		return err
+	}
+
+	if found != int64(len(ids)) {
+		return ErrAuthorNotFound
+	}
+
+	return nil
+}
+
+// Update overwrites the book with id. When b.Version is set, the book is only
+// updated if it still has that version, otherwise ErrVersionMismatch is
+// returned.
+func (i *booksRepository) Update(ctx context.Context, id int, b domain.Book) error {
+	return i.db(ctx).Transaction(func(tx *gorm.DB) error {
+		book := domain.Book{}
+		if err := tx.First(&book, id).Error; err != nil {
+			return err
+		}
+
+		if b.Version > 0 && b.Version != book.Version {
+			return ErrVersionMismatch
+		}
+
+		before := snapshotBook(book)
+		version := book.Version
+
+		book.Name = b.Name
+		book.Edition = b.Edition
+		book.PublicationYear = b.PublicationYear
+
+		err := bumpVersion(tx.Model(&book), version, map[string]interface{}{
+			"name":             book.Name,
+			"edition":          book.Edition,
+			"publication_year": book.PublicationYear,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		book.Version = version + 1
+		if err := record(ctx, tx, BookEntity, book.ID, ActionUpdate, before, snapshotBook(book)); err != nil {
+			return err
+		}
+
+		return publishBook(ctx, tx, BookUpdated, book)
+	})
+}
+
+// Delete soft-deletes the book with id. A version of 0 matches any version.
+func (i *booksRepository) Delete(ctx context.Context, id int, version uint) error {
+	return i.db(ctx).Transaction(func(tx *gorm.DB) error {
+		book := domain.Book{}
+		if err := tx.First(&book, id).Error; err != nil {
+			return err
+		}
+
+		if version > 0 && version != book.Version {
+			return ErrVersionMismatch
+		}
+
+		result := tx.Where("version = ?", book.Version).Delete(&domain.Book{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrVersionMismatch
+		}
+
+		if err := record(ctx, tx, BookEntity, book.ID, ActionDelete, snapshotBook(book), nil); err != nil {
+			return err
+		}
+
+		return publish(ctx, tx, BookDeleted, BookEntity, book.ID, BookDeletedPayload{ID: book.ID, Version: book.Version})
+	})
+}
+
+func (i *booksRepository) GetTrash(ctx context.Context, r PageRequest) ([]domain.Book, error) {
+	books := []domain.Book{}
+	err := trash(i.db(ctx), r, &books)
+	return books, err
+}
+
+func (i *booksRepository) Restore(ctx context.Context, id int) error {
+	return i.db(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := restore(tx, &domain.Book{}, id); err != nil {
+			return err
+		}
+
+		book := domain.Book{}
+		if err := tx.First(&book, id).Error; err != nil {
+			return err
+		}
+
+		if err := record(ctx, tx, BookEntity, book.ID, ActionRestore, nil, snapshotBook(book)); err != nil {
+			return err
+		}
+
+		// A restored book is published as updated: downstream it is upserted
+		// again.
+		return publishBook(ctx, tx, BookUpdated, book)
+	})
+}
+
+func (i *booksRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purge(i.db(ctx), &domain.Book{}, "book_id", before)
+}
+
+func (i *booksRepository) GetHistory(ctx context.Context, id int) ([]domain.History, error) {
+	return history(i.db(ctx), BookEntity, id)
+}
+
+// GetAuthors returns the authors of the book with id, in name order.
+func (i *booksRepository) GetAuthors(ctx context.Context, id int) ([]domain.Author, error) {
+	authors := []domain.Author{}
+
+	err := i.read(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&domain.Book{}, id).Error; err != nil {
+			return err
+		}
+
+		return tx.
+			Where("authors.id IN (SELECT author_id FROM author_books WHERE book_id = ?)", id).
+			Order("name, id").
+			Find(&authors).Error
+	})
+
+	return authors, err
+}
+
+// AddAuthor links the author with authorID to the book with id, which gets a
+// new version. Linking an author twice changes nothing. A version of 0
+// matches any version.
+func (i *booksRepository) AddAuthor(ctx context.Context, id int, authorID int, version uint) error {
+	return i.db(ctx).Transaction(func(tx *gorm.DB) error {
+		book := domain.Book{}
+		if err := tx.First(&book, id).Error; err != nil {
+			return err
+		}
+
+		if version > 0 && version != book.Version {
+			return ErrVersionMismatch
+		}
+
+		result := tx.Limit(1).Find(&domain.Author{}, authorID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrAuthorNotFound
+		}
+
+		var linked int64
+		if err := tx.Model(&domain.AuthorBook{}).Where("book_id = ? AND author_id = ?", id, authorID).Count(&linked).Error; err != nil {
+			return err
+		}
+
+		if linked > 0 {
+			return nil
+		}
+
+		if err := tx.Create(&domain.AuthorBook{AuthorID: uint(authorID), BookID: book.ID}).Error; err != nil {
+			return err
+		}
+
+		return relink(ctx, tx, book, ActionLink, nil, linkSnapshot{AuthorID: uint(authorID)})
+	})
+}
+
+// RemoveAuthor unlinks the author with authorID from the book with id, which
+// gets a new version. It fails with gorm.ErrRecordNotFound when they are not
+// linked. A version of 0 matches any version.
+func (i *booksRepository) RemoveAuthor(ctx context.Context, id int, authorID int, version uint) error {
+	return i.db(ctx).Transaction(func(tx *gorm.DB) error {
+		book := domain.Book{}
+		if err := tx.First(&book, id).Error; err != nil {
+			return err
+		}
+
+		if version > 0 && version != book.Version {
+			return ErrVersionMismatch
+		}
+
+		result := tx.Where("book_id = ? AND author_id = ?", id, authorID).Delete(&domain.AuthorBook{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return relink(ctx, tx, book, ActionUnlink, linkSnapshot{AuthorID: uint(authorID)}, nil)
+	})
+}
+
+// relink bumps the version of a book whose authors changed, and records and
+// publishes the change.
+func relink(ctx context.Context, tx *gorm.DB, book domain.Book, action string, before, after interface{}) error {
+	version := book.Version
+	if err := bumpVersion(tx.Model(&book), version, map[string]interface{}{}); err != nil {
+		return err
+	}
+
+	book.Version = version + 1
+	if err := record(ctx, tx, BookEntity, book.ID, action, before, after); err != nil {
+		return err
+	}
+
+	return publishBook(ctx, tx, BookUpdated, book)
 }
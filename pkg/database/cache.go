@@ -0,0 +1,145 @@
+package database
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jedielson/bookstore/pkg/uctx"
+)
+
+// Cache is an in-process LRU whose entries expire after a TTL. Concurrent
+// misses for the same key are collapsed into a single load. It is shared by
+// the caching repositories so that a write to any of them clears every entry,
+// since a book listing may embed authors and the other way around.
+type Cache struct {
+	mu         sync.Mutex
+	size       int
+	ttl        time.Duration
+	entries    *list.List
+	keys       map[string]*list.Element
+	calls      map[string]*cacheCall
+	generation uint64
+
+	now func() time.Time
+}
+
+type cacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+type cacheCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// NewCache returns a Cache holding at most size entries for ttl each.
+func NewCache(size int, ttl time.Duration) *Cache {
+	return &Cache{
+		size:    size,
+		ttl:     ttl,
+		entries: list.New(),
+		keys:    map[string]*list.Element{},
+		calls:   map[string]*cacheCall{},
+		now:     time.Now,
+	}
+}
+
+// Get returns the value cached for key, loading it with load on a miss. Only
+// one load runs at a time for a key; the other callers wait for its result,
+// or until their own ctx is done. The load is shared, so it runs on ctx
+// detached from its cancellation: the caller running it giving up does not
+// fail the others. Errors are never cached, and a value loaded while the cache was
+// cleared is returned but not kept. Cached values are shared, callers must not
+// modify them.
+func (c *Cache) Get(ctx context.Context, key string, load func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+
+	if e, ok := c.keys[key]; ok {
+		entry := e.Value.(*cacheEntry)
+		if c.now().Before(entry.expires) {
+			c.entries.MoveToFront(e)
+			c.mu.Unlock()
+			return entry.value, nil
+		}
+
+		c.remove(e)
+	}
+
+	if call, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		return call.wait(ctx)
+	}
+
+	call := &cacheCall{done: make(chan struct{})}
+	c.calls[key] = call
+	generation := c.generation
+	c.mu.Unlock()
+
+	loaded := false
+	defer func() {
+		c.mu.Lock()
+		if c.calls[key] == call {
+			delete(c.calls, key)
+		}
+		if loaded && call.err == nil && generation == c.generation {
+			c.add(key, call.value)
+		}
+		c.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	call.value, call.err = load(uctx.Detach(ctx))
+	loaded = true
+	return call.value, call.err
+}
+
+func (call *cacheCall) wait(ctx context.Context) (interface{}, error) {
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Clear drops every entry and discards the loads still running.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries.Init()
+	c.keys = map[string]*list.Element{}
+	c.calls = map[string]*cacheCall{}
+}
+
+func (c *Cache) add(key string, value interface{}) {
+	if c.size <= 0 {
+		return
+	}
+
+	if e, ok := c.keys[key]; ok {
+		c.remove(e)
+	}
+
+	c.keys[key] = c.entries.PushFront(&cacheEntry{
+		key:     key,
+		value:   value,
+		expires: c.now().Add(c.ttl),
+	})
+
+	for c.entries.Len() > c.size {
+		c.remove(c.entries.Back())
+	}
+}
+
+func (c *Cache) remove(e *list.Element) {
+	c.entries.Remove(e)
+	delete(c.keys, e.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type CacheSuite struct {
+	suite.Suite
+
+	ctx   context.Context
+	now   time.Time
+	cache *Cache
+	books *BooksRepositoryMock
+	repo  BooksRepository
+}
+
+func (s *CacheSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.now = time.Now()
+	s.cache = NewCache(2, time.Minute)
+	s.cache.now = func() time.Time { return s.now }
+	s.books = NewBooksRepositoryMock()
+	s.repo = NewCachedBooksRepository(s.books, s.cache)
+}
+
+func value(v interface{}) func(context.Context) (interface{}, error) {
+	return func(context.Context) (interface{}, error) { return v, nil }
+}
+
+func (s *CacheSuite) TestGetShouldEvictLeastRecentlyUsed() {
+	// arrange
+	s.cache.Get(s.ctx, "a", value(1))
+	s.cache.Get(s.ctx, "b", value(2))
+	s.cache.Get(s.ctx, "a", value(0))
+
+	// act
+	s.cache.Get(s.ctx, "c", value(3))
+
+	// assert
+	a, _ := s.cache.Get(s.ctx, "a", value(0))
+	b, _ := s.cache.Get(s.ctx, "b", value(0))
+	s.Assert().Equal(1, a)
+	s.Assert().Equal(0, b)
+}
+
+func (s *CacheSuite) TestGetShouldExpireEntries() {
+	// arrange
+	s.cache.Get(s.ctx, "a", value(1))
+	s.now = s.now.Add(time.Minute)
+
+	// act
+	a, _ := s.cache.Get(s.ctx, "a", value(2))
+
+	// assert
+	s.Assert().Equal(2, a)
+}
+
+func (s *CacheSuite) TestGetShouldNotCacheErrors() {
+	// arrange
+	s.cache.Get(s.ctx, "a", func(context.Context) (interface{}, error) { return nil, errors.New("failed") })
+
+	// act
+	a, err := s.cache.Get(s.ctx, "a", value(1))
+
+	// assert
+	s.Assert().NoError(err)
+	s.Assert().Equal(1, a)
+}
+
+func (s *CacheSuite) TestGetShouldCollapseConcurrentMisses() {
+	// arrange
+	var loads int32
+	release := make(chan struct{})
+	wg := sync.WaitGroup{}
+
+	// act
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.cache.Get(s.ctx, "a", func(context.Context) (interface{}, error) {
+				atomic.AddInt32(&loads, 1)
+				<-release
+				return 1, nil
+			})
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// assert
+	s.Assert().Equal(int32(1), atomic.LoadInt32(&loads))
+}
+
+func (s *CacheSuite) TestClearShouldDiscardRunningLoads() {
+	// arrange
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		s.cache.Get(s.ctx, "a", func(context.Context) (interface{}, error) {
+			close(loading)
+			<-release
+			return "stale", nil
+		})
+		close(done)
+	}()
+
+	// act
+	<-loading
+	s.cache.Clear()
+	close(release)
+	<-done
+
+	// assert
+	a, _ := s.cache.Get(s.ctx, "a", value("fresh"))
+	s.Assert().Equal("fresh", a)
+}
+
+func (s *CacheSuite) TestGetShouldLoadOnDetachedContext() {
+	// arrange
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+
+	// act
+	a, err := s.cache.Get(ctx, "a", func(ctx context.Context) (interface{}, error) {
+		return 1, ctx.Err()
+	})
+
+	// assert
+	s.Assert().NoError(err)
+	s.Assert().Equal(1, a)
+}
+
+func (s *CacheSuite) TestCancelledWaiterShouldNotFailTheLoad() {
+	// arrange
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	ctx, cancel := context.WithCancel(s.ctx)
+
+	go func() {
+		s.cache.Get(s.ctx, "a", func(context.Context) (interface{}, error) {
+			close(loading)
+			<-release
+			return 1, nil
+		})
+		close(done)
+	}()
+	<-loading
+
+	// act
+	cancel()
+	_, err := s.cache.Get(ctx, "a", value(0))
+	close(release)
+	<-done
+
+	// assert
+	a, _ := s.cache.Get(s.ctx, "a", value(0))
+	s.Assert().Equal(context.Canceled, err)
+	s.Assert().Equal(1, a)
+}
+
+func (s *CacheSuite) TestFailedListingsShouldNotBeCached() {
+	// arrange
+	failure := errors.New("database is locked")
+	s.books.On("GetAll", mock.Anything, GetAllRequest{}).Return(nil, failure).Once()
+	s.books.On("GetAll", mock.Anything, GetAllRequest{}).Return([]domain.Book{{Name: "A"}}, nil).Once()
+
+	// act
+	_, failed := s.repo.GetAll(s.ctx, GetAllRequest{})
+	books, err := s.repo.GetAll(s.ctx, GetAllRequest{})
+
+	// assert
+	s.Assert().Equal(failure, failed)
+	s.Require().NoError(err)
+	s.Assert().Len(books, 1)
+	s.books.AssertExpectations(s.T())
+}
+
+func (s *CacheSuite) TestGetBookShouldBeServedFromCache() {
+	// arrange
+	s.books.On("GetBook", mock.Anything, 1, ExpandNone).Return(domain.Book{Name: "Cached"}, nil).Once()
+
+	// act
+	s.repo.GetBook(s.ctx, 1, ExpandNone)
+	book, err := s.repo.GetBook(s.ctx, 1, ExpandNone)
+
+	// assert
+	s.Assert().NoError(err)
+	s.Assert().Equal("Cached", book.Name)
+	s.books.AssertExpectations(s.T())
+}
+
+func (s *CacheSuite) TestWritesShouldInvalidateCache() {
+	// arrange
+	s.books.On("GetBook", mock.Anything, 1, ExpandNone).Return(domain.Book{Name: "Old"}, nil).Once()
+	s.books.On("Update", mock.Anything, 1, mock.Anything).Return(nil)
+	s.books.On("GetBook", mock.Anything, 1, ExpandNone).Return(domain.Book{Name: "New"}, nil).Once()
+
+	// act
+	s.repo.GetBook(s.ctx, 1, ExpandNone)
+	s.repo.Update(s.ctx, 1, domain.Book{Name: "New"})
+	book, _ := s.repo.GetBook(s.ctx, 1, ExpandNone)
+
+	// assert
+	s.Assert().Equal("New", book.Name)
+	s.books.AssertExpectations(s.T())
+}
+
+func (s *CacheSuite) TestListingsShouldBeCachedByCursor() {
+	// arrange
+	first := Cursor{Keys: []interface{}{"A"}, ID: 1}
+	second := Cursor{Keys: []interface{}{"B"}, ID: 2}
+	s.books.On("GetAll", mock.Anything, GetAllRequest{Limit: 1, After: &first}).Return([]domain.Book{{Name: "B"}}, nil).Once()
+	s.books.On("GetAll", mock.Anything, GetAllRequest{Limit: 1, After: &second}).Return([]domain.Book{{Name: "C"}}, nil).Once()
+
+	// act
+	s.repo.GetAll(s.ctx, GetAllRequest{Limit: 1, After: &first})
+	s.repo.GetAll(s.ctx, GetAllRequest{Limit: 1, After: &Cursor{Keys: []interface{}{"A"}, ID: 1}})
+	books, err := s.repo.GetAll(s.ctx, GetAllRequest{Limit: 1, After: &second})
+	s.Require().NoError(err)
+
+	// assert
+	s.Assert().Equal("C", books[0].Name)
+	s.books.AssertExpectations(s.T())
+}
+
+func (s *CacheSuite) TestTenantsShouldNotShareEntries() {
+	// arrange
+	theirs := uctx.WithTenant(s.ctx, "other")
+	s.books.On("GetBook", tenant(uctx.DefaultTenant), 1, ExpandNone).Return(domain.Book{Name: "Mine"}, nil).Once()
+	s.books.On("GetBook", tenant("other"), 1, ExpandNone).Return(domain.Book{Name: "Theirs"}, nil).Once()
+
+	// act
+	s.repo.GetBook(s.ctx, 1, ExpandNone)
+	book, _ := s.repo.GetBook(theirs, 1, ExpandNone)
+
+	// assert
+	s.Assert().Equal("Theirs", book.Name)
+	s.books.AssertExpectations(s.T())
+}
+
+// tenant matches the contexts of the given tenant, which loads see detached.
+func tenant(name string) interface{} {
+	return mock.MatchedBy(func(ctx context.Context) bool { return uctx.Tenant(ctx) == name })
+}
+
+func TestCacheSuite(t *testing.T) {
+	suite.Run(t, new(CacheSuite))
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+)
+
+var ErrInvalidCursor = errors.New("cursor is invalid")
+
+// Cursor points right after a row in a listing ordered by Sort, holding the
+// Keys of the row for each of its fields, and then by ID. It is handed to
+// clients as an opaque string.
+type Cursor struct {
+	Sort string        `json:"s,omitempty"`
+	Keys []interface{} `json:"k"`
+	ID   uint          `json:"id"`
+}
+
+func (c Cursor) String() string {
+	bytes, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func ParseCursor(s string) (Cursor, error) {
+	var c Cursor
+
+	bytes, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err = json.Unmarshal(bytes, &c); err != nil || len(c.Keys) == 0 || c.ID == 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	// Keys are bound as query parameters, which only take scalars.
+	for _, key := range c.Keys {
+		switch key.(type) {
+		case string, float64:
+		default:
+			return Cursor{}, ErrInvalidCursor
+		}
+	}
+
+	return c, nil
+}
+
+func AuthorCursor(a domain.Author, s Sort) Cursor {
+	return Cursor{Sort: s.orDefault().String(), Keys: authorKeys(a, s), ID: a.ID}
+}
+
+func BookCursor(b domain.Book, s Sort) Cursor {
+	return Cursor{Sort: s.orDefault().String(), Keys: bookKeys(b, s), ID: b.ID}
+}
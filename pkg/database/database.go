@@ -1,6 +1,10 @@
 package database
 
 import (
+	"context"
+	"sync/atomic"
+
+	"github.com/jedielson/bookstore/pkg/uctx"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -8,42 +12,171 @@ import (
 type DBManager interface {
 	InitDb() error
 	GetDB() *gorm.DB
+	GetReadDB(ctx context.Context) *gorm.DB
 	Close() error
+	WithTx(ctx context.Context, fn func(tx Repos) error) error
+}
+
+// Repos groups the repositories that share a single unit of work.
+type Repos struct {
+	Books   BooksRepository
+	Authors AuthorsRepository
+	Stats   StatsRepository
+}
+
+func NewRepos(m DBManager) Repos {
+	return Repos{
+		Books:   NewBooksRepository(m),
+		Authors: NewAuthorsRepository(m),
+		Stats:   NewStatsRepository(m),
+	}
+}
+
+// NewCachedRepos wraps every repository of repos with cache.
+func NewCachedRepos(repos Repos, cache *Cache) Repos {
+	return Repos{
+		Books:   NewCachedBooksRepository(repos.Books, cache),
+		Authors: NewCachedAuthorsRepository(repos.Authors, cache),
+		Stats:   NewCachedStatsRepository(repos.Stats, cache),
+	}
 }
 
 type dbManager struct {
-	DBConn *gorm.DB
-	Dsn    string
+	DBConn  *gorm.DB
+	Readers []*gorm.DB
+	Dsn     string
+	Options Options
+
+	next uint32
 }
 
 func NewDbManager(dsn string) DBManager {
+	return NewDbManagerWithOptions(dsn, DefaultOptions())
+}
+
+func NewDbManagerWithOptions(dsn string, opts Options) DBManager {
 	man := &dbManager{
-		Dsn:    dsn,
-		DBConn: nil,
+		Dsn:     dsn,
+		DBConn:  nil,
+		Options: opts,
 	}
 
 	return man
 }
 
 func (bd *dbManager) InitDb() (err error) {
-	bd.DBConn, err = gorm.Open(sqlite.Open(bd.Dsn), &gorm.Config{})
-	return err
+	if err = bd.Options.Validate(); err != nil {
+		return err
+	}
+
+	if bd.DBConn, err = bd.open(bd.Dsn); err != nil {
+		return err
+	}
+
+	bd.Readers = nil
+	for _, dsn := range bd.Options.Replicas {
+		reader, err := bd.open(dsn)
+		if err != nil {
+			return err
+		}
+
+		bd.Readers = append(bd.Readers, reader)
+	}
+
+	return nil
 }
 
-func (bd *dbManager) Close() (err error) {
-	db, err := bd.DBConn.DB()
+func (bd *dbManager) open(dsn string) (*gorm.DB, error) {
+	conn, err := gorm.Open(sqlite.Open(bd.Options.Dsn(dsn)), &gorm.Config{
+		PrepareStmt: bd.Options.PrepareStmt,
+	})
 
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	defer func() {
-		err = db.Close()
-	}()
+	if err := setupTenancy(conn); err != nil {
+		return nil, err
+	}
+
+	db, err := conn.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(bd.Options.MaxOpenConns)
+	db.SetMaxIdleConns(bd.Options.MaxIdleConns)
 
-	return
+	return conn, nil
+}
+
+func (bd *dbManager) Close() (err error) {
+	for _, conn := range append([]*gorm.DB{bd.DBConn}, bd.Readers...) {
+		db, dbErr := conn.DB()
+		if dbErr == nil {
+			dbErr = db.Close()
+		}
+
+		if dbErr != nil && err == nil {
+			err = dbErr
+		}
+	}
+
+	return err
 }
 
 func (bd *dbManager) GetDB() *gorm.DB {
 	return bd.DBConn
 }
+
+// GetReadDB returns the connection to read from: the readers take turns,
+// unless there are none or ctx asks to read its own writes, in which case the
+// primary is used.
+func (bd *dbManager) GetReadDB(ctx context.Context) *gorm.DB {
+	if len(bd.Readers) == 0 || uctx.ReadYourWrites(ctx) {
+		return bd.DBConn
+	}
+
+	next := atomic.AddUint32(&bd.next, 1)
+	return bd.Readers[next%uint32(len(bd.Readers))]
+}
+
+// WithTx runs fn with repositories bound to a single transaction. It commits
+// when fn returns nil and rolls back when fn returns an error or panics; the
+// panic is propagated after the rollback.
+func (bd *dbManager) WithTx(ctx context.Context, fn func(tx Repos) error) error {
+	return withTx(bd.DBConn.WithContext(ctx), fn)
+}
+
+func withTx(db *gorm.DB, fn func(tx Repos) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return fn(NewRepos(&txManager{tx: tx}))
+	})
+}
+
+// txManager is the DBManager handed to transaction-scoped repositories. The
+// transaction is owned by WithTx, so it can neither be opened nor closed here.
+type txManager struct {
+	tx *gorm.DB
+}
+
+func (t *txManager) InitDb() error {
+	return nil
+}
+
+func (t *txManager) GetDB() *gorm.DB {
+	return t.tx
+}
+
+// GetReadDB reads within the transaction, so that its own writes are seen.
+func (t *txManager) GetReadDB(ctx context.Context) *gorm.DB {
+	return t.tx
+}
+
+func (t *txManager) Close() error {
+	return nil
+}
+
+func (t *txManager) WithTx(ctx context.Context, fn func(tx Repos) error) error {
+	return withTx(t.tx.WithContext(ctx), fn)
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"github.com/stretchr/testify/suite"
+)
+
+type DBManagerIntegrationSuite struct {
+	suite.Suite
+
+	ctx     context.Context
+	manager DBManager
+}
+
+func (s *DBManagerIntegrationSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.manager = NewDbManager(fmt.Sprintf("file:%s?mode=memory&cache=shared", s.T().Name()))
+	s.Require().NoError(s.manager.InitDb())
+	Migrate(s.manager.GetDB())
+}
+
+func (s *DBManagerIntegrationSuite) TearDownTest() {
+	s.Require().NoError(s.manager.Close())
+}
+
+func (s *DBManagerIntegrationSuite) createBookWithNewAuthor(tx Repos) error {
+	authorID, err := tx.Authors.Create(s.ctx, domain.Author{Name: "Luciano Ramalho"})
+	if err != nil {
+		return err
+	}
+
+	author := &domain.Author{}
+	author.ID = authorID
+
+	_, err = tx.Books.Create(s.ctx, domain.Book{Name: "Fluent Python", Authors: []*domain.Author{author}})
+	return err
+}
+
+func (s *DBManagerIntegrationSuite) count(model interface{}) int64 {
+	var count int64
+	s.manager.GetDB().Model(model).Count(&count)
+	return count
+}
+
+func (s *DBManagerIntegrationSuite) TestWithTxShouldCommitOnSuccess() {
+	// act
+	err := s.manager.WithTx(s.ctx, s.createBookWithNewAuthor)
+
+	// assert
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(1), s.count(&domain.Author{}))
+
+	book, err := NewBooksRepository(s.manager).GetBook(s.ctx, 1, ExpandAuthors)
+	s.Assert().NoError(err)
+	s.Require().Len(book.Authors, 1)
+	s.Assert().Equal("Luciano Ramalho", book.Authors[0].Name)
+}
+
+func (s *DBManagerIntegrationSuite) TestWithTxShouldRollbackOnError() {
+	// arrange
+	expected := errors.New("some error")
+
+	// act
+	err := s.manager.WithTx(s.ctx, func(tx Repos) error {
+		if err := s.createBookWithNewAuthor(tx); err != nil {
+			return err
+		}
+		return expected
+	})
+
+	// assert
+	s.Assert().Equal(expected, err)
+	s.Assert().Equal(int64(0), s.count(&domain.Author{}))
+	s.Assert().Equal(int64(0), s.count(&domain.Book{}))
+}
+
+func (s *DBManagerIntegrationSuite) TestWithTxShouldRollbackOnPanic() {
+	// act
+	s.Assert().Panics(func() {
+		_ = s.manager.WithTx(s.ctx, func(tx Repos) error {
+			if err := s.createBookWithNewAuthor(tx); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	})
+
+	// assert
+	s.Assert().Equal(int64(0), s.count(&domain.Author{}))
+	s.Assert().Equal(int64(0), s.count(&domain.Book{}))
+}
+
+func (s *DBManagerIntegrationSuite) TestReadsShouldGoToReplicasUnlessReadingOwnWrites() {
+	// arrange
+	opts := DefaultOptions()
+	opts.Replicas = []string{fmt.Sprintf("file:%s-replica?mode=memory&cache=shared", s.T().Name())}
+	manager := NewDbManagerWithOptions(fmt.Sprintf("file:%s-primary?mode=memory&cache=shared", s.T().Name()), opts)
+	s.Require().NoError(manager.InitDb())
+	defer manager.Close()
+
+	Migrate(manager.GetDB())
+	Migrate(manager.GetReadDB(s.ctx))
+	repos := NewRepos(manager)
+
+	_, err := repos.Books.Create(s.ctx, domain.Book{Name: "Not replicated yet"})
+	s.Require().NoError(err)
+
+	// act
+	fromReplica, err := repos.Books.GetAll(s.ctx, GetAllRequest{})
+	s.Require().NoError(err)
+	fromPrimary, err := repos.Books.GetAll(uctx.WithReadYourWrites(s.ctx), GetAllRequest{})
+	s.Require().NoError(err)
+
+	var fromTx []domain.Book
+	err = manager.WithTx(s.ctx, func(tx Repos) error {
+		fromTx, err = tx.Books.GetAll(s.ctx, GetAllRequest{})
+		return err
+	})
+
+	// assert
+	s.Require().NoError(err)
+	s.Assert().Empty(fromReplica)
+	s.Assert().Len(fromPrimary, 1)
+	s.Assert().Len(fromTx, 1)
+}
+
+func TestIntegrationDBManagerSuite(t *testing.T) {
+	suite.Run(t, new(DBManagerIntegrationSuite))
+}
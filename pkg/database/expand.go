@@ -0,0 +1,29 @@
+package database
+
+import "gorm.io/gorm"
+
+type Expand int
+
+const (
+	ExpandNone Expand = iota
+	ExpandAuthors
+	ExpandAuthorsBooks
+)
+
+const MaxExpandDepth = int(ExpandAuthorsBooks)
+
+func summary(db *gorm.DB) *gorm.DB {
+	return db.Select("id", "name")
+}
+
+func preloadAuthors(db *gorm.DB, e Expand) *gorm.DB {
+	if e >= ExpandAuthors {
+		db = db.Preload("Authors", summary)
+	}
+
+	if e >= ExpandAuthorsBooks {
+		db = db.Preload("Authors.Books", summary)
+	}
+
+	return db
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"gorm.io/gorm"
+)
+
+const (
+	BookEntity   = "book"
+	AuthorEntity = "author"
+)
+
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionLink    = "link"
+	ActionUnlink  = "unlink"
+)
+
+// record appends a change of an entity to the history. before and after are
+// the snapshots of the entity around the change; nil means it did not exist.
+func record(ctx context.Context, db *gorm.DB, entityType string, id uint, action string, before, after interface{}) error {
+	h := domain.History{
+		EntityType: entityType,
+		EntityID:   id,
+		Action:     action,
+		Actor:      uctx.Actor(ctx),
+		RequestID:  uctx.RequestID(ctx),
+	}
+
+	var err error
+	if h.Before, err = snapshotJson(before); err != nil {
+		return err
+	}
+
+	if h.After, err = snapshotJson(after); err != nil {
+		return err
+	}
+
+	return db.Create(&h).Error
+}
+
+func snapshotJson(snapshot interface{}) (string, error) {
+	if snapshot == nil {
+		return "", nil
+	}
+
+	bytes, err := json.Marshal(snapshot)
+	return string(bytes), err
+}
+
+func history(db *gorm.DB, entityType string, id int) ([]domain.History, error) {
+	entries := []domain.History{}
+
+	err := db.
+		Where("entity_type = ? AND entity_id = ?", entityType, id).
+		Order("id").
+		Find(&entries).Error
+
+	return entries, err
+}
+
+type bookSnapshot struct {
+	Name            string
+	Edition         string
+	PublicationYear int
+}
+
+func snapshotBook(b domain.Book) interface{} {
+	return bookSnapshot{
+		Name:            b.Name,
+		Edition:         b.Edition,
+		PublicationYear: b.PublicationYear,
+	}
+}
+
+type authorSnapshot struct {
+	Name string
+}
+
+func snapshotAuthor(a domain.Author) interface{} {
+	return authorSnapshot{
+		Name: a.Name,
+	}
+}
+
+// linkSnapshot is the author a link or unlink of a book is about.
+type linkSnapshot struct {
+	AuthorID uint
+}
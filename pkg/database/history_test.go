@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type HistoryIntegrationSuite struct {
+	suite.Suite
+
+	ctx     context.Context
+	manager DBManager
+	authors AuthorsRepository
+	books   BooksRepository
+}
+
+func (s *HistoryIntegrationSuite) SetupTest() {
+	s.ctx = uctx.WithRequestID(uctx.WithActor(context.Background(), "librarian"), "req-1")
+	s.manager = NewDbManager(fmt.Sprintf("file:%s?mode=memory&cache=shared", s.T().Name()))
+	s.Require().NoError(s.manager.InitDb())
+	Migrate(s.manager.GetDB())
+
+	s.authors = NewAuthorsRepository(s.manager)
+	s.books = NewBooksRepository(s.manager)
+}
+
+func (s *HistoryIntegrationSuite) TearDownTest() {
+	s.Require().NoError(s.manager.Close())
+}
+
+func (s *HistoryIntegrationSuite) actions(entries []domain.History) []string {
+	actions := []string{}
+	for _, h := range entries {
+		actions = append(actions, h.Action)
+	}
+	return actions
+}
+
+func (s *HistoryIntegrationSuite) TestShouldRecordEveryBookChange() {
+	// arrange
+	id, err := s.books.Create(s.ctx, domain.Book{Name: "Fluent Python", Edition: "1", PublicationYear: 2015})
+	s.Require().NoError(err)
+
+	// act
+	s.Require().NoError(s.books.Update(s.ctx, int(id), domain.Book{Name: "Fluent Python", Edition: "2", PublicationYear: 2022}))
+	s.Require().NoError(s.books.Delete(s.ctx, int(id), 0))
+	s.Require().NoError(s.books.Restore(s.ctx, int(id)))
+
+	// assert
+	entries, err := s.books.GetHistory(s.ctx, int(id))
+	s.Require().NoError(err)
+	s.Require().Equal([]string{ActionCreate, ActionUpdate, ActionDelete, ActionRestore}, s.actions(entries))
+
+	update := entries[1]
+	s.Assert().Equal(BookEntity, update.EntityType)
+	s.Assert().Equal("librarian", update.Actor)
+	s.Assert().Equal("req-1", update.RequestID)
+	s.Assert().JSONEq(`{"Name":"Fluent Python","Edition":"1","PublicationYear":2015}`, update.Before)
+	s.Assert().JSONEq(`{"Name":"Fluent Python","Edition":"2","PublicationYear":2022}`, update.After)
+	s.Assert().Empty(entries[0].Before)
+	s.Assert().Empty(entries[2].After)
+}
+
+func (s *HistoryIntegrationSuite) TestShouldNotRecordDeleteOfMissingBook() {
+	// act
+	err := s.books.Delete(s.ctx, 42, 0)
+
+	// assert
+	s.Assert().Equal(gorm.ErrRecordNotFound, err)
+	entries, err := s.books.GetHistory(s.ctx, 42)
+	s.Require().NoError(err)
+	s.Assert().Empty(entries)
+}
+
+func (s *HistoryIntegrationSuite) TestShouldRecordAuthorChanges() {
+	// arrange
+	id, err := s.authors.Create(context.Background(), domain.Author{Name: "Luciano Ramalho"})
+	s.Require().NoError(err)
+	s.manager.GetDB().Delete(&domain.Author{}, id)
+
+	// act
+	s.Require().NoError(s.authors.Restore(s.ctx, int(id)))
+
+	// assert
+	entries, err := s.authors.GetHistory(s.ctx, int(id))
+	s.Require().NoError(err)
+	s.Require().Equal([]string{ActionCreate, ActionRestore}, s.actions(entries))
+	s.Assert().Equal("anonymous", entries[0].Actor)
+	s.Assert().Equal("librarian", entries[1].Actor)
+
+	bookEntries, err := s.books.GetHistory(s.ctx, int(id))
+	s.Require().NoError(err)
+	s.Assert().Empty(bookEntries)
+}
+
+func (s *HistoryIntegrationSuite) TestShouldRollbackHistoryWithTheChange() {
+	// act
+	_ = s.manager.WithTx(s.ctx, func(tx Repos) error {
+		_, err := tx.Books.Create(s.ctx, domain.Book{Name: "Fluent Python"})
+		s.Require().NoError(err)
+		return fmt.Errorf("rollback")
+	})
+
+	// assert
+	var count int64
+	s.manager.GetDB().Model(&domain.History{}).Count(&count)
+	s.Assert().Equal(int64(0), count)
+}
+
+func TestIntegrationHistorySuite(t *testing.T) {
+	suite.Run(t, new(HistoryIntegrationSuite))
+}
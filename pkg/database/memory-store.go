@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"gorm.io/gorm"
+)
+
+// MemoryStore keeps books, authors, their links and their history in memory.
+// It backs the memory repositories, which share it to resolve associations.
+// Every row belongs to the tenant it was created for, and links only join rows
+// of the same tenant.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	books   map[uint]domain.Book
+	authors map[uint]domain.Author
+	links   map[uint]map[uint]bool
+	history []domain.History
+
+	lastBook    uint
+	lastAuthor  uint
+	lastHistory uint
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		books:   map[uint]domain.Book{},
+		authors: map[uint]domain.Author{},
+		links:   map[uint]map[uint]bool{},
+	}
+}
+
+func NewMemoryRepos(store *MemoryStore) Repos {
+	return Repos{
+		Books:   NewBooksMemoryRepository(store),
+		Authors: NewAuthorsMemoryRepository(store),
+		Stats:   NewStatsMemoryRepository(store),
+	}
+}
+
+// bookAuthors returns the ids of the authors linked to a book, in id order.
+func (m *MemoryStore) bookAuthors(bookID uint) []uint {
+	ids := []uint{}
+	for authorID := range m.links[bookID] {
+		ids = append(ids, authorID)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// authorBooks returns the ids of the books linked to an author, in id order.
+func (m *MemoryStore) authorBooks(authorID uint) []uint {
+	ids := []uint{}
+	for bookID, authors := range m.links {
+		if authors[authorID] {
+			ids = append(ids, bookID)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (m *MemoryStore) expand(b domain.Book, e Expand) domain.Book {
+	b.Authors = nil
+	if e < ExpandAuthors {
+		return b
+	}
+
+	b.Authors = []*domain.Author{}
+	for _, authorID := range m.bookAuthors(b.ID) {
+		a, ok := m.authors[authorID]
+		if !ok || a.DeletedAt.Valid {
+			continue
+		}
+
+		summary := &domain.Author{Name: a.Name}
+		summary.ID = a.ID
+
+		if e >= ExpandAuthorsBooks {
+			summary.Books = []*domain.Book{}
+			for _, bookID := range m.authorBooks(a.ID) {
+				if ab, ok := m.books[bookID]; ok && !ab.DeletedAt.Valid {
+					book := &domain.Book{Name: ab.Name}
+					book.ID = ab.ID
+					summary.Books = append(summary.Books, book)
+				}
+			}
+		}
+
+		b.Authors = append(b.Authors, summary)
+	}
+
+	return b
+}
+
+func (m *MemoryStore) record(ctx context.Context, entityType string, id uint, action string, before, after interface{}) error {
+	h := domain.History{
+		TenantID:   uctx.Tenant(ctx),
+		EntityType: entityType,
+		EntityID:   id,
+		Action:     action,
+		Actor:      uctx.Actor(ctx),
+		RequestID:  uctx.RequestID(ctx),
+		CreatedAt:  time.Now(),
+	}
+
+	var err error
+	if h.Before, err = snapshotJson(before); err != nil {
+		return err
+	}
+
+	if h.After, err = snapshotJson(after); err != nil {
+		return err
+	}
+
+	m.lastHistory++
+	h.ID = m.lastHistory
+	m.history = append(m.history, h)
+	return nil
+}
+
+func (m *MemoryStore) entityHistory(ctx context.Context, entityType string, id int) []domain.History {
+	tenant := uctx.Tenant(ctx)
+
+	entries := []domain.History{}
+	for _, h := range m.history {
+		if h.TenantID == tenant && h.EntityType == entityType && h.EntityID == uint(id) {
+			entries = append(entries, h)
+		}
+	}
+
+	return entries
+}
+
+func deletedNow() gorm.DeletedAt {
+	return gorm.DeletedAt{Time: time.Now(), Valid: true}
+}
+
+// matches tells whether every term of q is the prefix of a word of name, the
+// way the FTS5 search does.
+func matches(name string, q string) bool {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, term := range strings.Fields(strings.ToLower(q)) {
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func byNameAndID(name func(i int) string, id func(i int) uint) func(i, j int) bool {
+	return func(i, j int) bool {
+		if name(i) != name(j) {
+			return name(i) < name(j)
+		}
+		return id(i) < id(j)
+	}
+}
+
+// page returns the [start, end) bounds of a page over n rows.
+func page(n int, limit int, offset int) (int, int) {
+	if offset > n {
+		offset = n
+	}
+
+	end := n
+	if limit > 0 && offset+limit < n {
+		end = offset + limit
+	}
+
+	return offset, end
+}
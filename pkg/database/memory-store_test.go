@@ -0,0 +1,39 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreShouldSupportConcurrentAccess(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	repos := NewMemoryRepos(NewMemoryStore())
+	wg := sync.WaitGroup{}
+
+	// act
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			id, _ := repos.Books.Create(ctx, domain.Book{Name: fmt.Sprintf("Book %d", i)})
+			_ = repos.Books.Update(ctx, int(id), domain.Book{Name: fmt.Sprintf("Book %d", i), Version: 1})
+			repos.Books.GetAll(ctx, GetAllRequest{Expand: ExpandAuthorsBooks})
+		}(i)
+	}
+	wg.Wait()
+
+	// assert
+	books, err := repos.Books.GetAll(ctx, GetAllRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, books, 20)
+	for _, b := range books {
+		assert.Equal(t, uint(2), b.Version)
+	}
+}
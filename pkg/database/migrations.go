@@ -5,9 +5,20 @@ import (
 	"gorm.io/gorm"
 )
 
+// SchemaVersion identifies the tables a backup carries. Bump it whenever a
+// migration changes them, but not for tables backups leave out, such as the
+// outbox, so that Restore can tell what an older backup lacks.
+const SchemaVersion = 2
+
 func Migrate(db *gorm.DB) {
 
-	err := db.AutoMigrate(domain.Author{}, domain.Book{})
+	err := db.AutoMigrate(domain.Author{}, domain.Book{}, domain.History{}, domain.OutboxEvent{}, domain.OutboxOffset{})
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = MigrateSearch(db)
 
 	if err != nil {
 		panic(err)
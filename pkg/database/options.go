@@ -0,0 +1,98 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Options tunes the SQLite connection. The pragmas are passed through the dsn
+// so that every connection of the pool gets them, not only the first one.
+type Options struct {
+	JournalMode  string
+	Synchronous  string
+	BusyTimeout  time.Duration
+	ForeignKeys  bool
+	MaxOpenConns int
+	MaxIdleConns int
+	PrepareStmt  bool
+
+	// Replicas are the dsns of read-only connections. Reads are spread over
+	// them while writes always go to the primary dsn.
+	Replicas []string
+}
+
+// DefaultOptions lets a reader and a writer share the same database file
+// without failing with "database is locked".
+func DefaultOptions() Options {
+	return Options{
+		JournalMode:  "WAL",
+		Synchronous:  "NORMAL",
+		BusyTimeout:  5 * time.Second,
+		ForeignKeys:  true,
+		MaxOpenConns: 0,
+		MaxIdleConns: 2,
+		PrepareStmt:  false,
+	}
+}
+
+var journalModes = []string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}
+
+var synchronousLevels = []string{"OFF", "NORMAL", "FULL", "EXTRA"}
+
+func (o Options) Validate() error {
+	if !oneOf(o.JournalMode, journalModes) {
+		return fmt.Errorf("journal mode %q is invalid, use one of %s", o.JournalMode, strings.Join(journalModes, ", "))
+	}
+
+	if !oneOf(o.Synchronous, synchronousLevels) {
+		return fmt.Errorf("synchronous %q is invalid, use one of %s", o.Synchronous, strings.Join(synchronousLevels, ", "))
+	}
+
+	if o.BusyTimeout < 0 {
+		return fmt.Errorf("busy timeout %s is invalid", o.BusyTimeout)
+	}
+
+	if o.MaxOpenConns < 0 || o.MaxIdleConns < 0 {
+		return fmt.Errorf("connection limits can not be negative")
+	}
+
+	return nil
+}
+
+// Dsn appends the pragmas to dsn, keeping any parameter already set there.
+func (o Options) Dsn(dsn string) string {
+	params := url.Values{}
+	params.Set("_journal_mode", strings.ToUpper(o.JournalMode))
+	params.Set("_synchronous", strings.ToUpper(o.Synchronous))
+	params.Set("_busy_timeout", fmt.Sprintf("%d", o.BusyTimeout.Milliseconds()))
+	params.Set("_foreign_keys", fmt.Sprintf("%t", o.ForeignKeys))
+
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+
+	for key := range params {
+		if strings.Contains(dsn, key+"=") {
+			params.Del(key)
+		}
+	}
+
+	if len(params) == 0 {
+		return dsn
+	}
+
+	return dsn + separator + params.Encode()
+}
+
+func oneOf(value string, values []string) bool {
+	for _, v := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type OptionsSuite struct {
+	suite.Suite
+}
+
+func (s *OptionsSuite) TestDsnShouldAppendPragmas() {
+	// act
+	dsn := DefaultOptions().Dsn("bookstore.db")
+
+	// assert
+	s.Assert().Equal("bookstore.db?_busy_timeout=5000&_foreign_keys=true&_journal_mode=WAL&_synchronous=NORMAL", dsn)
+}
+
+func (s *OptionsSuite) TestDsnShouldKeepExistingParameters() {
+	// arrange
+	opts := DefaultOptions()
+	opts.BusyTimeout = time.Second
+
+	// act
+	dsn := opts.Dsn("file:bookstore.db?cache=shared&_journal_mode=DELETE")
+
+	// assert
+	s.Assert().Equal("file:bookstore.db?cache=shared&_journal_mode=DELETE&_busy_timeout=1000&_foreign_keys=true&_synchronous=NORMAL", dsn)
+}
+
+var testsInvalidOptions = []func(o *Options){
+	func(o *Options) { o.JournalMode = "fast" },
+	func(o *Options) { o.Synchronous = "" },
+	func(o *Options) { o.BusyTimeout = -time.Second },
+	func(o *Options) { o.MaxOpenConns = -1 },
+	func(o *Options) { o.MaxIdleConns = -1 },
+}
+
+func (s *OptionsSuite) TestValidateShouldRejectInvalidOptions() {
+	s.Assert().NoError(DefaultOptions().Validate())
+
+	for _, change := range testsInvalidOptions {
+		opts := DefaultOptions()
+		change(&opts)
+		s.Assert().Error(opts.Validate())
+	}
+}
+
+func (s *OptionsSuite) TestIntegrationInitDbShouldApplyPragmas() {
+	// arrange
+	opts := DefaultOptions()
+	opts.MaxOpenConns = 3
+	manager := NewDbManagerWithOptions(filepath.Join(s.T().TempDir(), "bookstore.db"), opts)
+
+	// act
+	err := manager.InitDb()
+	s.Require().NoError(err)
+	defer manager.Close()
+
+	// assert
+	var journalMode string
+	var foreignKeys, busyTimeout int
+	manager.GetDB().Raw("PRAGMA journal_mode").Scan(&journalMode)
+	manager.GetDB().Raw("PRAGMA foreign_keys").Scan(&foreignKeys)
+	manager.GetDB().Raw("PRAGMA busy_timeout").Scan(&busyTimeout)
+
+	db, _ := manager.GetDB().DB()
+	s.Assert().Equal("wal", journalMode)
+	s.Assert().Equal(1, foreignKeys)
+	s.Assert().Equal(5000, busyTimeout)
+	s.Assert().Equal(3, db.Stats().MaxOpenConnections)
+}
+
+func TestOptionsSuite(t *testing.T) {
+	suite.Run(t, new(OptionsSuite))
+}
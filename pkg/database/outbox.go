@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	BookCreated  = "BookCreated"
+	BookUpdated  = "BookUpdated"
+	BookDeleted  = "BookDeleted"
+	AuthorMerged = "AuthorMerged"
+)
+
+type BookPayload struct {
+	ID              uint   `json:"id"`
+	Name            string `json:"name"`
+	Edition         string `json:"edition"`
+	PublicationYear int    `json:"publication_year"`
+	Version         uint   `json:"version"`
+	Authors         []uint `json:"authors"`
+}
+
+type BookDeletedPayload struct {
+	ID      uint `json:"id"`
+	Version uint `json:"version"`
+}
+
+type AuthorMergedPayload struct {
+	ID    uint   `json:"id"`
+	Into  uint   `json:"into"`
+	Books []uint `json:"books"`
+}
+
+// publish writes an event to the outbox. It must be given the transaction of
+// the change, so that the event is stored if and only if the change is.
+func publish(ctx context.Context, tx *gorm.DB, eventType string, aggregateType string, id uint, payload interface{}) error {
+	bytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&domain.OutboxEvent{
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   id,
+		Payload:       string(bytes),
+		RequestID:     uctx.RequestID(ctx),
+	}).Error
+}
+
+// publishBook writes a BookCreated or BookUpdated event with the book as
+// stored by tx, its authors included.
+func publishBook(ctx context.Context, tx *gorm.DB, eventType string, book domain.Book) error {
+	authors := []uint{}
+	err := tx.Model(&domain.AuthorBook{}).Where("book_id = ?", book.ID).Order("author_id").Pluck("author_id", &authors).Error
+	if err != nil {
+		return err
+	}
+
+	return publish(ctx, tx, eventType, BookEntity, book.ID, BookPayload{
+		ID:              book.ID,
+		Name:            book.Name,
+		Edition:         book.Edition,
+		PublicationYear: book.PublicationYear,
+		Version:         book.Version,
+		Authors:         authors,
+	})
+}
+
+// OutboxRepository reads the outbox of the tenant of the context and keeps
+// track of what each consumer has been delivered.
+type OutboxRepository interface {
+	// Read returns up to limit events with an id greater than after, in id
+	// order.
+	Read(ctx context.Context, after uint, limit int) ([]domain.OutboxEvent, error)
+	// Offset returns the id of the last event delivered to consumer, 0 when
+	// it has not been delivered any.
+	Offset(ctx context.Context, consumer string) (uint, error)
+	// Commit stores the id of the last event delivered to consumer.
+	Commit(ctx context.Context, consumer string, position uint) error
+}
+
+type outboxRepository struct {
+	manager DBManager
+}
+
+func NewOutboxRepository(m DBManager) OutboxRepository {
+	return &outboxRepository{
+		manager: m,
+	}
+}
+
+func (o *outboxRepository) db(ctx context.Context) *gorm.DB {
+	return o.manager.GetDB().WithContext(ctx)
+}
+
+func (o *outboxRepository) Read(ctx context.Context, after uint, limit int) ([]domain.OutboxEvent, error) {
+	events := []domain.OutboxEvent{}
+	err := o.db(ctx).Where("id > ?", after).Order("id").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+func (o *outboxRepository) Offset(ctx context.Context, consumer string) (uint, error) {
+	offsets := []domain.OutboxOffset{}
+	if err := o.db(ctx).Where("consumer = ?", consumer).Limit(1).Find(&offsets).Error; err != nil {
+		return 0, err
+	}
+
+	if len(offsets) == 0 {
+		return 0, nil
+	}
+
+	return offsets[0].Position, nil
+}
+
+func (o *outboxRepository) Commit(ctx context.Context, consumer string, position uint) error {
+	return o.db(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "consumer"}},
+		DoUpdates: clause.AssignmentColumns([]string{"position", "updated_at"}),
+	}).Create(&domain.OutboxOffset{Consumer: consumer, Position: position}).Error
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"github.com/stretchr/testify/suite"
+)
+
+type OutboxIntegrationSuite struct {
+	suite.Suite
+
+	ctx     context.Context
+	manager DBManager
+	repos   Repos
+	outbox  OutboxRepository
+}
+
+func (s *OutboxIntegrationSuite) SetupTest() {
+	s.ctx = uctx.WithRequestID(context.Background(), "request-1")
+	s.manager = NewDbManager(fmt.Sprintf("file:%s?mode=memory&cache=shared", s.T().Name()))
+	s.Require().NoError(s.manager.InitDb())
+	Migrate(s.manager.GetDB())
+
+	s.repos = NewRepos(s.manager)
+	s.outbox = NewOutboxRepository(s.manager)
+}
+
+func (s *OutboxIntegrationSuite) TearDownTest() {
+	s.Require().NoError(s.manager.Close())
+}
+
+func (s *OutboxIntegrationSuite) events() []domain.OutboxEvent {
+	events, err := s.outbox.Read(s.ctx, 0, 100)
+	s.Require().NoError(err)
+	return events
+}
+
+func (s *OutboxIntegrationSuite) TestBookChangesShouldBePublished() {
+	// arrange
+	author, err := s.repos.Authors.Create(s.ctx, domain.Author{Name: "Alan Donovan"})
+	s.Require().NoError(err)
+
+	b := domain.Book{Name: "The Go Programming Language", Edition: "1", PublicationYear: 2015, Authors: []*domain.Author{{}}}
+	b.Authors[0].ID = author
+
+	// act
+	id, err := s.repos.Books.Create(s.ctx, b)
+	s.Require().NoError(err)
+	s.Require().NoError(s.repos.Books.Update(s.ctx, int(id), domain.Book{Name: "The Go Programming Language", Edition: "2", PublicationYear: 2016}))
+	s.Require().NoError(s.repos.Books.Delete(s.ctx, int(id), 0))
+
+	// assert
+	events := s.events()
+	s.Require().Len(events, 3)
+	s.Assert().Equal([]string{BookCreated, BookUpdated, BookDeleted}, []string{events[0].Type, events[1].Type, events[2].Type})
+
+	for _, e := range events {
+		s.Assert().Equal(BookEntity, e.AggregateType)
+		s.Assert().Equal(id, e.AggregateID)
+		s.Assert().Equal("request-1", e.RequestID)
+		s.Assert().Equal(uctx.DefaultTenant, e.TenantID)
+	}
+
+	updated := BookPayload{}
+	s.Require().NoError(json.Unmarshal([]byte(events[1].Payload), &updated))
+	s.Assert().Equal(BookPayload{ID: id, Name: "The Go Programming Language", Edition: "2", PublicationYear: 2016, Version: 2, Authors: []uint{author}}, updated)
+
+	deleted := BookDeletedPayload{}
+	s.Require().NoError(json.Unmarshal([]byte(events[2].Payload), &deleted))
+	s.Assert().Equal(BookDeletedPayload{ID: id, Version: 2}, deleted)
+}
+
+func (s *OutboxIntegrationSuite) TestFailedChangeShouldNotBePublished() {
+	// arrange
+	b := domain.Book{Name: "Orphan", Authors: []*domain.Author{{}}}
+	b.Authors[0].ID = 42
+
+	// act
+	_, err := s.repos.Books.Create(s.ctx, b)
+
+	// assert
+	s.Assert().Equal(ErrAuthorNotFound, err)
+	s.Assert().Empty(s.events())
+}
+
+func (s *OutboxIntegrationSuite) TestMergedAuthorShouldBePublished() {
+	// arrange
+	kernighan, _ := s.repos.Authors.Create(s.ctx, domain.Author{Name: "Brian Kernighan"})
+	duplicate, _ := s.repos.Authors.Create(s.ctx, domain.Author{Name: "B. W. Kernighan"})
+
+	b := domain.Book{Name: "The C Programming Language", Authors: []*domain.Author{{}}}
+	b.Authors[0].ID = duplicate
+	book, err := s.repos.Books.Create(s.ctx, b)
+	s.Require().NoError(err)
+
+	// act
+	err = s.repos.Authors.Merge(s.ctx, int(duplicate), int(kernighan))
+
+	// assert
+	s.Require().NoError(err)
+	events := s.events()
+	s.Require().Len(events, 2)
+	s.Assert().Equal(AuthorMerged, events[1].Type)
+	s.Assert().Equal(duplicate, events[1].AggregateID)
+
+	merged := AuthorMergedPayload{}
+	s.Require().NoError(json.Unmarshal([]byte(events[1].Payload), &merged))
+	s.Assert().Equal(AuthorMergedPayload{ID: duplicate, Into: kernighan, Books: []uint{book}}, merged)
+}
+
+func (s *OutboxIntegrationSuite) TestUnlinkedBooksShouldBePublished() {
+	// arrange
+	kernighan, _ := s.repos.Authors.Create(s.ctx, domain.Author{Name: "Brian Kernighan"})
+	ritchie, _ := s.repos.Authors.Create(s.ctx, domain.Author{Name: "Dennis Ritchie"})
+
+	b := domain.Book{Name: "The C Programming Language", Authors: []*domain.Author{{}, {}}}
+	b.Authors[0].ID = kernighan
+	b.Authors[1].ID = ritchie
+	book, err := s.repos.Books.Create(s.ctx, b)
+	s.Require().NoError(err)
+
+	// act
+	err = s.repos.Authors.Delete(s.ctx, int(ritchie), 0, true)
+
+	// assert
+	s.Require().NoError(err)
+	events := s.events()
+	s.Require().Len(events, 2)
+	s.Assert().Equal(BookUpdated, events[1].Type)
+
+	updated := BookPayload{}
+	s.Require().NoError(json.Unmarshal([]byte(events[1].Payload), &updated))
+	s.Assert().Equal(book, updated.ID)
+	s.Assert().Equal(uint(2), updated.Version)
+	s.Assert().Equal([]uint{kernighan}, updated.Authors)
+}
+
+func (s *OutboxIntegrationSuite) TestOutboxShouldBeReadInOrderAfterAnOffset() {
+	// arrange
+	for i := 0; i < 5; i++ {
+		_, err := s.repos.Books.Create(s.ctx, domain.Book{Name: fmt.Sprintf("Book %d", i)})
+		s.Require().NoError(err)
+	}
+
+	// act
+	events, err := s.outbox.Read(s.ctx, 2, 2)
+
+	// assert
+	s.Require().NoError(err)
+	s.Require().Len(events, 2)
+	s.Assert().Equal(uint(3), events[0].ID)
+	s.Assert().Equal(uint(4), events[1].ID)
+}
+
+func (s *OutboxIntegrationSuite) TestOffsetsShouldBeTrackedPerConsumerAndTenant() {
+	// arrange
+	other := uctx.WithTenant(s.ctx, "other")
+
+	// act
+	s.Require().NoError(s.outbox.Commit(s.ctx, "search", 3))
+	s.Require().NoError(s.outbox.Commit(s.ctx, "search", 7))
+	s.Require().NoError(s.outbox.Commit(s.ctx, "analytics", 1))
+
+	// assert
+	search, err := s.outbox.Offset(s.ctx, "search")
+	s.Require().NoError(err)
+	s.Assert().Equal(uint(7), search)
+
+	analytics, _ := s.outbox.Offset(s.ctx, "analytics")
+	s.Assert().Equal(uint(1), analytics)
+
+	unknown, _ := s.outbox.Offset(other, "search")
+	s.Assert().Equal(uint(0), unknown)
+}
+
+func (s *OutboxIntegrationSuite) TestOutboxShouldBeScopedToTheTenant() {
+	// arrange
+	other := uctx.WithTenant(s.ctx, "other")
+	_, err := s.repos.Books.Create(other, domain.Book{Name: "Elsewhere"})
+	s.Require().NoError(err)
+
+	// act
+	events, err := s.outbox.Read(s.ctx, 0, 10)
+
+	// assert
+	s.Require().NoError(err)
+	s.Assert().Empty(events)
+}
+
+func TestIntegrationOutboxSuite(t *testing.T) {
+	suite.Run(t, new(OutboxIntegrationSuite))
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type PaginationIntegrationSuite struct {
+	suite.Suite
+
+	ctx     context.Context
+	manager DBManager
+	authors AuthorsRepository
+	books   BooksRepository
+}
+
+func (s *PaginationIntegrationSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.manager = NewDbManager(fmt.Sprintf("file:%s?mode=memory&cache=shared", s.T().Name()))
+	s.Require().NoError(s.manager.InitDb())
+	Migrate(s.manager.GetDB())
+
+	s.authors = NewAuthorsRepository(s.manager)
+	s.books = NewBooksRepository(s.manager)
+
+	for _, name := range []string{"E", "B", "D", "A", "C", "B"} {
+		s.manager.GetDB().Create(&domain.Author{Name: name})
+		s.manager.GetDB().Create(&domain.Book{Name: name})
+	}
+}
+
+func (s *PaginationIntegrationSuite) TearDownTest() {
+	s.Require().NoError(s.manager.Close())
+}
+
+func (s *PaginationIntegrationSuite) TestAuthorsShouldWalkEveryRowOnce() {
+	// arrange
+	seen := map[uint]string{}
+	var after *Cursor
+
+	// act
+	for page := 0; page < 10; page++ {
+		authors, err := s.authors.GetAll(s.ctx, GetAuthorsRequest{Limit: 2, After: after})
+		s.Require().NoError(err)
+		if len(authors) == 0 {
+			break
+		}
+
+		for _, a := range authors {
+			_, ok := seen[a.ID]
+			s.Assert().False(ok, "author %d returned twice", a.ID)
+			seen[a.ID] = a.Name
+		}
+
+		// rows inserted before the cursor must not shift the next pages
+		s.manager.GetDB().Create(&domain.Author{Name: "0"})
+
+		cursor := AuthorCursor(authors[len(authors)-1], nil)
+		after = &cursor
+	}
+
+	// assert
+	s.Assert().Len(seen, 6)
+}
+
+func (s *PaginationIntegrationSuite) TestBooksShouldBeOrderedByNameAndId() {
+	// arrange
+	first, err := s.books.GetAll(s.ctx, GetAllRequest{Limit: 3})
+	s.Require().NoError(err)
+	cursor := BookCursor(first[len(first)-1], nil)
+
+	// act
+	second, err := s.books.GetAll(s.ctx, GetAllRequest{Limit: 3, After: &cursor})
+	s.Require().NoError(err)
+
+	// assert
+	names := []string{}
+	for _, b := range append(first, second...) {
+		names = append(names, b.Name)
+	}
+	s.Assert().Equal([]string{"A", "B", "B", "C", "D", "E"}, names)
+	s.Assert().Less(first[1].ID, first[2].ID)
+}
+
+func (s *PaginationIntegrationSuite) TestCursorShouldRoundTrip() {
+	// arrange
+	cursor := AuthorCursor(domain.Author{Name: "Luciano Ramalho"}, nil)
+	cursor.ID = 42
+
+	// act
+	parsed, err := ParseCursor(cursor.String())
+
+	// assert
+	s.Assert().NoError(err)
+	s.Assert().Equal(cursor, parsed)
+}
+
+func (s *PaginationIntegrationSuite) TestCursorShouldRejectGarbage() {
+	for _, c := range []string{"???", "bm90LWpzb24", "e30"} {
+		_, err := ParseCursor(c)
+		s.Assert().Equal(ErrInvalidCursor, err, c)
+	}
+}
+
+func (s *PaginationIntegrationSuite) TestCursorShouldRejectKeysThatAreNotScalars() {
+	for _, c := range []string{`{"k":[{"x":1}],"id":1}`, `{"k":[["x"]],"id":1}`, `{"k":[null],"id":1}`, `{"k":[true],"id":1}`} {
+		_, err := ParseCursor(base64.RawURLEncoding.EncodeToString([]byte(c)))
+		s.Assert().Equal(ErrInvalidCursor, err, c)
+	}
+}
+
+var testsParseSort = []struct {
+	sort     string
+	expected Sort
+	fails    bool
+}{
+	{sort: "", expected: nil},
+	{sort: "name", expected: Sort{{Field: "name"}}},
+	{sort: "-publication_year,name", expected: Sort{{Field: "publication_year", Desc: true}, {Field: "name"}}},
+	{sort: "id", fails: true},
+	{sort: "name,-name", fails: true},
+	{sort: "name,", fails: true},
+}
+
+func (s *PaginationIntegrationSuite) TestParseSort() {
+	for _, t := range testsParseSort {
+		sort, err := ParseSort(t.sort, BookSortFields)
+
+		if t.fails {
+			s.Assert().True(errors.Is(err, ErrInvalidSort), t.sort)
+		} else {
+			s.Assert().NoError(err, t.sort)
+			s.Assert().Equal(t.expected, sort, t.sort)
+			s.Assert().Equal(t.sort, sort.String())
+		}
+	}
+}
+
+func (s *PaginationIntegrationSuite) TestSortedPagesShouldBeReadFromAnIndex() {
+	for _, sort := range []Sort{
+		nil,
+		{{Field: "publication_year", Desc: true}, {Field: "name"}},
+		{{Field: "publication_year"}},
+		{{Field: "publication_year", Desc: true}},
+		{{Field: "edition", Desc: true}},
+	} {
+		// arrange
+		var last domain.Book
+		s.Require().NoError(s.manager.GetDB().Last(&last).Error)
+		cursor := BookCursor(last, sort)
+
+		db := keyset(s.manager.GetDB().Model(&domain.Book{}), "books", sort, &cursor).Limit(10)
+		stmt := db.Session(&gorm.Session{DryRun: true}).Find(&[]domain.Book{}).Statement
+
+		// act
+		rows, err := s.manager.GetDB().Raw("EXPLAIN QUERY PLAN "+stmt.SQL.String(), stmt.Vars...).Rows()
+		s.Require().NoError(err)
+
+		var plan []string
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			s.Require().NoError(rows.Scan(&id, &parent, &unused, &detail))
+			plan = append(plan, detail)
+		}
+		s.Require().NoError(rows.Close())
+
+		// assert
+		s.Assert().Contains(strings.Join(plan, "\n"), "USING INDEX idx_books_sort_", sort.String())
+		s.Assert().NotContains(strings.Join(plan, "\n"), "TEMP B-TREE", sort.String())
+	}
+}
+
+func TestIntegrationPaginationSuite(t *testing.T) {
+	suite.Run(t, new(PaginationIntegrationSuite))
+}
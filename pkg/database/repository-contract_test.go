@@ -0,0 +1,702 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+// RepositoryContractSuite checks the behaviour every repository implementation
+// must share, whatever its storage.
+type RepositoryContractSuite struct {
+	suite.Suite
+
+	open func(name string) (Repos, func() error)
+
+	ctx     context.Context
+	repos   Repos
+	closeFn func() error
+}
+
+func (s *RepositoryContractSuite) SetupTest() {
+	s.ctx = uctx.WithActor(context.Background(), "tester")
+	s.repos, s.closeFn = s.open(s.T().Name())
+}
+
+func (s *RepositoryContractSuite) TearDownTest() {
+	s.Require().NoError(s.closeFn())
+}
+
+func (s *RepositoryContractSuite) author(name string) uint {
+	id, err := s.repos.Authors.Create(s.ctx, domain.Author{Name: name})
+	s.Require().NoError(err)
+	return id
+}
+
+func (s *RepositoryContractSuite) book(name string, edition string, year int, authors ...uint) uint {
+	b := domain.Book{Name: name, Edition: edition, PublicationYear: year}
+	for _, id := range authors {
+		a := &domain.Author{}
+		a.ID = id
+		b.Authors = append(b.Authors, a)
+	}
+
+	id, err := s.repos.Books.Create(s.ctx, b)
+	s.Require().NoError(err)
+	return id
+}
+
+func (s *RepositoryContractSuite) books(r GetAllRequest) []domain.Book {
+	books, err := s.repos.Books.GetAll(s.ctx, r)
+	s.Require().NoError(err)
+	return books
+}
+
+func (s *RepositoryContractSuite) authors(r GetAuthorsRequest) []domain.Author {
+	authors, err := s.repos.Authors.GetAll(s.ctx, r)
+	s.Require().NoError(err)
+	return authors
+}
+
+func bookNames(books []domain.Book) []string {
+	names := []string{}
+	for _, b := range books {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+func authorNames(authors []domain.Author) []string {
+	names := []string{}
+	for _, a := range authors {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+func (s *RepositoryContractSuite) TestBooksShouldBeFiltered() {
+	// arrange
+	kernighan := s.author("Brian Kernighan")
+	s.book("The Go Programming Language", "1", 2015, kernighan)
+	s.book("The C Programming Language", "2", 1988, kernighan)
+	s.book("Python Cookbook", "3", 2013)
+
+	// act
+	byName := s.books(GetAllRequest{Name: "Python Cookbook"})
+	byEdition := s.books(GetAllRequest{Edition: "2"})
+	byYear := s.books(GetAllRequest{PublicationYear: 2015})
+	byAuthor := s.books(GetAllRequest{Author: int(kernighan)})
+	byQuery := s.books(GetAllRequest{Query: "program lang"})
+
+	// assert
+	s.Assert().Equal([]string{"Python Cookbook"}, bookNames(byName))
+	s.Assert().Equal([]string{"The C Programming Language"}, bookNames(byEdition))
+	s.Assert().Equal([]string{"The Go Programming Language"}, bookNames(byYear))
+	s.Assert().Equal([]string{"The C Programming Language", "The Go Programming Language"}, bookNames(byAuthor))
+	s.Assert().ElementsMatch([]string{"The C Programming Language", "The Go Programming Language"}, bookNames(byQuery))
+}
+
+func (s *RepositoryContractSuite) TestBooksShouldBePaged() {
+	// arrange
+	for _, name := range []string{"E", "B", "D", "A", "C", "B"} {
+		s.book(name, "1", 2000)
+	}
+
+	// act
+	first := s.books(GetAllRequest{Limit: 2, Offset: 1})
+	cursor := BookCursor(first[len(first)-1], nil)
+	second := s.books(GetAllRequest{Limit: 3, After: &cursor})
+
+	// assert
+	s.Assert().Equal([]string{"B", "B"}, bookNames(first))
+	s.Assert().Equal([]string{"C", "D", "E"}, bookNames(second))
+}
+
+func (s *RepositoryContractSuite) TestBooksShouldBeSortedAndWalkedByOffsetOrCursor() {
+	// arrange
+	s.book("B", "2", 2015)
+	s.book("A", "1", 1988)
+	s.book("C", "1", 2015)
+	s.book("A", "3", 2015)
+	s.book("D", "1", 2001)
+	s.book("A", "2", 2015)
+
+	sort := Sort{{Field: "publication_year", Desc: true}, {Field: "name"}}
+	expected := []string{"A 3", "A 2", "B 2", "C 1", "D 1", "A 1"}
+
+	// act
+	all := s.books(GetAllRequest{Sort: sort})
+
+	var offsets, cursors []domain.Book
+	for offset := 0; offset < len(all); offset += 2 {
+		offsets = append(offsets, s.books(GetAllRequest{Sort: sort, Limit: 2, Offset: offset})...)
+	}
+
+	var after *Cursor
+	for {
+		page := s.books(GetAllRequest{Sort: sort, Limit: 2, After: after})
+		if len(page) == 0 {
+			break
+		}
+
+		cursors = append(cursors, page...)
+		cursor, err := ParseCursor(BookCursor(page[len(page)-1], sort).String())
+		s.Require().NoError(err)
+		after = &cursor
+	}
+
+	byEdition := s.books(GetAllRequest{Sort: Sort{{Field: "edition", Desc: true}}, Limit: 2})
+
+	// assert
+	label := func(books []domain.Book) []string {
+		labels := []string{}
+		for _, b := range books {
+			labels = append(labels, b.Name+" "+b.Edition)
+		}
+		return labels
+	}
+
+	s.Assert().Equal(expected, label(all))
+	s.Assert().Equal(expected, label(offsets))
+	s.Assert().Equal(expected, label(cursors))
+	s.Assert().Equal([]string{"A 3", "A 2"}, label(byEdition))
+}
+
+func (s *RepositoryContractSuite) TestAuthorsShouldBeSorted() {
+	// arrange
+	for _, name := range []string{"Rob Pike", "Brian Kernighan", "Dennis Ritchie"} {
+		s.author(name)
+	}
+
+	sort := Sort{{Field: "name", Desc: true}}
+
+	// act
+	first := s.authors(GetAuthorsRequest{Sort: sort, Limit: 2})
+	cursor := AuthorCursor(first[len(first)-1], sort)
+	second := s.authors(GetAuthorsRequest{Sort: sort, Limit: 2, After: &cursor})
+	misfit := s.authors(GetAuthorsRequest{Limit: 2, After: &cursor})
+
+	// assert
+	s.Assert().Equal([]string{"Rob Pike", "Dennis Ritchie"}, authorNames(first))
+	s.Assert().Equal([]string{"Brian Kernighan"}, authorNames(second))
+	s.Assert().Empty(misfit)
+}
+
+func (s *RepositoryContractSuite) TestListingsShouldBeCountedOverEveryPage() {
+	// arrange
+	kernighan := s.author("Brian Kernighan")
+	s.author("Dennis Ritchie")
+	s.book("The Go Programming Language", "1", 2015, kernighan)
+	s.book("The C Programming Language", "2", 1988, kernighan)
+	deleted := s.book("The AWK Programming Language", "1", 1988, kernighan)
+	s.Require().NoError(s.repos.Books.Delete(s.ctx, int(deleted), 0))
+	s.book("Python Cookbook", "3", 2013)
+
+	cursor := Cursor{Keys: []interface{}{"Python Cookbook"}, ID: 1}
+	theirs := uctx.WithTenant(s.ctx, "other")
+
+	// act
+	all, err := s.repos.Books.Count(s.ctx, GetAllRequest{Limit: 1, Offset: 1, After: &cursor})
+	s.Require().NoError(err)
+	byAuthor, _ := s.repos.Books.Count(s.ctx, GetAllRequest{Author: int(kernighan), Limit: 1})
+	byQuery, _ := s.repos.Books.Count(s.ctx, GetAllRequest{Query: "program lang"})
+	authors, err := s.repos.Authors.Count(s.ctx, GetAuthorsRequest{Limit: 1})
+	s.Require().NoError(err)
+	byName, _ := s.repos.Authors.Count(s.ctx, GetAuthorsRequest{Name: "ritchie"})
+	foreign, _ := s.repos.Books.Count(theirs, GetAllRequest{})
+
+	// assert
+	s.Assert().Equal(int64(3), all)
+	s.Assert().Equal(int64(2), byAuthor)
+	s.Assert().Equal(int64(2), byQuery)
+	s.Assert().Equal(int64(2), authors)
+	s.Assert().Equal(int64(1), byName)
+	s.Assert().Equal(int64(0), foreign)
+}
+
+func (s *RepositoryContractSuite) TestBookShouldExpandAuthorsAndTheirBooks() {
+	// arrange
+	pike := s.author("Rob Pike")
+	kernighan := s.author("Brian Kernighan")
+	goBook := s.book("The Go Programming Language", "1", 2015, kernighan)
+	s.book("The Unix Programming Environment", "1", 1984, pike, kernighan)
+	deleted := s.book("Deleted", "1", 2000, kernighan)
+	s.Require().NoError(s.repos.Books.Delete(s.ctx, int(deleted), 0))
+
+	// act
+	plain, err := s.repos.Books.GetBook(s.ctx, int(goBook), ExpandNone)
+	s.Require().NoError(err)
+	expanded, err := s.repos.Books.GetBook(s.ctx, int(goBook), ExpandAuthorsBooks)
+	s.Require().NoError(err)
+
+	// assert
+	s.Assert().Empty(plain.Authors)
+	s.Require().Len(expanded.Authors, 1)
+	s.Assert().Equal("Brian Kernighan", expanded.Authors[0].Name)
+
+	names := []string{}
+	for _, b := range expanded.Authors[0].Books {
+		names = append(names, b.Name)
+	}
+	s.Assert().Equal([]string{"The Go Programming Language", "The Unix Programming Environment"}, names)
+}
+
+func (s *RepositoryContractSuite) TestBookShouldNotLinkUnknownAuthors() {
+	// arrange
+	unknown := &domain.Author{}
+	unknown.ID = 42
+
+	// act
+	_, err := s.repos.Books.Create(s.ctx, domain.Book{Name: "Orphan", Authors: []*domain.Author{unknown}})
+
+	// assert
+	s.Assert().Error(err)
+}
+
+func (s *RepositoryContractSuite) TestBookShouldNotLinkDeletedAuthors() {
+	// arrange
+	deleted := &domain.Author{}
+	deleted.ID = s.author("Ken Thompson")
+	s.Require().NoError(s.repos.Authors.Delete(s.ctx, int(deleted.ID), 0, false))
+
+	// act
+	_, err := s.repos.Books.Create(s.ctx, domain.Book{Name: "Unix", Authors: []*domain.Author{deleted}})
+
+	// assert
+	s.Assert().Equal(ErrAuthorNotFound, err)
+	s.Assert().Empty(s.books(GetAllRequest{}))
+}
+
+func (s *RepositoryContractSuite) TestMissingBookShouldNotBeFound() {
+	// act
+	_, getErr := s.repos.Books.GetBook(s.ctx, 42, ExpandNone)
+	updateErr := s.repos.Books.Update(s.ctx, 42, domain.Book{Name: "Missing"})
+	deleteErr := s.repos.Books.Delete(s.ctx, 42, 0)
+	restoreErr := s.repos.Books.Restore(s.ctx, 42)
+
+	// assert
+	s.Assert().Error(getErr)
+	s.Assert().Error(updateErr)
+	s.Assert().Equal(gorm.ErrRecordNotFound, deleteErr)
+	s.Assert().Error(restoreErr)
+}
+
+func (s *RepositoryContractSuite) TestBookShouldFollowItsLifecycle() {
+	// arrange
+	id := s.book("Draft", "1", 2020)
+
+	// act
+	staleErr := s.repos.Books.Update(s.ctx, int(id), domain.Book{Name: "Stale", Version: 2})
+	s.Require().NoError(s.repos.Books.Update(s.ctx, int(id), domain.Book{Name: "Final", Version: 1}))
+	staleDeleteErr := s.repos.Books.Delete(s.ctx, int(id), 1)
+	s.Require().NoError(s.repos.Books.Delete(s.ctx, int(id), 2))
+	trash, err := s.repos.Books.GetTrash(s.ctx, PageRequest{})
+	s.Require().NoError(err)
+	_, deletedErr := s.repos.Books.GetBook(s.ctx, int(id), ExpandNone)
+	s.Require().NoError(s.repos.Books.Restore(s.ctx, int(id)))
+	restored, err := s.repos.Books.GetBook(s.ctx, int(id), ExpandNone)
+	s.Require().NoError(err)
+
+	// assert
+	s.Assert().Equal(ErrVersionMismatch, staleErr)
+	s.Assert().Equal(ErrVersionMismatch, staleDeleteErr)
+	s.Assert().Equal([]string{"Final"}, bookNames(trash))
+	s.Assert().Error(deletedErr)
+	s.Assert().Equal("Final", restored.Name)
+	s.Assert().Equal(uint(3), restored.Version)
+
+	history, err := s.repos.Books.GetHistory(s.ctx, int(id))
+	s.Require().NoError(err)
+
+	actions := []string{}
+	for _, h := range history {
+		s.Assert().Equal("tester", h.Actor)
+		actions = append(actions, h.Action)
+	}
+	s.Assert().Equal([]string{ActionCreate, ActionUpdate, ActionDelete, ActionRestore}, actions)
+}
+
+func (s *RepositoryContractSuite) TestDeletedBooksShouldBePurged() {
+	// arrange
+	author := s.author("Author")
+	s.book("Kept", "1", 2000, author)
+	purged := s.book("Purged", "1", 2000, author)
+	s.Require().NoError(s.repos.Books.Delete(s.ctx, int(purged), 0))
+
+	// act
+	count, err := s.repos.Books.Purge(s.ctx, time.Now().Add(time.Minute))
+
+	// assert
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(1), count)
+	trash, err := s.repos.Books.GetTrash(s.ctx, PageRequest{})
+	s.Require().NoError(err)
+	s.Assert().Empty(trash)
+	s.Assert().Equal([]string{"Kept"}, bookNames(s.books(GetAllRequest{Author: int(author)})))
+}
+
+func (s *RepositoryContractSuite) TestAuthorsShouldBeFilteredAndPaged() {
+	// arrange
+	for _, name := range []string{"Rob Pike", "Brian Kernighan", "Robert Griesemer", "Ken Thompson"} {
+		s.author(name)
+	}
+
+	// act
+	byName := s.authors(GetAuthorsRequest{Name: "rob"})
+	byQuery := s.authors(GetAuthorsRequest{Query: "ken"})
+	first := s.authors(GetAuthorsRequest{Limit: 2})
+	cursor := AuthorCursor(first[len(first)-1], nil)
+	second := s.authors(GetAuthorsRequest{Limit: 2, After: &cursor})
+
+	// assert
+	s.Assert().Equal([]string{"Rob Pike", "Robert Griesemer"}, authorNames(byName))
+	s.Assert().Equal([]string{"Ken Thompson"}, authorNames(byQuery))
+	s.Assert().Equal([]string{"Brian Kernighan", "Ken Thompson"}, authorNames(first))
+	s.Assert().Equal([]string{"Rob Pike", "Robert Griesemer"}, authorNames(second))
+	history, err := s.repos.Authors.GetHistory(s.ctx, int(first[0].ID))
+	s.Require().NoError(err)
+	s.Assert().Len(history, 1)
+}
+
+func (s *RepositoryContractSuite) TestAuthorShouldFollowItsLifecycle() {
+	// arrange
+	id := s.author("Brian Kernigan")
+
+	// act
+	mismatchErr := s.repos.Authors.Update(s.ctx, int(id), domain.Author{Name: "Brian Kernighan", Version: 2})
+	updateErr := s.repos.Authors.Update(s.ctx, int(id), domain.Author{Name: "Brian Kernighan", Version: 1})
+	updated, getErr := s.repos.Authors.GetAuthor(s.ctx, int(id))
+	deleteErr := s.repos.Authors.Delete(s.ctx, int(id), 2, false)
+	_, deletedErr := s.repos.Authors.GetAuthor(s.ctx, int(id))
+
+	// assert
+	s.Assert().Equal(ErrVersionMismatch, mismatchErr)
+	s.Require().NoError(updateErr)
+	s.Require().NoError(getErr)
+	s.Assert().Equal("Brian Kernighan", updated.Name)
+	s.Assert().Equal(uint(2), updated.Version)
+	s.Require().NoError(deleteErr)
+	s.Assert().Equal(gorm.ErrRecordNotFound, deletedErr)
+	s.Assert().Equal(gorm.ErrRecordNotFound, s.repos.Authors.Update(s.ctx, int(id), domain.Author{Name: "Ghost"}))
+	s.Assert().Equal(gorm.ErrRecordNotFound, s.repos.Authors.Delete(s.ctx, int(id), 0, false))
+
+	history, err := s.repos.Authors.GetHistory(s.ctx, int(id))
+	s.Require().NoError(err)
+	s.Require().Len(history, 3)
+	s.Assert().Equal([]string{ActionCreate, ActionUpdate, ActionDelete}, []string{history[0].Action, history[1].Action, history[2].Action})
+}
+
+func (s *RepositoryContractSuite) TestAuthorWithBooksShouldOnlyBeDeletedWhenUnlinked() {
+	// arrange
+	kernighan := s.author("Brian Kernighan")
+	ritchie := s.author("Dennis Ritchie")
+	book := s.book("The C Programming Language", "2", 1988, kernighan, ritchie)
+
+	// act
+	rejected := s.repos.Authors.Delete(s.ctx, int(ritchie), 0, false)
+	unlinked := s.repos.Authors.Delete(s.ctx, int(ritchie), 0, true)
+
+	// assert
+	s.Assert().Equal(ErrAuthorHasBooks, rejected)
+	s.Require().NoError(unlinked)
+
+	b, err := s.repos.Books.GetBook(s.ctx, int(book), ExpandAuthors)
+	s.Require().NoError(err)
+	s.Require().Len(b.Authors, 1)
+	s.Assert().Equal(kernighan, b.Authors[0].ID)
+	s.Assert().Equal(uint(2), b.Version)
+	s.Assert().Equal([]string{"Brian Kernighan"}, authorNames(s.authors(GetAuthorsRequest{})))
+
+	history, err := s.repos.Books.GetHistory(s.ctx, int(book))
+	s.Require().NoError(err)
+	s.Require().NotEmpty(history)
+	s.Assert().Equal(ActionUnlink, history[len(history)-1].Action)
+}
+
+func (s *RepositoryContractSuite) TestAuthorOfDeletedBooksOnlyShouldBeDeleted() {
+	// arrange
+	ritchie := s.author("Dennis Ritchie")
+	book := s.book("The C Programming Language", "2", 1988, ritchie)
+	s.Require().NoError(s.repos.Books.Delete(s.ctx, int(book), 0))
+
+	// act
+	err := s.repos.Authors.Delete(s.ctx, int(ritchie), 0, false)
+
+	// assert
+	s.Assert().NoError(err)
+}
+
+func (s *RepositoryContractSuite) TestBookAndAuthorRestoredFromTrashShouldStayLinked() {
+	// arrange
+	ritchie := s.author("Dennis Ritchie")
+	book := s.book("The C Programming Language", "2", 1988, ritchie)
+	s.Require().NoError(s.repos.Books.Delete(s.ctx, int(book), 0))
+	s.Require().NoError(s.repos.Authors.Delete(s.ctx, int(ritchie), 0, false))
+
+	// act
+	s.Require().NoError(s.repos.Books.Restore(s.ctx, int(book)))
+	s.Require().NoError(s.repos.Authors.Restore(s.ctx, int(ritchie)))
+
+	// assert
+	b, err := s.repos.Books.GetBook(s.ctx, int(book), ExpandAuthors)
+	s.Require().NoError(err)
+	s.Require().Len(b.Authors, 1)
+	s.Assert().Equal(ritchie, b.Authors[0].ID)
+}
+
+func (s *RepositoryContractSuite) TestAuthorsShouldBeLinkedToBooksOneByOne() {
+	// arrange
+	kernighan := s.author("Brian Kernighan")
+	ritchie := s.author("Dennis Ritchie")
+	book := s.book("The C Programming Language", "2", 1988, kernighan)
+
+	// act
+	addErr := s.repos.Books.AddAuthor(s.ctx, int(book), int(ritchie), 1)
+	againErr := s.repos.Books.AddAuthor(s.ctx, int(book), int(ritchie), 0)
+	staleErr := s.repos.Books.AddAuthor(s.ctx, int(book), int(ritchie), 1)
+	linked, linkedErr := s.repos.Books.GetAuthors(s.ctx, int(book))
+	removeErr := s.repos.Books.RemoveAuthor(s.ctx, int(book), int(kernighan), 2)
+	unlinkedErr := s.repos.Books.RemoveAuthor(s.ctx, int(book), int(kernighan), 0)
+	unknownErr := s.repos.Books.AddAuthor(s.ctx, int(book), 42, 0)
+	_, missingErr := s.repos.Books.GetAuthors(s.ctx, 42)
+
+	// assert
+	s.Require().NoError(addErr)
+	s.Require().NoError(againErr)
+	s.Assert().Equal(ErrVersionMismatch, staleErr)
+	s.Require().NoError(linkedErr)
+	s.Assert().Equal([]string{"Brian Kernighan", "Dennis Ritchie"}, authorNames(linked))
+	s.Require().NoError(removeErr)
+	s.Assert().Equal(gorm.ErrRecordNotFound, unlinkedErr)
+	s.Assert().Equal(ErrAuthorNotFound, unknownErr)
+	s.Assert().Equal(gorm.ErrRecordNotFound, missingErr)
+
+	b, err := s.repos.Books.GetBook(s.ctx, int(book), ExpandAuthors)
+	s.Require().NoError(err)
+	s.Assert().Equal(uint(3), b.Version)
+	s.Require().Len(b.Authors, 1)
+	s.Assert().Equal(ritchie, b.Authors[0].ID)
+	s.Assert().Equal([]string{"The C Programming Language"}, bookNames(s.books(GetAllRequest{Author: int(ritchie)})))
+
+	history, err := s.repos.Books.GetHistory(s.ctx, int(book))
+	s.Require().NoError(err)
+	s.Require().Len(history, 3)
+	s.Assert().Equal([]string{ActionCreate, ActionLink, ActionUnlink}, []string{history[0].Action, history[1].Action, history[2].Action})
+}
+
+func (s *RepositoryContractSuite) TestMergedAuthorShouldHandOverItsBooks() {
+	// arrange
+	kernighan := s.author("Brian Kernighan")
+	duplicate := s.author("B. W. Kernighan")
+	ritchie := s.author("Dennis Ritchie")
+	s.book("The C Programming Language", "2", 1988, duplicate, kernighan, ritchie)
+	unix := s.book("The Unix Programming Environment", "1", 1984, duplicate)
+
+	// act
+	err := s.repos.Authors.Merge(s.ctx, int(duplicate), int(kernighan))
+
+	// assert
+	s.Require().NoError(err)
+	book, err := s.repos.Books.GetBook(s.ctx, int(unix), ExpandNone)
+	s.Require().NoError(err)
+	s.Assert().Equal(uint(2), book.Version)
+	s.Assert().Equal([]string{"Brian Kernighan", "Dennis Ritchie"}, authorNames(s.authors(GetAuthorsRequest{})))
+	s.Assert().Equal([]string{"The C Programming Language", "The Unix Programming Environment"},
+		bookNames(s.books(GetAllRequest{Author: int(kernighan)})))
+	s.Assert().Empty(s.books(GetAllRequest{Author: int(duplicate)}))
+
+	history, err := s.repos.Authors.GetHistory(s.ctx, int(duplicate))
+	s.Require().NoError(err)
+	s.Require().Len(history, 2)
+	s.Assert().Equal(ActionDelete, history[1].Action)
+}
+
+func (s *RepositoryContractSuite) TestAuthorShouldNotBeMergedIntoItselfOrTheUnknown() {
+	// arrange
+	kernighan := s.author("Brian Kernighan")
+
+	// act, assert
+	s.Assert().Equal(ErrMergeIntoItself, s.repos.Authors.Merge(s.ctx, int(kernighan), int(kernighan)))
+	s.Assert().Equal(gorm.ErrRecordNotFound, s.repos.Authors.Merge(s.ctx, int(kernighan), 42))
+	s.Assert().Equal(gorm.ErrRecordNotFound, s.repos.Authors.Merge(s.ctx, 42, int(kernighan)))
+	s.Assert().Len(s.authors(GetAuthorsRequest{}), 1)
+}
+
+func (s *RepositoryContractSuite) TestStatsShouldSummariseTheCatalogue() {
+	// arrange
+	kernighan := s.author("Brian Kernighan")
+	ritchie := s.author("Dennis Ritchie")
+	pike := s.author("Rob Pike")
+	s.author("Ken Thompson")
+	s.book("The C Programming Language", "2", 1988, kernighan, ritchie)
+	s.book("The Unix Programming Environment", "1", 1984, kernighan, pike)
+	s.book("The Go Programming Language", "1", 2015, kernighan)
+	s.book("Untitled", "1", 0)
+	deleted := s.book("The Practice of Programming", "1", 1999, kernighan, pike)
+	s.Require().NoError(s.repos.Books.Delete(s.ctx, int(deleted), 0))
+
+	// act
+	stats, err := s.repos.Stats.GetStats(s.ctx, 2)
+
+	// assert
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(4), stats.Books)
+	s.Assert().Equal(int64(4), stats.Authors)
+	s.Assert().Equal([]DecadeStats{{Decade: 1980, Books: 2}, {Decade: 2010, Books: 1}}, stats.Decades)
+	s.Assert().Equal([]AuthorBookCount{
+		{ID: kernighan, Name: "Brian Kernighan", Books: 3},
+		{ID: ritchie, Name: "Dennis Ritchie", Books: 1},
+	}, stats.TopAuthors)
+	s.Require().Len(stats.AuthorsWithoutBooks, 1)
+	s.Assert().Equal("Ken Thompson", stats.AuthorsWithoutBooks[0].Name)
+	s.Assert().Equal(int64(1), stats.AuthorsWithoutBooksCount)
+}
+
+func (s *RepositoryContractSuite) TestStatsShouldOnlySampleAuthorsWithoutBooks() {
+	// arrange
+	for _, name := range []string{"Ken Thompson", "Alan Donovan", "Rob Pike"} {
+		s.author(name)
+	}
+
+	// act
+	stats, err := s.repos.Stats.GetStats(s.ctx, 2)
+
+	// assert
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(3), stats.AuthorsWithoutBooksCount)
+	s.Require().Len(stats.AuthorsWithoutBooks, 2)
+	s.Assert().Equal("Alan Donovan", stats.AuthorsWithoutBooks[0].Name)
+	s.Assert().Equal("Ken Thompson", stats.AuthorsWithoutBooks[1].Name)
+}
+
+func (s *RepositoryContractSuite) TestAuthorStatsShouldSummariseTheirBooks() {
+	// arrange
+	kernighan := s.author("Brian Kernighan")
+	ritchie := s.author("Dennis Ritchie")
+	pike := s.author("Rob Pike")
+	thompson := s.author("Ken Thompson")
+	s.book("The C Programming Language", "2", 1988, kernighan, ritchie)
+	s.book("The Unix Programming Environment", "1", 1984, kernighan, pike)
+	s.book("The Go Programming Language", "1", 2015, kernighan, ritchie)
+	deleted := s.book("Unix: A History and a Memoir", "1", 2019, kernighan, thompson)
+	s.Require().NoError(s.repos.Books.Delete(s.ctx, int(deleted), 0))
+
+	// act
+	stats, err := s.repos.Stats.GetAuthorStats(s.ctx, int(kernighan))
+	lonely, lonelyErr := s.repos.Stats.GetAuthorStats(s.ctx, int(thompson))
+	_, missingErr := s.repos.Stats.GetAuthorStats(s.ctx, 42)
+
+	// assert
+	s.Require().NoError(err)
+	first, last := 1984, 2015
+	s.Assert().Equal(AuthorStats{ID: kernighan, Name: "Brian Kernighan", Books: 3, FirstPublicationYear: &first, LastPublicationYear: &last, CoAuthors: 2}, stats)
+
+	s.Require().NoError(lonelyErr)
+	s.Assert().Equal(AuthorStats{ID: thompson, Name: "Ken Thompson"}, lonely)
+
+	s.Assert().Equal(gorm.ErrRecordNotFound, missingErr)
+}
+
+func (s *RepositoryContractSuite) TestTenantsShouldBeIsolated() {
+	// arrange
+	mine := s.ctx
+	theirs := uctx.WithTenant(s.ctx, "other")
+
+	author := s.author("Rob Pike")
+	book := s.book("The Go Programming Language", "1", 2015, author)
+	deleted := s.book("Deleted", "1", 2015)
+	s.Require().NoError(s.repos.Books.Delete(mine, int(deleted), 0))
+
+	theirAuthor, err := s.repos.Authors.Create(theirs, domain.Author{Name: "Rob Pike"})
+	s.Require().NoError(err)
+	linked := &domain.Author{}
+	linked.ID = theirAuthor
+	theirBook, err := s.repos.Books.Create(theirs, domain.Book{Name: "Their Book", Authors: []*domain.Author{linked}})
+	s.Require().NoError(err)
+
+	// act
+	theirBooks, err := s.repos.Books.GetAll(theirs, GetAllRequest{Query: "go"})
+	s.Require().NoError(err)
+	theirExpanded, theirErr := s.repos.Books.GetBook(theirs, int(theirBook), ExpandAuthorsBooks)
+	theirAuthors, err := s.repos.Authors.GetAll(theirs, GetAuthorsRequest{})
+	s.Require().NoError(err)
+	_, getErr := s.repos.Books.GetBook(theirs, int(book), ExpandAuthors)
+	updateErr := s.repos.Books.Update(theirs, int(book), domain.Book{Name: "Hijacked"})
+	deleteErr := s.repos.Books.Delete(theirs, int(book), 0)
+	restoreErr := s.repos.Books.Restore(theirs, int(deleted))
+	purged, purgeErr := s.repos.Books.Purge(theirs, time.Now().Add(time.Minute))
+	theirTrash, err := s.repos.Books.GetTrash(theirs, PageRequest{})
+	s.Require().NoError(err)
+	theirHistory, err := s.repos.Books.GetHistory(theirs, int(book))
+	s.Require().NoError(err)
+	theirStats, statsErr := s.repos.Stats.GetStats(theirs, 10)
+	_, authorStatsErr := s.repos.Stats.GetAuthorStats(theirs, int(author))
+
+	stolen := &domain.Author{}
+	stolen.ID = author
+	_, linkErr := s.repos.Books.Create(theirs, domain.Book{Name: "Stolen", Authors: []*domain.Author{stolen}})
+
+	// assert
+	s.Assert().Empty(theirBooks)
+	s.Require().NoError(theirErr)
+	s.Require().Len(theirExpanded.Authors, 1)
+	s.Assert().Equal(theirAuthor, theirExpanded.Authors[0].ID)
+	s.Assert().Len(theirExpanded.Authors[0].Books, 1)
+	s.Assert().Equal([]string{"Rob Pike"}, authorNames(theirAuthors))
+	s.Assert().Equal(theirAuthor, theirAuthors[0].ID)
+	s.Assert().Error(getErr)
+	s.Assert().Error(updateErr)
+	s.Assert().Equal(gorm.ErrRecordNotFound, deleteErr)
+	s.Assert().Error(restoreErr)
+	s.Assert().NoError(purgeErr)
+	s.Assert().Zero(purged)
+	s.Assert().Empty(theirTrash)
+	s.Assert().Empty(theirHistory)
+	s.Require().NoError(statsErr)
+	s.Assert().Equal(int64(1), theirStats.Books)
+	s.Assert().Equal(int64(1), theirStats.Authors)
+	s.Assert().Equal([]AuthorBookCount{{ID: theirAuthor, Name: "Rob Pike", Books: 1}}, theirStats.TopAuthors)
+	s.Assert().Equal(gorm.ErrRecordNotFound, authorStatsErr)
+	s.Assert().Equal(ErrAuthorNotFound, linkErr)
+
+	mineBook, err := s.repos.Books.GetBook(mine, int(book), ExpandAuthors)
+	s.Require().NoError(err)
+	s.Assert().Equal("The Go Programming Language", mineBook.Name)
+	s.Assert().Equal(uint(1), mineBook.Version)
+	s.Require().Len(mineBook.Authors, 1)
+	s.Assert().Equal(author, mineBook.Authors[0].ID)
+	mineTrash, err := s.repos.Books.GetTrash(mine, PageRequest{})
+	s.Require().NoError(err)
+	s.Assert().Len(mineTrash, 1)
+	mineHistory, err := s.repos.Books.GetHistory(mine, int(book))
+	s.Require().NoError(err)
+	s.Assert().Len(mineHistory, 1)
+}
+
+func TestIntegrationSqlRepositoriesSuite(t *testing.T) {
+	suite.Run(t, &RepositoryContractSuite{
+		open: func(name string) (Repos, func() error) {
+			manager := NewDbManager(fmt.Sprintf("file:%s?mode=memory&cache=shared", name))
+			if err := manager.InitDb(); err != nil {
+				t.Fatal(err)
+			}
+
+			Migrate(manager.GetDB())
+			return NewRepos(manager), manager.Close
+		},
+	})
+}
+
+func TestMemoryRepositoriesSuite(t *testing.T) {
+	suite.Run(t, &RepositoryContractSuite{
+		open: func(name string) (Repos, func() error) {
+			return NewMemoryRepos(NewMemoryStore()), func() error { return nil }
+		},
+	})
+}
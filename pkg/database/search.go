@@ -0,0 +1,115 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// searchTables lists the tables that have a name column indexed by a FTS5
+// external content table named <table>_fts.
+var searchTables = []string{"authors", "books"}
+
+// fullText is set once MigrateSearch created the FTS5 tables. Whether SQLite
+// has FTS5 depends on how the binary was built, so it holds for every database
+// of the process, and searches need not look for the tables each time.
+var fullText int32
+
+// MigrateSearch creates the FTS5 tables and the triggers that keep them in
+// sync. SQLite must be built with the sqlite_fts5 tag, otherwise searches fall
+// back to LIKE.
+func MigrateSearch(db *gorm.DB) error {
+	for _, table := range searchTables {
+		fts := table + "_fts"
+		exists := db.Migrator().HasTable(fts)
+
+		err := db.Exec(fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(name, content='%s', content_rowid='id')", fts, table)).Error
+		if err != nil && strings.Contains(err.Error(), "no such module") {
+			log.Printf("full-text search disabled: %v\n", err)
+			atomic.StoreInt32(&fullText, 0)
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		triggers := []string{
+			fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_ai AFTER INSERT ON %[2]s BEGIN
+				INSERT INTO %[1]s(rowid, name) VALUES (new.id, new.name);
+			END`, fts, table),
+			fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_ad AFTER DELETE ON %[2]s BEGIN
+				INSERT INTO %[1]s(%[1]s, rowid, name) VALUES ('delete', old.id, old.name);
+			END`, fts, table),
+			fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_au AFTER UPDATE OF name ON %[2]s BEGIN
+				INSERT INTO %[1]s(%[1]s, rowid, name) VALUES ('delete', old.id, old.name);
+				INSERT INTO %[1]s(rowid, name) VALUES (new.id, new.name);
+			END`, fts, table),
+		}
+
+		for _, trigger := range triggers {
+			if err := db.Exec(trigger).Error; err != nil {
+				return err
+			}
+		}
+
+		if !exists {
+			err = db.Exec(fmt.Sprintf("INSERT INTO %[1]s(%[1]s) VALUES ('rebuild')", fts)).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	atomic.StoreInt32(&fullText, 1)
+	return nil
+}
+
+// likeEscaper makes the wildcards of a term match themselves in LIKE.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// search filters db by the full-text query q, ranked by relevance if rank is
+// set. Every term is matched as a prefix, so "luc ram" finds "Luciano
+// Ramalho". Without FTS5 every term is matched with LIKE and no ranking is
+// applied.
+func search(db *gorm.DB, table string, q string, rank bool) *gorm.DB {
+	db, joined := match(db, table, q)
+	if !joined {
+		return db
+	}
+
+	db = db.Select(table + ".*")
+	if rank {
+		db = db.Order(table + "_fts.rank")
+	}
+
+	return db
+}
+
+// match filters db by the full-text query q like search, without ranking. It
+// tells whether the FTS5 table was joined.
+func match(db *gorm.DB, table string, q string) (*gorm.DB, bool) {
+	terms := strings.Fields(q)
+	if len(terms) == 0 {
+		return db, false
+	}
+
+	fts := table + "_fts"
+	if atomic.LoadInt32(&fullText) == 0 {
+		for _, term := range terms {
+			db = db.Where(table+".name LIKE ? ESCAPE '\\'", "%"+likeEscaper.Replace(term)+"%")
+		}
+		return db, false
+	}
+
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+
+	return db.
+		Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.rowid = %[2]s.id", fts, table)).
+		Where(fts+" MATCH ?", strings.Join(terms, " ")), true
+}
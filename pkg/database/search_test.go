@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/stretchr/testify/suite"
+)
+
+type SearchIntegrationSuite struct {
+	suite.Suite
+
+	ctx     context.Context
+	manager DBManager
+	authors AuthorsRepository
+	books   BooksRepository
+}
+
+func (s *SearchIntegrationSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.manager = NewDbManager(fmt.Sprintf("file:%s?mode=memory&cache=shared", s.T().Name()))
+	s.Require().NoError(s.manager.InitDb())
+	Migrate(s.manager.GetDB())
+
+	s.authors = NewAuthorsRepository(s.manager)
+	s.books = NewBooksRepository(s.manager)
+
+	for _, name := range []string{"Luciano Ramalho", "Osvaldo Santana Neto", "David Beazley", "Luciana Lima"} {
+		s.manager.GetDB().Create(&domain.Author{Name: name})
+	}
+
+	for _, name := range []string{"Fluent Python", "Python Cookbook", "Learning Go"} {
+		s.manager.GetDB().Create(&domain.Book{Name: name})
+	}
+}
+
+func (s *SearchIntegrationSuite) TearDownTest() {
+	s.Require().NoError(s.manager.Close())
+}
+
+func (s *SearchIntegrationSuite) names(authors []domain.Author) []string {
+	names := []string{}
+	for _, a := range authors {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+func (s *SearchIntegrationSuite) TestAuthorsShouldMatchByPrefix() {
+	// act
+	authors, err := s.authors.GetAll(s.ctx, GetAuthorsRequest{Query: "luc ram", Limit: 10})
+	s.Require().NoError(err)
+
+	// assert
+	s.Assert().Equal([]string{"Luciano Ramalho"}, s.names(authors))
+}
+
+func (s *SearchIntegrationSuite) TestMatchesShouldBeCounted() {
+	// act
+	authors, err := s.authors.Count(s.ctx, GetAuthorsRequest{Query: "luc", Limit: 1})
+	s.Require().NoError(err)
+	books, err := s.books.Count(s.ctx, GetAllRequest{Query: "python"})
+	s.Require().NoError(err)
+
+	// assert
+	s.Assert().Equal(int64(2), authors)
+	s.Assert().Equal(int64(2), books)
+}
+
+func (s *SearchIntegrationSuite) TestAuthorsShouldReturnAllWithoutQuery() {
+	// act
+	authors, err := s.authors.GetAll(s.ctx, GetAuthorsRequest{Limit: 10})
+	s.Require().NoError(err)
+
+	// assert
+	s.Assert().Len(authors, 4)
+}
+
+func (s *SearchIntegrationSuite) TestAuthorsShouldFollowUpdatesAndDeletes() {
+	// arrange
+	db := s.manager.GetDB()
+	db.Model(&domain.Author{}).Where("name = ?", "David Beazley").Update("name", "Dave Beazley")
+	db.Unscoped().Where("name = ?", "Luciana Lima").Delete(&domain.Author{})
+
+	// act
+	dave, err := s.authors.GetAll(s.ctx, GetAuthorsRequest{Query: "dave", Limit: 10})
+	s.Require().NoError(err)
+	luciana, err := s.authors.GetAll(s.ctx, GetAuthorsRequest{Query: "luciana", Limit: 10})
+	s.Require().NoError(err)
+
+	// assert
+	s.Assert().Equal([]string{"Dave Beazley"}, s.names(dave))
+	s.Assert().Empty(luciana)
+}
+
+func (s *SearchIntegrationSuite) TestBooksShouldMatchByPrefix() {
+	// act
+	books, err := s.books.GetAll(s.ctx, GetAllRequest{Query: "pyth", Limit: 10})
+	s.Require().NoError(err)
+
+	// assert
+	s.Assert().Len(books, 2)
+}
+
+func (s *SearchIntegrationSuite) TestLikeWildcardsShouldMatchThemselves() {
+	if s.manager.GetDB().Migrator().HasTable("books_fts") {
+		s.T().Skip("sqlite built with the sqlite_fts5 tag does not fall back to LIKE")
+	}
+
+	// arrange
+	s.manager.GetDB().Create(&domain.Book{Name: "100% Go"})
+
+	// act
+	percent, err := s.books.GetAll(s.ctx, GetAllRequest{Query: "%", Limit: 10})
+	s.Require().NoError(err)
+	underscore, err := s.books.GetAll(s.ctx, GetAllRequest{Query: "_", Limit: 10})
+	s.Require().NoError(err)
+
+	// assert
+	s.Require().Len(percent, 1)
+	s.Assert().Equal("100% Go", percent[0].Name)
+	s.Assert().Empty(underscore)
+}
+
+func (s *SearchIntegrationSuite) TestBooksShouldBeRankedByRelevance() {
+	if !s.manager.GetDB().Migrator().HasTable("books_fts") {
+		s.T().Skip("sqlite built without the sqlite_fts5 tag")
+	}
+
+	// arrange
+	s.manager.GetDB().Create(&domain.Book{Name: "Go Go Go"})
+
+	// act
+	books, err := s.books.GetAll(s.ctx, GetAllRequest{Query: "go", Limit: 10})
+	s.Require().NoError(err)
+
+	// assert
+	s.Require().Len(books, 2)
+	s.Assert().Equal("Go Go Go", books[0].Name)
+}
+
+func TestIntegrationSearchSuite(t *testing.T) {
+	suite.Run(t, new(SearchIntegrationSuite))
+}
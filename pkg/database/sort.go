@@ -0,0 +1,310 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"gorm.io/gorm"
+)
+
+// The fields listings may be sorted by. Each leads an index after the tenant,
+// idx_books_sort_<field> and idx_authors_sort_name in the models, which SQLite
+// ends with the id that breaks ties, so a sort by one field is read from its
+// index. Books also have idx_books_sort_year for -publication_year,name; other
+// sorts by several fields only use an index for their first one.
+var (
+	BookSortFields   = []string{"name", "edition", "publication_year"}
+	AuthorSortFields = []string{"name"}
+)
+
+var ErrInvalidSort = errors.New("sort is invalid")
+
+// SortField orders a listing by a column, in descending order if Desc is set.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Sort orders a listing by its fields and then by id, so that rows with the
+// same fields keep a stable order across pages. The id goes in the direction
+// of the last field, so that one index serves a sort and its reverse. An empty
+// Sort orders by name.
+type Sort []SortField
+
+var DefaultSort = Sort{{Field: "name"}}
+
+// ParseSort reads a sort such as "-publication_year,name": fields separated by
+// commas, each descending if prefixed with "-". Only the given fields may be
+// used, and each only once.
+func ParseSort(s string, fields []string) (Sort, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	var sort Sort
+	used := map[string]bool{}
+
+	for _, part := range strings.Split(s, ",") {
+		field := SortField{Field: strings.TrimSpace(part)}
+		if strings.HasPrefix(field.Field, "-") {
+			field.Field, field.Desc = field.Field[1:], true
+		}
+
+		if !contains(fields, field.Field) || used[field.Field] {
+			return nil, fmt.Errorf("%w: %q is not one of %s, or is given twice", ErrInvalidSort, field.Field, strings.Join(fields, ", "))
+		}
+
+		used[field.Field] = true
+		sort = append(sort, field)
+	}
+
+	return sort, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s Sort) String() string {
+	parts := make([]string, len(s))
+	for i, f := range s {
+		parts[i] = f.Field
+		if f.Desc {
+			parts[i] = "-" + f.Field
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func (s Sort) orDefault() Sort {
+	if len(s) == 0 {
+		return DefaultSort
+	}
+
+	return s
+}
+
+// Fits tells whether the cursor points into a listing ordered by s. Cursors
+// that do not name their sort only need as many keys.
+func (c Cursor) Fits(s Sort) bool {
+	s = s.orDefault()
+	return len(c.Keys) == len(s) && (len(c.Sort) == 0 || c.Sort == s.String())
+}
+
+// keyset orders db by the sort and then by id and, if after is set, skips
+// every row up to and including the one it points to. A cursor that does not
+// fit the sort skips every row.
+func keyset(db *gorm.DB, table string, s Sort, after *Cursor) *gorm.DB {
+	s = s.orDefault()
+
+	if after != nil {
+		if !after.Fits(s) {
+			return db.Where("1 = 0")
+		}
+
+		query, args := s.after(table, after)
+		db = db.Where(query, args...)
+	}
+
+	order := make([]string, 0, len(s)+1)
+	for _, f := range s {
+		order = append(order, table+"."+f.Field+direction(f.Desc))
+	}
+
+	return db.Order(strings.Join(append(order, table+".id"+direction(s.desc())), ", "))
+}
+
+// desc tells whether ties are broken by descending id.
+func (s Sort) desc() bool {
+	return s[len(s)-1].Desc
+}
+
+// after builds the condition of the rows that follow the cursor. The fields
+// may go in different directions, so rather than comparing tuples it expands
+// to "a > ? OR (a = ? AND b > ?) OR ...", bounded by the first field so that
+// its index can be used.
+func (s Sort) after(table string, c *Cursor) (string, []interface{}) {
+	columns := make([]string, 0, len(s)+1)
+	keys := make([]interface{}, 0, len(s)+1)
+	ops := make([]string, 0, len(s)+1)
+
+	for i, f := range s {
+		columns = append(columns, table+"."+f.Field)
+		keys = append(keys, key(c.Keys[i]))
+		ops = append(ops, comparison(f.Desc))
+	}
+
+	columns = append(columns, table+".id")
+	keys = append(keys, c.ID)
+	ops = append(ops, comparison(s.desc()))
+
+	var terms []string
+	args := []interface{}{keys[0]}
+
+	for i := range columns {
+		var conds []string
+		for j := 0; j < i; j++ {
+			conds = append(conds, columns[j]+" = ?")
+			args = append(args, keys[j])
+		}
+
+		conds = append(conds, columns[i]+" "+ops[i]+" ?")
+		args = append(args, keys[i])
+		terms = append(terms, "("+strings.Join(conds, " AND ")+")")
+	}
+
+	return fmt.Sprintf("%s %s= ? AND (%s)", columns[0], ops[0], strings.Join(terms, " OR ")), args
+}
+
+func direction(desc bool) string {
+	if desc {
+		return " DESC"
+	}
+
+	return ""
+}
+
+func comparison(desc bool) string {
+	if desc {
+		return "<"
+	}
+
+	return ">"
+}
+
+// key turns a key of a cursor, decoded from json, back into a value that
+// compares like the column: numbers are decoded as float64.
+func key(value interface{}) interface{} {
+	if n, ok := value.(float64); ok && n == float64(int64(n)) {
+		return int64(n)
+	}
+
+	return value
+}
+
+func (s Sort) keys(value func(field string) interface{}) []interface{} {
+	keys := make([]interface{}, len(s))
+	for i, f := range s {
+		keys[i] = value(f.Field)
+	}
+
+	return keys
+}
+
+func bookKeys(b domain.Book, s Sort) []interface{} {
+	return s.orDefault().keys(func(field string) interface{} { return bookKey(b, field) })
+}
+
+func authorKeys(a domain.Author, s Sort) []interface{} {
+	return s.orDefault().keys(func(field string) interface{} { return authorKey(a, field) })
+}
+
+func bookKey(b domain.Book, field string) interface{} {
+	switch field {
+	case "edition":
+		return b.Edition
+	case "publication_year":
+		return b.PublicationYear
+	default:
+		return b.Name
+	}
+}
+
+func authorKey(a domain.Author, field string) interface{} {
+	return a.Name
+}
+
+// less orders the rows of a memory listing like keyset: by the sort, with the
+// keys of row i given by keys, and then by id.
+func (s Sort) less(keys func(i int) []interface{}, id func(i int) uint) func(i, j int) bool {
+	s = s.orDefault()
+
+	return func(i, j int) bool {
+		if c := s.compare(keys(i), keys(j)); c != 0 {
+			return c < 0
+		}
+		if s.desc() {
+			return id(i) > id(j)
+		}
+		return id(i) < id(j)
+	}
+}
+
+// follows tells whether the row with keys and id comes after the cursor.
+func (s Sort) follows(keys []interface{}, id uint, c *Cursor) bool {
+	if c == nil {
+		return true
+	}
+
+	s = s.orDefault()
+	if !c.Fits(s) {
+		return false
+	}
+
+	if cmp := s.compare(keys, c.Keys); cmp != 0 {
+		return cmp > 0
+	}
+	if s.desc() {
+		return id < c.ID
+	}
+	return id > c.ID
+}
+
+// compare compares two rows by their keys, in the direction of each field.
+func (s Sort) compare(a []interface{}, b []interface{}) int {
+	for i, f := range s {
+		c := compareKey(a[i], b[i])
+		if f.Desc {
+			c = -c
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+func compareKey(a interface{}, b interface{}) int {
+	if x, ok := a.(string); ok {
+		y, _ := b.(string)
+		return strings.Compare(x, y)
+	}
+
+	x, y := number(a), number(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func number(value interface{}) float64 {
+	switch n := value.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	case json.Number:
+		f, _ := n.Float64()
+		return f
+	default:
+		return 0
+	}
+}
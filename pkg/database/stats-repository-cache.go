@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jedielson/bookstore/pkg/uctx"
+)
+
+type cachedStatsRepository struct {
+	next  StatsRepository
+	cache *Cache
+}
+
+// NewCachedStatsRepository caches the statistics computed by next in cache,
+// which the writes of the other cached repositories clear.
+func NewCachedStatsRepository(next StatsRepository, cache *Cache) StatsRepository {
+	return &cachedStatsRepository{
+		next:  next,
+		cache: cache,
+	}
+}
+
+func (s *cachedStatsRepository) GetStats(ctx context.Context, top int) (CatalogueStats, error) {
+	if uctx.ReadYourWrites(ctx) {
+		return s.next.GetStats(ctx, top)
+	}
+
+	stats, err := s.cache.Get(ctx, tenantKey(ctx, fmt.Sprintf("stats:%d", top)), func(ctx context.Context) (interface{}, error) {
+		return s.next.GetStats(ctx, top)
+	})
+
+	if err != nil {
+		return CatalogueStats{}, err
+	}
+
+	result, _ := stats.(CatalogueStats)
+	return result, nil
+}
+
+func (s *cachedStatsRepository) GetAuthorStats(ctx context.Context, id int) (AuthorStats, error) {
+	if uctx.ReadYourWrites(ctx) {
+		return s.next.GetAuthorStats(ctx, id)
+	}
+
+	stats, err := s.cache.Get(ctx, tenantKey(ctx, fmt.Sprintf("author-stats:%d", id)), func(ctx context.Context) (interface{}, error) {
+		return s.next.GetAuthorStats(ctx, id)
+	})
+
+	if err != nil {
+		return AuthorStats{}, err
+	}
+
+	result, _ := stats.(AuthorStats)
+	return result, nil
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"gorm.io/gorm"
+)
+
+type statsMemoryRepository struct {
+	store *MemoryStore
+}
+
+// NewStatsMemoryRepository returns a StatsRepository over store.
+func NewStatsMemoryRepository(store *MemoryStore) StatsRepository {
+	return &statsMemoryRepository{
+		store: store,
+	}
+}
+
+// liveBooks returns the ids of the books of an author that are not deleted.
+func (s *statsMemoryRepository) liveBooks(authorID uint) []uint {
+	ids := []uint{}
+	for _, bookID := range s.store.authorBooks(authorID) {
+		if b, ok := s.store.books[bookID]; ok && !b.DeletedAt.Valid {
+			ids = append(ids, bookID)
+		}
+	}
+
+	return ids
+}
+
+func (s *statsMemoryRepository) GetStats(ctx context.Context, top int) (CatalogueStats, error) {
+	s.store.mu.RLock()
+	defer s.store.mu.RUnlock()
+
+	tenant := uctx.Tenant(ctx)
+	stats := CatalogueStats{Decades: []DecadeStats{}, TopAuthors: []AuthorBookCount{}, AuthorsWithoutBooks: []AuthorBookCount{}}
+
+	decades := map[int]int64{}
+	for _, b := range s.store.books {
+		if b.TenantID != tenant || b.DeletedAt.Valid {
+			continue
+		}
+
+		stats.Books++
+		if b.PublicationYear > 0 {
+			decades[b.PublicationYear/10*10]++
+		}
+	}
+
+	for decade, books := range decades {
+		stats.Decades = append(stats.Decades, DecadeStats{Decade: decade, Books: books})
+	}
+
+	sort.Slice(stats.Decades, func(i, j int) bool { return stats.Decades[i].Decade < stats.Decades[j].Decade })
+
+	for _, a := range s.store.authors {
+		if a.TenantID != tenant || a.DeletedAt.Valid {
+			continue
+		}
+
+		stats.Authors++
+		count := AuthorBookCount{ID: a.ID, Name: a.Name, Books: int64(len(s.liveBooks(a.ID)))}
+		if count.Books == 0 {
+			stats.AuthorsWithoutBooks = append(stats.AuthorsWithoutBooks, count)
+		} else {
+			stats.TopAuthors = append(stats.TopAuthors, count)
+		}
+	}
+
+	sort.Slice(stats.AuthorsWithoutBooks, byNameAndID(
+		func(i int) string { return stats.AuthorsWithoutBooks[i].Name },
+		func(i int) uint { return stats.AuthorsWithoutBooks[i].ID }))
+
+	byName := byNameAndID(
+		func(i int) string { return stats.TopAuthors[i].Name },
+		func(i int) uint { return stats.TopAuthors[i].ID })
+
+	sort.Slice(stats.TopAuthors, func(i, j int) bool {
+		if stats.TopAuthors[i].Books != stats.TopAuthors[j].Books {
+			return stats.TopAuthors[i].Books > stats.TopAuthors[j].Books
+		}
+		return byName(i, j)
+	})
+
+	if top > 0 && len(stats.TopAuthors) > top {
+		stats.TopAuthors = stats.TopAuthors[:top]
+	}
+
+	stats.AuthorsWithoutBooksCount = int64(len(stats.AuthorsWithoutBooks))
+	if top > 0 && len(stats.AuthorsWithoutBooks) > top {
+		stats.AuthorsWithoutBooks = stats.AuthorsWithoutBooks[:top]
+	}
+
+	return stats, nil
+}
+
+func (s *statsMemoryRepository) GetAuthorStats(ctx context.Context, id int) (AuthorStats, error) {
+	s.store.mu.RLock()
+	defer s.store.mu.RUnlock()
+
+	author, ok := s.store.authors[uint(id)]
+	if !ok || author.TenantID != uctx.Tenant(ctx) || author.DeletedAt.Valid {
+		return AuthorStats{}, gorm.ErrRecordNotFound
+	}
+
+	stats := AuthorStats{ID: author.ID, Name: author.Name}
+	coAuthors := map[uint]bool{}
+
+	for _, bookID := range s.liveBooks(author.ID) {
+		stats.Books++
+
+		year := s.store.books[bookID].PublicationYear
+		if year > 0 && (stats.FirstPublicationYear == nil || year < *stats.FirstPublicationYear) {
+			stats.FirstPublicationYear = &year
+		}
+
+		if year > 0 && (stats.LastPublicationYear == nil || year > *stats.LastPublicationYear) {
+			stats.LastPublicationYear = &year
+		}
+
+		for _, authorID := range s.store.bookAuthors(bookID) {
+			if a, ok := s.store.authors[authorID]; ok && authorID != author.ID && !a.DeletedAt.Valid {
+				coAuthors[authorID] = true
+			}
+		}
+	}
+
+	stats.CoAuthors = int64(len(coAuthors))
+	return stats, nil
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type StatsRepositoryMock struct {
+	mock.Mock
+}
+
+func NewStatsRepositoryMock() *StatsRepositoryMock {
+	return &StatsRepositoryMock{}
+}
+
+func (m *StatsRepositoryMock) GetStats(ctx context.Context, top int) (CatalogueStats, error) {
+	args := m.Called(ctx, top)
+	stats, ok := args.Get(0).(CatalogueStats)
+
+	if !ok {
+		stats = CatalogueStats{}
+	}
+
+	return stats, args.Error(1)
+}
+
+func (m *StatsRepositoryMock) GetAuthorStats(ctx context.Context, id int) (AuthorStats, error) {
+	args := m.Called(ctx, id)
+	stats, ok := args.Get(0).(AuthorStats)
+
+	if !ok {
+		stats = AuthorStats{}
+	}
+
+	return stats, args.Error(1)
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"gorm.io/gorm"
+)
+
+const DefaultTopAuthors = 10
+
+// CatalogueStats summarises the catalogue. AuthorsWithoutBooks only holds the
+// first of them by name, as many as TopAuthors at most, while
+// AuthorsWithoutBooksCount counts them all.
+type CatalogueStats struct {
+	Books                    int64
+	Authors                  int64
+	Decades                  []DecadeStats
+	TopAuthors               []AuthorBookCount
+	AuthorsWithoutBooks      []AuthorBookCount
+	AuthorsWithoutBooksCount int64
+}
+
+// DecadeStats counts the books published in the decade starting with Decade.
+// Books without a publication year belong to no decade.
+type DecadeStats struct {
+	Decade int
+	Books  int64
+}
+
+type AuthorBookCount struct {
+	ID    uint
+	Name  string
+	Books int64
+}
+
+// AuthorStats describes the books of an author. The publication years are nil
+// when none of them has one.
+type AuthorStats struct {
+	ID                   uint
+	Name                 string
+	Books                int64
+	FirstPublicationYear *int
+	LastPublicationYear  *int
+	CoAuthors            int64
+}
+
+// StatsRepository computes statistics over the books and authors that are not
+// deleted.
+type StatsRepository interface {
+	// GetStats returns the statistics of the catalogue, with the top
+	// authors by number of books and as many authors without books.
+	GetStats(ctx context.Context, top int) (CatalogueStats, error)
+	GetAuthorStats(ctx context.Context, id int) (AuthorStats, error)
+}
+
+type statsRepository struct {
+	manager DBManager
+}
+
+func NewStatsRepository(m DBManager) StatsRepository {
+	return &statsRepository{
+		manager: m,
+	}
+}
+
+func (s *statsRepository) read(ctx context.Context) *gorm.DB {
+	return s.manager.GetReadDB(ctx).WithContext(ctx)
+}
+
+// liveBooks joins the books of an author that are not deleted.
+const liveBooks = "JOIN books ON books.id = author_books.book_id AND books.deleted_at IS NULL"
+
+func (s *statsRepository) GetStats(ctx context.Context, top int) (CatalogueStats, error) {
+	stats := CatalogueStats{Decades: []DecadeStats{}, TopAuthors: []AuthorBookCount{}, AuthorsWithoutBooks: []AuthorBookCount{}}
+
+	err := s.read(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Book{}).Count(&stats.Books).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&domain.Author{}).Count(&stats.Authors).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&domain.Book{}).
+			Select("publication_year / 10 * 10 AS decade, COUNT(*) AS books").
+			Where("publication_year > 0").
+			Group("decade").
+			Order("decade").
+			Scan(&stats.Decades).Error
+
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&domain.Author{}).
+			Select("authors.id, authors.name, COUNT(*) AS books").
+			Joins("JOIN author_books ON author_books.author_id = authors.id").
+			Joins(liveBooks).
+			Group("authors.id, authors.name").
+			Order("COUNT(*) DESC, authors.name, authors.id").
+			Limit(top).
+			Scan(&stats.TopAuthors).Error
+
+		if err != nil {
+			return err
+		}
+
+		withoutBooks := tx.Model(&domain.Author{}).
+			Where("NOT EXISTS (SELECT 1 FROM author_books " + liveBooks + " WHERE author_books.author_id = authors.id)")
+
+		if err := withoutBooks.Session(&gorm.Session{}).Count(&stats.AuthorsWithoutBooksCount).Error; err != nil {
+			return err
+		}
+
+		return withoutBooks.
+			Select("authors.id, authors.name, 0 AS books").
+			Order("authors.name, authors.id").
+			Limit(top).
+			Scan(&stats.AuthorsWithoutBooks).Error
+	})
+
+	return stats, err
+}
+
+func (s *statsRepository) GetAuthorStats(ctx context.Context, id int) (AuthorStats, error) {
+	stats := AuthorStats{}
+
+	err := s.read(ctx).Transaction(func(tx *gorm.DB) error {
+		author := domain.Author{}
+		if err := tx.First(&author, id).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&domain.Book{}).
+			Select("COUNT(*) AS books, MIN(NULLIF(publication_year, 0)) AS first_publication_year, MAX(NULLIF(publication_year, 0)) AS last_publication_year").
+			Where("books.id IN (SELECT book_id FROM author_books WHERE author_id = ?)", id).
+			Scan(&stats).Error
+
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&domain.Author{}).
+			Where("authors.id <> ?", id).
+			Where("authors.id IN (SELECT co.author_id FROM author_books AS co "+
+				"JOIN author_books ON author_books.book_id = co.book_id "+liveBooks+
+				" WHERE author_books.author_id = ?)", id).
+			Count(&stats.CoAuthors).Error
+
+		stats.ID = author.ID
+		stats.Name = author.Name
+		return err
+	})
+
+	return stats, err
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const tenantField = "TenantID"
+
+// setupTenancy scopes every statement on a model with a TenantID to the tenant
+// of its context: created rows are stamped with it and queries, updates and
+// deletes only reach its rows, whether scoped or not. Raw SQL is left alone.
+func setupTenancy(db *gorm.DB) error {
+	if err := db.SetupJoinTable(&domain.Book{}, "Authors", &domain.AuthorBook{}); err != nil {
+		return err
+	}
+
+	if err := db.SetupJoinTable(&domain.Author{}, "Books", &domain.AuthorBook{}); err != nil {
+		return err
+	}
+
+	callbacks := db.Callback()
+
+	if err := callbacks.Create().Before("gorm:create").Register("tenancy:create", stampTenant); err != nil {
+		return err
+	}
+
+	if err := callbacks.Query().Before("gorm:query").Register("tenancy:query", scopeTenant); err != nil {
+		return err
+	}
+
+	if err := callbacks.Row().Before("gorm:row").Register("tenancy:row", scopeTenant); err != nil {
+		return err
+	}
+
+	if err := callbacks.Update().Before("gorm:update").Register("tenancy:update", scopeTenant); err != nil {
+		return err
+	}
+
+	return callbacks.Delete().Before("gorm:delete").Register("tenancy:delete", scopeTenant)
+}
+
+func statementContext(db *gorm.DB) context.Context {
+	if db.Statement.Context != nil {
+		return db.Statement.Context
+	}
+
+	return context.Background()
+}
+
+func stampTenant(db *gorm.DB) {
+	if db.Statement.Schema == nil {
+		return
+	}
+
+	field := db.Statement.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+
+	tenant := uctx.Tenant(statementContext(db))
+
+	value := db.Statement.ReflectValue
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := field.Set(reflect.Indirect(value.Index(i)), tenant); err != nil {
+				db.AddError(err)
+			}
+		}
+	case reflect.Struct:
+		if err := field.Set(value, tenant); err != nil {
+			db.AddError(err)
+		}
+	}
+}
+
+func scopeTenant(db *gorm.DB) {
+	if db.Statement.Schema == nil {
+		return
+	}
+
+	field := db.Statement.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName},
+			Value:  uctx.Tenant(statementContext(db)),
+		},
+	}})
+}
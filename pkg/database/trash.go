@@ -0,0 +1,67 @@
+package database
+
+import (
+	"time"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"gorm.io/gorm"
+)
+
+type PageRequest struct {
+	Limit  int
+	Offset int
+}
+
+// trash lists the soft-deleted rows of model, most recently deleted first.
+func trash(db *gorm.DB, r PageRequest, records interface{}) error {
+	return db.Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC, id").
+		Limit(r.Limit).
+		Offset(r.Offset).
+		Find(records).Error
+}
+
+// restore clears the deletion mark of a soft-deleted row and bumps its
+// version. It fails with gorm.ErrRecordNotFound when there is no such row in
+// the trash.
+func restore(db *gorm.DB, model interface{}, id int) error {
+	result := db.Unscoped().
+		Model(model).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// purge hard-deletes the rows of model soft-deleted before the given time,
+// together with their author_books links.
+func purge(db *gorm.DB, model interface{}, linkColumn string, before time.Time) (int64, error) {
+	var purged int64
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		expired := tx.Unscoped().Model(model).Select("id").Where("deleted_at IS NOT NULL AND deleted_at < ?", before)
+
+		err := tx.Where(linkColumn+" IN (?)", expired).Delete(&domain.AuthorBook{}).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(model)
+		purged = result.RowsAffected
+		return result.Error
+	})
+
+	return purged, err
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type TrashIntegrationSuite struct {
+	suite.Suite
+
+	ctx     context.Context
+	manager DBManager
+	authors AuthorsRepository
+	books   BooksRepository
+}
+
+func (s *TrashIntegrationSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.manager = NewDbManager(fmt.Sprintf("file:%s?mode=memory&cache=shared", s.T().Name()))
+	s.Require().NoError(s.manager.InitDb())
+	Migrate(s.manager.GetDB())
+
+	s.authors = NewAuthorsRepository(s.manager)
+	s.books = NewBooksRepository(s.manager)
+
+	author := &domain.Author{Name: "Luciano Ramalho"}
+	s.manager.GetDB().Create(&domain.Book{Name: "Fluent Python", Authors: []*domain.Author{author}})
+	s.manager.GetDB().Create(&domain.Book{Name: "Python Cookbook", Authors: []*domain.Author{author}})
+}
+
+func (s *TrashIntegrationSuite) TearDownTest() {
+	s.Require().NoError(s.manager.Close())
+}
+
+func (s *TrashIntegrationSuite) links() int64 {
+	var count int64
+	s.manager.GetDB().Table("author_books").Count(&count)
+	return count
+}
+
+func (s *TrashIntegrationSuite) TestDeletedBookShouldGoToTrash() {
+	// act
+	s.Require().NoError(s.books.Delete(s.ctx, 1, 0))
+
+	// assert
+	trash, err := s.books.GetTrash(s.ctx, PageRequest{Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(trash, 1)
+	s.Assert().Equal("Fluent Python", trash[0].Name)
+	s.Assert().True(trash[0].DeletedAt.Valid)
+	books, err := s.books.GetAll(s.ctx, GetAllRequest{Limit: 10})
+	s.Require().NoError(err)
+	s.Assert().Len(books, 1)
+}
+
+func (s *TrashIntegrationSuite) TestRestoreShouldBringBookBack() {
+	// arrange
+	s.Require().NoError(s.books.Delete(s.ctx, 1, 0))
+
+	// act
+	err := s.books.Restore(s.ctx, 1)
+
+	// assert
+	s.Assert().NoError(err)
+	trash, err := s.books.GetTrash(s.ctx, PageRequest{Limit: 10})
+	s.Require().NoError(err)
+	s.Assert().Empty(trash)
+	book, err := s.books.GetBook(s.ctx, 1, ExpandAuthors)
+	s.Assert().NoError(err)
+	s.Assert().Len(book.Authors, 1)
+}
+
+func (s *TrashIntegrationSuite) TestRestoreShouldFailIfNotInTrash() {
+	s.Assert().Equal(gorm.ErrRecordNotFound, s.books.Restore(s.ctx, 1))
+	s.Assert().Equal(gorm.ErrRecordNotFound, s.books.Restore(s.ctx, 42))
+	s.Assert().Equal(gorm.ErrRecordNotFound, s.authors.Restore(s.ctx, 1))
+}
+
+func (s *TrashIntegrationSuite) TestPurgeShouldHardDeleteOnlyExpiredRows() {
+	// arrange
+	s.Require().NoError(s.books.Delete(s.ctx, 1, 0))
+	s.Require().NoError(s.books.Delete(s.ctx, 2, 0))
+	s.manager.GetDB().Unscoped().Model(&domain.Book{}).Where("id = ?", 1).
+		Update("deleted_at", time.Now().Add(-31*24*time.Hour))
+
+	// act
+	purged, err := s.books.Purge(s.ctx, time.Now().Add(-30*24*time.Hour))
+
+	// assert
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(1), purged)
+	s.Assert().Equal(int64(1), s.links())
+
+	trash, err := s.books.GetTrash(s.ctx, PageRequest{Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(trash, 1)
+	s.Assert().Equal("Python Cookbook", trash[0].Name)
+}
+
+func (s *TrashIntegrationSuite) TestPurgeShouldRemoveAuthorLinks() {
+	// arrange
+	s.manager.GetDB().Delete(&domain.Author{}, 1)
+
+	// act
+	purged, err := s.authors.Purge(s.ctx, time.Now().Add(time.Second))
+
+	// assert
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(1), purged)
+	s.Assert().Equal(int64(0), s.links())
+	trash, err := s.authors.GetTrash(s.ctx, PageRequest{Limit: 10})
+	s.Require().NoError(err)
+	s.Assert().Empty(trash)
+}
+
+func TestIntegrationTrashSuite(t *testing.T) {
+	suite.Run(t, new(TrashIntegrationSuite))
+}
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var ErrVersionMismatch = errors.New("version does not match")
+
+// bumpVersion applies values to the row selected by db, provided it still has
+// the given version, and increments that version.
+func bumpVersion(db *gorm.DB, version uint, values map[string]interface{}) error {
+	values["version"] = version + 1
+
+	result := db.Where("version = ?", version).Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrVersionMismatch
+	}
+
+	return nil
+}
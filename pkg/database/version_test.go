@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/stretchr/testify/suite"
+)
+
+type VersionIntegrationSuite struct {
+	suite.Suite
+
+	ctx     context.Context
+	manager DBManager
+	books   BooksRepository
+	id      int
+}
+
+func (s *VersionIntegrationSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.manager = NewDbManager(fmt.Sprintf("file:%s?mode=memory&cache=shared", s.T().Name()))
+	s.Require().NoError(s.manager.InitDb())
+	Migrate(s.manager.GetDB())
+
+	s.books = NewBooksRepository(s.manager)
+
+	id, err := s.books.Create(s.ctx, domain.Book{Name: "Fluent Python", Edition: "1"})
+	s.Require().NoError(err)
+	s.id = int(id)
+}
+
+func (s *VersionIntegrationSuite) TearDownTest() {
+	s.Require().NoError(s.manager.Close())
+}
+
+func (s *VersionIntegrationSuite) version() uint {
+	book, err := s.books.GetBook(s.ctx, s.id, ExpandNone)
+	s.Require().NoError(err)
+	return book.Version
+}
+
+func (s *VersionIntegrationSuite) TestUpdateShouldBumpVersion() {
+	// act
+	err := s.books.Update(s.ctx, s.id, domain.Book{Name: "Fluent Python", Edition: "2", Version: 1})
+
+	// assert
+	s.Assert().NoError(err)
+	s.Assert().Equal(uint(2), s.version())
+}
+
+func (s *VersionIntegrationSuite) TestUpdateShouldRejectStaleVersion() {
+	// arrange
+	s.Require().NoError(s.books.Update(s.ctx, s.id, domain.Book{Name: "Fluent Python", Edition: "2", Version: 1}))
+
+	// act
+	err := s.books.Update(s.ctx, s.id, domain.Book{Name: "Fluent Python", Edition: "3", Version: 1})
+
+	// assert
+	s.Assert().Equal(ErrVersionMismatch, err)
+	book, _ := s.books.GetBook(s.ctx, s.id, ExpandNone)
+	s.Assert().Equal("2", book.Edition)
+	entries, err := s.books.GetHistory(s.ctx, s.id)
+	s.Require().NoError(err)
+	s.Assert().Len(entries, 2)
+}
+
+func (s *VersionIntegrationSuite) TestUpdateWithoutVersionShouldOverwrite() {
+	// act
+	err := s.books.Update(s.ctx, s.id, domain.Book{Name: "Fluent Python", Edition: "2"})
+
+	// assert
+	s.Assert().NoError(err)
+	s.Assert().Equal(uint(2), s.version())
+}
+
+func (s *VersionIntegrationSuite) TestDeleteShouldRejectStaleVersion() {
+	// act
+	err := s.books.Delete(s.ctx, s.id, 2)
+
+	// assert
+	s.Assert().Equal(ErrVersionMismatch, err)
+	s.Assert().Equal(uint(1), s.version())
+}
+
+func (s *VersionIntegrationSuite) TestRestoreShouldBumpVersion() {
+	// arrange
+	s.Require().NoError(s.books.Delete(s.ctx, s.id, 1))
+
+	// act
+	err := s.books.Restore(s.ctx, s.id)
+
+	// assert
+	s.Assert().NoError(err)
+	s.Assert().Equal(uint(2), s.version())
+}
+
+func TestIntegrationVersionSuite(t *testing.T) {
+	suite.Run(t, new(VersionIntegrationSuite))
+}
@@ -8,7 +8,9 @@ import (
 
 type Author struct {
 	gorm.Model
-	Name      string `gorm:"size:255"`
+	TenantID  string `gorm:"size:64;not null;default:'default';index;index:idx_authors_sort_name,priority:1" json:"-"`
+	Name      string `gorm:"size:255;index;index:idx_authors_sort_name,priority:2"`
+	Version   uint   `gorm:"not null;default:1"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
@@ -17,11 +19,55 @@ type Author struct {
 
 type Book struct {
 	gorm.Model
-	Name            string `gorm:"size:255"`
-	Edition         string `gorm:"size:255"`
-	PublicationYear int
+	TenantID        string `gorm:"size:64;not null;default:'default';index;index:idx_books_sort_name,priority:1;index:idx_books_sort_edition,priority:1;index:idx_books_sort_year,priority:1;index:idx_books_sort_publication_year,priority:1" json:"-"`
+	Name            string `gorm:"size:255;index;index:idx_books_sort_name,priority:2;index:idx_books_sort_year,priority:3"`
+	Edition         string `gorm:"size:255;index:idx_books_sort_edition,priority:2"`
+	PublicationYear int    `gorm:"index:idx_books_sort_year,priority:2,sort:desc;index:idx_books_sort_publication_year,priority:2"`
+	Version         uint   `gorm:"not null;default:1"`
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 	DeletedAt       gorm.DeletedAt `gorm:"index"`
 	Authors         []*Author      `gorm:"many2many:author_books;"`
 }
+
+type History struct {
+	ID         uint   `gorm:"primarykey"`
+	TenantID   string `gorm:"size:64;not null;default:'default';index" json:"-"`
+	EntityType string `gorm:"size:32;index:idx_history_entity"`
+	EntityID   uint   `gorm:"index:idx_history_entity"`
+	Action     string `gorm:"size:16"`
+	Before     string
+	After      string
+	Actor      string `gorm:"size:255"`
+	RequestID  string `gorm:"size:64"`
+	CreatedAt  time.Time
+}
+
+// AuthorBook links an author to a book of the same tenant.
+type AuthorBook struct {
+	AuthorID uint   `gorm:"primaryKey"`
+	BookID   uint   `gorm:"primaryKey"`
+	TenantID string `gorm:"size:64;not null;default:'default';index" json:"-"`
+}
+
+// OutboxEvent is a change written in the same transaction as the change
+// itself, waiting to be relayed downstream. Events are relayed in id order.
+type OutboxEvent struct {
+	ID            uint   `gorm:"primarykey"`
+	TenantID      string `gorm:"size:64;not null;default:'default';index" json:"-"`
+	Type          string `gorm:"size:32"`
+	AggregateType string `gorm:"size:32"`
+	AggregateID   uint
+	Payload       string
+	RequestID     string `gorm:"size:64"`
+	CreatedAt     time.Time
+}
+
+// OutboxOffset is the id of the last event a consumer of the outbox has been
+// delivered.
+type OutboxOffset struct {
+	TenantID  string `gorm:"primaryKey;size:64;not null;default:'default'" json:"-"`
+	Consumer  string `gorm:"primaryKey;size:64"`
+	Position  uint
+	UpdatedAt time.Time
+}
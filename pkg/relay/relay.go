@@ -0,0 +1,111 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/domain"
+)
+
+const DefaultBatchSize = 100
+
+// Event is an outbox event as delivered to sinks.
+type Event struct {
+	ID            uint            `json:"id"`
+	Type          string          `json:"type"`
+	Tenant        string          `json:"tenant"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   uint            `json:"aggregate_id"`
+	RequestID     string          `json:"request_id,omitempty"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+func NewEvent(e domain.OutboxEvent) Event {
+	return Event{
+		ID:            e.ID,
+		Type:          e.Type,
+		Tenant:        e.TenantID,
+		AggregateType: e.AggregateType,
+		AggregateID:   e.AggregateID,
+		RequestID:     e.RequestID,
+		OccurredAt:    e.CreatedAt.UTC(),
+		Payload:       json.RawMessage(e.Payload),
+	}
+}
+
+// Relay delivers the outbox of the tenant of the context to a sink, in order,
+// and stores the offset of the consumer after each delivered batch. Should it
+// stop between a delivery and the commit of its offset, that batch is
+// delivered again.
+type Relay struct {
+	Outbox    database.OutboxRepository
+	Sink      Sink
+	Consumer  string
+	BatchSize int
+}
+
+// Drain delivers the events that are not delivered yet and returns how many
+// it delivered.
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	offset, err := r.Outbox.Offset(ctx, r.Consumer)
+	if err != nil {
+		return 0, err
+	}
+
+	size := r.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+
+	delivered := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return delivered, err
+		}
+
+		events, err := r.Outbox.Read(ctx, offset, size)
+		if err != nil || len(events) == 0 {
+			return delivered, err
+		}
+
+		batch := make([]Event, 0, len(events))
+		for _, e := range events {
+			batch = append(batch, NewEvent(e))
+		}
+
+		if err := r.Sink.Deliver(ctx, batch); err != nil {
+			return delivered, err
+		}
+
+		offset = events[len(events)-1].ID
+		if err := r.Outbox.Commit(ctx, r.Consumer, offset); err != nil {
+			return delivered, err
+		}
+
+		delivered += len(events)
+	}
+}
+
+// Follow drains the outbox every interval until ctx is done.
+func (r *Relay) Follow(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Drain(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
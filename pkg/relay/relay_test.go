@@ -0,0 +1,166 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/stretchr/testify/suite"
+)
+
+type failingSink struct {
+	err error
+}
+
+func (s *failingSink) Deliver(ctx context.Context, events []Event) error {
+	return s.err
+}
+
+func (s *failingSink) Close() error {
+	return nil
+}
+
+type RelayIntegrationSuite struct {
+	suite.Suite
+
+	ctx     context.Context
+	manager database.DBManager
+	outbox  database.OutboxRepository
+	books   database.BooksRepository
+}
+
+func (s *RelayIntegrationSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.manager = database.NewDbManager(fmt.Sprintf("file:%s?mode=memory&cache=shared", s.T().Name()))
+	s.Require().NoError(s.manager.InitDb())
+	database.Migrate(s.manager.GetDB())
+
+	s.outbox = database.NewOutboxRepository(s.manager)
+	s.books = database.NewBooksRepository(s.manager)
+
+	for i := 1; i <= 5; i++ {
+		_, err := s.books.Create(s.ctx, domain.Book{Name: fmt.Sprintf("Book %d", i)})
+		s.Require().NoError(err)
+	}
+}
+
+func (s *RelayIntegrationSuite) TearDownTest() {
+	s.Require().NoError(s.manager.Close())
+}
+
+func (s *RelayIntegrationSuite) relay(sink Sink) *Relay {
+	return &Relay{Outbox: s.outbox, Sink: sink, Consumer: "test", BatchSize: 2}
+}
+
+func decodeLines(s string) []Event {
+	events := []Event{}
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		if line == "" {
+			continue
+		}
+
+		e := Event{}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			panic(err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func (s *RelayIntegrationSuite) TestDrainShouldDeliverEveryEventInOrder() {
+	// arrange
+	out := &bytes.Buffer{}
+
+	// act
+	delivered, err := s.relay(NewWriterSink(out)).Drain(s.ctx)
+
+	// assert
+	s.Require().NoError(err)
+	s.Assert().Equal(5, delivered)
+
+	events := decodeLines(out.String())
+	s.Require().Len(events, 5)
+	for i, e := range events {
+		s.Assert().Equal(uint(i+1), e.ID)
+		s.Assert().Equal(database.BookCreated, e.Type)
+		s.Assert().Equal("default", e.Tenant)
+		s.Assert().Contains(string(e.Payload), fmt.Sprintf(`"name":"Book %d"`, i+1))
+	}
+
+	offset, _ := s.outbox.Offset(s.ctx, "test")
+	s.Assert().Equal(uint(5), offset)
+}
+
+func (s *RelayIntegrationSuite) TestDrainShouldResumeFromTheOffset() {
+	// arrange
+	out := &bytes.Buffer{}
+	_, err := s.relay(NewWriterSink(out)).Drain(s.ctx)
+	s.Require().NoError(err)
+	out.Reset()
+
+	_, err = s.books.Create(s.ctx, domain.Book{Name: "Book 6"})
+	s.Require().NoError(err)
+
+	// act
+	delivered, err := s.relay(NewWriterSink(out)).Drain(s.ctx)
+
+	// assert
+	s.Require().NoError(err)
+	s.Assert().Equal(1, delivered)
+	events := decodeLines(out.String())
+	s.Require().Len(events, 1)
+	s.Assert().Equal(uint(6), events[0].ID)
+}
+
+func (s *RelayIntegrationSuite) TestFailedDeliveryShouldBeRetried() {
+	// arrange
+	failure := errors.New("sink is down")
+
+	// act
+	_, err := s.relay(&failingSink{err: failure}).Drain(s.ctx)
+
+	// assert
+	s.Assert().Equal(failure, err)
+	offset, _ := s.outbox.Offset(s.ctx, "test")
+	s.Assert().Equal(uint(0), offset)
+
+	out := &bytes.Buffer{}
+	delivered, err := s.relay(NewWriterSink(out)).Drain(s.ctx)
+	s.Require().NoError(err)
+	s.Assert().Equal(5, delivered)
+}
+
+func (s *RelayIntegrationSuite) TestFileSinkShouldAppendEvents() {
+	// arrange
+	path := filepath.Join(s.T().TempDir(), "events.jsonl")
+
+	// act
+	for i := 0; i < 2; i++ {
+		sink, err := NewFileSink(path)
+		s.Require().NoError(err)
+
+		r := s.relay(sink)
+		r.Consumer = fmt.Sprintf("file-%d", i)
+		_, err = r.Drain(s.ctx)
+		s.Require().NoError(err)
+		s.Require().NoError(sink.Close())
+	}
+
+	// assert
+	content, err := ioutil.ReadFile(path)
+	s.Require().NoError(err)
+	s.Assert().Len(decodeLines(string(content)), 10)
+}
+
+func TestIntegrationRelaySuite(t *testing.T) {
+	suite.Run(t, new(RelayIntegrationSuite))
+}
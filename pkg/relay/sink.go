@@ -0,0 +1,97 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// Sink delivers events downstream. A batch is either delivered as a whole or
+// reported as failed, in which case it is delivered again later, so sinks get
+// every event at least once.
+type Sink interface {
+	Deliver(ctx context.Context, events []Event) error
+	Close() error
+}
+
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a Sink that writes each event to w as a JSON line.
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{
+		w: w,
+	}
+}
+
+// NewStdoutSink returns a Sink that writes each event to stdout as a JSON
+// line.
+func NewStdoutSink() Sink {
+	return NewWriterSink(os.Stdout)
+}
+
+func (s *writerSink) Deliver(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return writeLines(s.w, events)
+}
+
+func (s *writerSink) Close() error {
+	return nil
+}
+
+type fileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink returns a Sink that appends each event to the file at path as a
+// JSON line, creating it when it does not exist. Every batch is synced to disk
+// before it counts as delivered.
+func NewFileSink(path string) (Sink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileSink{
+		file: file,
+	}, nil
+}
+
+func (s *fileSink) Deliver(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := writeLines(s.file, events); err != nil {
+		return err
+	}
+
+	return s.file.Sync()
+}
+
+func (s *fileSink) Close() error {
+	return s.file.Close()
+}
+
+// writeLines writes the whole batch with a single write, so that a failed
+// batch does not leave half of it behind.
+func writeLines(w io.Writer, events []Event) error {
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+
+	for _, e := range events {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
@@ -0,0 +1,119 @@
+package testkit
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/domain"
+	"gorm.io/gorm"
+)
+
+var fixtures uint64
+
+// AuthorBuilder builds an author fixture. Unless told otherwise the author
+// gets a unique name.
+type AuthorBuilder struct {
+	author domain.Author
+}
+
+func AnAuthor() *AuthorBuilder {
+	return &AuthorBuilder{
+		author: domain.Author{
+			Name:    fmt.Sprintf("Author %d", atomic.AddUint64(&fixtures, 1)),
+			Version: 1,
+		},
+	}
+}
+
+func (b *AuthorBuilder) Named(name string) *AuthorBuilder {
+	b.author.Name = name
+	return b
+}
+
+func (b *AuthorBuilder) Deleted() *AuthorBuilder {
+	b.author.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return b
+}
+
+func (b *AuthorBuilder) Build() domain.Author {
+	return b.author
+}
+
+// Create stores the author straight in the database, without history, and
+// returns it with its id.
+func (b *AuthorBuilder) Create(t testing.TB, m database.DBManager) domain.Author {
+	t.Helper()
+
+	author := b.Build()
+	if err := m.GetDB().Create(&author).Error; err != nil {
+		t.Fatalf("can not create author: %v", err)
+	}
+
+	return author
+}
+
+// BookBuilder builds a book fixture. Unless told otherwise the book gets a
+// unique name, the first edition and no authors.
+type BookBuilder struct {
+	book domain.Book
+}
+
+func ABook() *BookBuilder {
+	return &BookBuilder{
+		book: domain.Book{
+			Name:            fmt.Sprintf("Book %d", atomic.AddUint64(&fixtures, 1)),
+			Edition:         "1",
+			PublicationYear: 2020,
+			Version:         1,
+		},
+	}
+}
+
+func (b *BookBuilder) Named(name string) *BookBuilder {
+	b.book.Name = name
+	return b
+}
+
+func (b *BookBuilder) Edition(edition string) *BookBuilder {
+	b.book.Edition = edition
+	return b
+}
+
+func (b *BookBuilder) PublishedIn(year int) *BookBuilder {
+	b.book.PublicationYear = year
+	return b
+}
+
+// By links the book to authors, which must have been created already.
+func (b *BookBuilder) By(authors ...domain.Author) *BookBuilder {
+	for i := range authors {
+		author := authors[i]
+		b.book.Authors = append(b.book.Authors, &author)
+	}
+	return b
+}
+
+func (b *BookBuilder) Deleted() *BookBuilder {
+	b.book.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return b
+}
+
+func (b *BookBuilder) Build() domain.Book {
+	return b.book
+}
+
+// Create stores the book and its links straight in the database, without
+// history, and returns it with its id.
+func (b *BookBuilder) Create(t testing.TB, m database.DBManager) domain.Book {
+	t.Helper()
+
+	book := b.Build()
+	if err := m.GetDB().Omit("Authors.*").Create(&book).Error; err != nil {
+		t.Fatalf("can not create book: %v", err)
+	}
+
+	return book
+}
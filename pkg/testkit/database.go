@@ -0,0 +1,38 @@
+// Package testkit helps writing integration tests: ephemeral databases,
+// fixture builders and the api served over HTTP.
+package testkit
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/database"
+)
+
+var (
+	databases   uint64
+	unsafeChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+)
+
+// NewDBManager returns a migrated in-memory SQLite database of its own,
+// closed when t ends.
+func NewDBManager(t testing.TB) database.DBManager {
+	t.Helper()
+
+	name := fmt.Sprintf("%s_%d", unsafeChars.ReplaceAllString(t.Name(), "_"), atomic.AddUint64(&databases, 1))
+	manager := database.NewDbManager(fmt.Sprintf("file:%s?mode=memory&cache=shared", name))
+	if err := manager.InitDb(); err != nil {
+		t.Fatalf("can not open database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("can not close database: %v", err)
+		}
+	})
+
+	database.Migrate(manager.GetDB())
+	return manager
+}
@@ -0,0 +1,86 @@
+package testkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/api"
+	"github.com/jedielson/bookstore/pkg/database"
+)
+
+// App is the whole api served over HTTP on top of an ephemeral database.
+type App struct {
+	Manager database.DBManager
+	Repos   database.Repos
+	Server  *httptest.Server
+}
+
+// NewApp starts the router of cmd/web on a test server, backed by a database
+// of its own. Both are closed when t ends.
+func NewApp(t testing.TB) *App {
+	return NewAppWithOptions(t, api.RouterOptions{})
+}
+
+func NewAppWithOptions(t testing.TB, opts api.RouterOptions) *App {
+	t.Helper()
+
+	manager := NewDBManager(t)
+	repos := database.NewRepos(manager)
+	server := httptest.NewServer(api.NewRouter(repos, opts))
+	t.Cleanup(server.Close)
+
+	return &App{
+		Manager: manager,
+		Repos:   repos,
+		Server:  server,
+	}
+}
+
+// Do sends a request to the app. A non-nil body is sent as JSON, and the
+// headers are given as name, value pairs.
+func (a *App) Do(t testing.TB, method string, path string, body interface{}, headers ...string) *http.Response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("can not encode body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, a.Server.URL+path, reader)
+	if err != nil {
+		t.Fatalf("can not build request: %v", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	res, err := a.Server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("can not send request: %v", err)
+	}
+
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+// Decode reads the JSON body of res into v.
+func Decode(t testing.TB, res *http.Response, v interface{}) {
+	t.Helper()
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		t.Fatalf("can not decode body: %v", err)
+	}
+}
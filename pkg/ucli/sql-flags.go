@@ -0,0 +1,114 @@
+package ucli
+
+import (
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/urfave/cli/v2"
+)
+
+var defaults = database.DefaultOptions()
+
+// The flags that open the SQL database, shared by the web server and the
+// worker.
+var (
+	SqlDsnFlag = &cli.StringFlag{
+		Name:     "sql-dsn",
+		Usage:    "dsn to use for connecting database",
+		Value:    "bookstore.db",
+		EnvVars:  []string{"BOOKSTORE_SQL_DSN"},
+		Required: false,
+	}
+
+	SqlJournalModeFlag = &cli.StringFlag{
+		Name:     "sql-journal-mode",
+		Usage:    "sqlite journal mode: DELETE, TRUNCATE, PERSIST, MEMORY, WAL or OFF",
+		Value:    defaults.JournalMode,
+		EnvVars:  []string{"BOOKSTORE_SQL_JOURNAL_MODE"},
+		Required: false,
+	}
+
+	SqlSynchronousFlag = &cli.StringFlag{
+		Name:     "sql-synchronous",
+		Usage:    "sqlite synchronous level: OFF, NORMAL, FULL or EXTRA",
+		Value:    defaults.Synchronous,
+		EnvVars:  []string{"BOOKSTORE_SQL_SYNCHRONOUS"},
+		Required: false,
+	}
+
+	SqlBusyTimeoutFlag = &cli.DurationFlag{
+		Name:     "sql-busy-timeout",
+		Usage:    "how long to wait for a locked database before failing",
+		Value:    defaults.BusyTimeout,
+		EnvVars:  []string{"BOOKSTORE_SQL_BUSY_TIMEOUT"},
+		Required: false,
+	}
+
+	SqlForeignKeysFlag = &cli.BoolFlag{
+		Name:     "sql-foreign-keys",
+		Usage:    "enforce foreign key constraints",
+		Value:    defaults.ForeignKeys,
+		EnvVars:  []string{"BOOKSTORE_SQL_FOREIGN_KEYS"},
+		Required: false,
+	}
+
+	SqlMaxOpenConnsFlag = &cli.IntFlag{
+		Name:     "sql-max-open-conns",
+		Usage:    "maximum number of open connections, 0 means unlimited",
+		Value:    defaults.MaxOpenConns,
+		EnvVars:  []string{"BOOKSTORE_SQL_MAX_OPEN_CONNS"},
+		Required: false,
+	}
+
+	SqlMaxIdleConnsFlag = &cli.IntFlag{
+		Name:     "sql-max-idle-conns",
+		Usage:    "maximum number of idle connections",
+		Value:    defaults.MaxIdleConns,
+		EnvVars:  []string{"BOOKSTORE_SQL_MAX_IDLE_CONNS"},
+		Required: false,
+	}
+
+	SqlPrepareStmtFlag = &cli.BoolFlag{
+		Name:     "sql-prepare-stmt",
+		Usage:    "cache prepared statements",
+		Value:    defaults.PrepareStmt,
+		EnvVars:  []string{"BOOKSTORE_SQL_PREPARE_STMT"},
+		Required: false,
+	}
+
+	SqlReplicaFlag = &cli.StringSliceFlag{
+		Name:     "sql-replica",
+		Usage:    "dsn of a read-only connection to spread reads over, may be repeated",
+		EnvVars:  []string{"BOOKSTORE_SQL_REPLICAS"},
+		Required: false,
+	}
+
+	SqlFlags = []cli.Flag{
+		SqlDsnFlag,
+		SqlJournalModeFlag,
+		SqlSynchronousFlag,
+		SqlBusyTimeoutFlag,
+		SqlForeignKeysFlag,
+		SqlMaxOpenConnsFlag,
+		SqlMaxIdleConnsFlag,
+		SqlPrepareStmtFlag,
+		SqlReplicaFlag,
+	}
+)
+
+// SqlOptions reads the options of the connection from the flags.
+func SqlOptions(c *cli.Context) database.Options {
+	return database.Options{
+		JournalMode:  c.String(SqlJournalModeFlag.Name),
+		Synchronous:  c.String(SqlSynchronousFlag.Name),
+		BusyTimeout:  c.Duration(SqlBusyTimeoutFlag.Name),
+		ForeignKeys:  c.Bool(SqlForeignKeysFlag.Name),
+		MaxOpenConns: c.Int(SqlMaxOpenConnsFlag.Name),
+		MaxIdleConns: c.Int(SqlMaxIdleConnsFlag.Name),
+		PrepareStmt:  c.Bool(SqlPrepareStmtFlag.Name),
+		Replicas:     c.StringSlice(SqlReplicaFlag.Name),
+	}
+}
+
+// NewDbManager returns a manager of the database the flags point to.
+func NewDbManager(c *cli.Context) database.DBManager {
+	return database.NewDbManagerWithOptions(c.String(SqlDsnFlag.Name), SqlOptions(c))
+}
@@ -1,6 +1,7 @@
 package ucsv
 
 import (
+	"context"
 	"encoding/csv"
 	"fmt"
 	"io"
@@ -11,7 +12,8 @@ import (
 	"github.com/jedielson/bookstore/pkg/domain"
 )
 
-func ReadFile(filePath string, manager database.DBManager) {
+// ReadFile imports the authors of the csv file into the tenant of ctx.
+func ReadFile(ctx context.Context, filePath string, manager database.DBManager) {
 
 	csvfile, err := os.Open(filePath)
 	if err != nil {
@@ -37,7 +39,7 @@ func ReadFile(filePath string, manager database.DBManager) {
 			continue
 		}
 
-		db := manager.GetDB()
+		db := manager.GetDB().WithContext(ctx)
 
 		var author domain.Author
 		db.Where("name = ?", record[0]).First(&author)
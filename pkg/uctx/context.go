@@ -0,0 +1,105 @@
+package uctx
+
+import (
+	"context"
+	"regexp"
+	"time"
+)
+
+type key int
+
+const (
+	actorKey key = iota
+	requestIDKey
+	strictPreconditionsKey
+	readYourWritesKey
+	tenantKey
+)
+
+const (
+	Anonymous     = "anonymous"
+	DefaultTenant = "default"
+)
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// Actor returns who is performing the request, or Anonymous when unknown.
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey).(string); ok && len(actor) > 0 {
+		return actor
+	}
+
+	return Anonymous
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+func WithStrictPreconditions(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strictPreconditionsKey, true)
+}
+
+// StrictPreconditions tells whether writes must carry an If-Match header.
+func StrictPreconditions(ctx context.Context) bool {
+	strict, _ := ctx.Value(strictPreconditionsKey).(bool)
+	return strict
+}
+
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey, true)
+}
+
+// ReadYourWrites tells whether reads must go to the primary database, so that
+// they see the writes not yet replicated to the readers.
+func ReadYourWrites(ctx context.Context) bool {
+	ryw, _ := ctx.Value(readYourWritesKey).(bool)
+	return ryw
+}
+
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// Tenant returns the library whose catalogue is being used, or DefaultTenant
+// when there is a single one.
+func Tenant(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey).(string); ok && len(tenant) > 0 {
+		return tenant
+	}
+
+	return DefaultTenant
+}
+
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ValidTenant tells whether tenant may name a library: up to 64 lower case
+// letters, digits, dashes and underscores, starting with a letter or digit.
+func ValidTenant(tenant string) bool {
+	return tenantPattern.MatchString(tenant)
+}
+
+// Detach returns a context that carries the values of ctx but is never
+// cancelled nor times out with it, for work shared by several requests.
+func Detach(ctx context.Context) context.Context {
+	return detached{parent: ctx}
+}
+
+type detached struct {
+	parent context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detached) Done() <-chan struct{} { return nil }
+
+func (detached) Err() error { return nil }
+
+func (d detached) Value(key interface{}) interface{} { return d.parent.Value(key) }
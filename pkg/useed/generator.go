@@ -0,0 +1,136 @@
+package useed
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+)
+
+var (
+	firstNames = []string{
+		"Ana", "Brian", "Carla", "David", "Elena", "Felipe", "Grace", "Hiro", "Ines", "Jorge",
+		"Karen", "Luciano", "Maria", "Nadia", "Osvaldo", "Paulo", "Quinn", "Rafael", "Sofia", "Tomas",
+		"Ursula", "Victor", "Wanda", "Xavier", "Yara", "Zoe", "Alan", "Barbara", "Chetan", "Donald",
+	}
+
+	lastNames = []string{
+		"Almeida", "Beazley", "Costa", "Dijkstra", "Evans", "Fowler", "Giridhar", "Hopper", "Iverson", "Jones",
+		"Kernighan", "Lamport", "Martin", "Neto", "Okafor", "Pike", "Quintana", "Ramalho", "Santana", "Thompson",
+		"Ueda", "Vieira", "Wirth", "Xu", "Yamamoto", "Zhang", "Knuth", "Liskov", "Ritchie", "Stroustrup",
+	}
+
+	topics = []string{
+		"Go", "Python", "Rust", "Distributed Systems", "Databases", "Algorithms", "Compilers", "Networks",
+		"Machine Learning", "Cryptography", "Operating Systems", "Web Development", "Data Structures",
+		"Functional Programming", "Concurrency", "Testing", "Software Architecture", "Cloud Computing",
+	}
+
+	adjectives = []string{
+		"Practical", "Fluent", "Effective", "Modern", "Advanced", "Essential", "Pragmatic", "Hands-On",
+		"Elegant", "Applied", "Professional", "Concise",
+	}
+
+	nouns = []string{
+		"Guide", "Handbook", "Cookbook", "Patterns", "Principles", "Recipes", "Foundations", "Craft",
+	}
+
+	titles = []func(r *rand.Rand) string{
+		func(r *rand.Rand) string { return fmt.Sprintf("%s %s", pick(r, adjectives), pick(r, topics)) },
+		func(r *rand.Rand) string { return fmt.Sprintf("The %s of %s", pick(r, nouns), pick(r, topics)) },
+		func(r *rand.Rand) string { return fmt.Sprintf("%s in Action", pick(r, topics)) },
+		func(r *rand.Rand) string { return fmt.Sprintf("Learning %s", pick(r, topics)) },
+		func(r *rand.Rand) string {
+			return fmt.Sprintf("%s: %s %s", pick(r, topics), pick(r, adjectives), pick(r, nouns))
+		},
+	}
+)
+
+// LatestYear is the most recent publication year generated. It is fixed, not
+// taken from the clock, so that a seed always generates the same books.
+const LatestYear = 2020
+
+// Generator builds realistic authors and books. The same seed always yields
+// the same sequence.
+type Generator struct {
+	r *rand.Rand
+}
+
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		r: rand.New(rand.NewSource(seed)),
+	}
+}
+
+func pick(r *rand.Rand, values []string) string {
+	return values[r.Intn(len(values))]
+}
+
+func (g *Generator) Author() domain.Author {
+	name := fmt.Sprintf("%s %s", pick(g.r, firstNames), pick(g.r, lastNames))
+	if g.r.Intn(3) == 0 {
+		name = fmt.Sprintf("%s %c. %s", pick(g.r, firstNames), 'A'+rune(g.r.Intn(26)), pick(g.r, lastNames))
+	}
+
+	return domain.Author{Name: name, Version: 1}
+}
+
+// Book builds a book written by one to three of the given authors. A few
+// authors write most of the books and most books are recent, as in a real
+// catalog.
+func (g *Generator) Book(authors []uint) domain.Book {
+	book := domain.Book{
+		Name:            titles[g.r.Intn(len(titles))](g.r),
+		Edition:         g.edition(),
+		PublicationYear: g.year(),
+		Version:         1,
+	}
+
+	if len(authors) == 0 {
+		return book
+	}
+
+	count := 1
+	switch n := g.r.Intn(100); {
+	case n >= 92:
+		count = 3
+	case n >= 70:
+		count = 2
+	}
+
+	zipf := rand.NewZipf(g.r, 1.2, 1, uint64(len(authors)-1))
+	chosen := map[uint]bool{}
+	for attempt := 0; len(chosen) < count && attempt < 10*count; attempt++ {
+		id := authors[zipf.Uint64()]
+		if chosen[id] {
+			continue
+		}
+
+		chosen[id] = true
+		author := &domain.Author{}
+		author.ID = id
+		book.Authors = append(book.Authors, author)
+	}
+
+	return book
+}
+
+func (g *Generator) edition() string {
+	edition := 1
+	for edition < 5 && g.r.Intn(4) == 0 {
+		edition++
+	}
+
+	return strconv.Itoa(edition)
+}
+
+// year is exponentially skewed towards LatestYear, back to 1950.
+func (g *Generator) year() int {
+	year := LatestYear - int(g.r.ExpFloat64()*8)
+	if year < 1950 {
+		return 1950
+	}
+
+	return year
+}
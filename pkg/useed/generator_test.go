@@ -0,0 +1,73 @@
+package useed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type GeneratorSuite struct {
+	suite.Suite
+}
+
+func (s *GeneratorSuite) TestSameSeedShouldGenerateSameData() {
+	// arrange
+	first, second := NewGenerator(42), NewGenerator(42)
+	authors := []uint{1, 2, 3, 4, 5}
+
+	for i := 0; i < 100; i++ {
+		// act
+		a, b := first.Book(authors), second.Book(authors)
+
+		// assert
+		s.Assert().Equal(first.Author(), second.Author())
+		s.Assert().Equal(a.Name, b.Name)
+		s.Assert().Equal(a.PublicationYear, b.PublicationYear)
+		s.Assert().Equal(len(a.Authors), len(b.Authors))
+	}
+}
+
+func (s *GeneratorSuite) TestBooksShouldBeSkewed() {
+	// arrange
+	g := NewGenerator(1)
+	authors := []uint{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	recent, coauthored := 0, 0
+	byAuthor := map[uint]int{}
+
+	// act
+	for i := 0; i < 1000; i++ {
+		book := g.Book(authors)
+		if book.PublicationYear > LatestYear-10 {
+			recent++
+		}
+
+		if len(book.Authors) > 1 {
+			coauthored++
+		}
+
+		seen := map[uint]bool{}
+		for _, a := range book.Authors {
+			s.Assert().False(seen[a.ID], "author listed twice")
+			seen[a.ID] = true
+			byAuthor[a.ID]++
+		}
+	}
+
+	// assert
+	s.Assert().Greater(recent, 600)
+	s.Assert().Greater(coauthored, 150)
+	s.Assert().Greater(byAuthor[1], 3*byAuthor[10])
+}
+
+func (s *GeneratorSuite) TestBookShouldAllowASingleAuthor() {
+	// act
+	book := NewGenerator(1).Book([]uint{9})
+
+	// assert
+	s.Require().NotEmpty(book.Authors)
+	s.Assert().Equal(uint(9), book.Authors[0].ID)
+}
+
+func TestGeneratorSuite(t *testing.T) {
+	suite.Run(t, new(GeneratorSuite))
+}
@@ -0,0 +1,70 @@
+package useed
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jedielson/bookstore/pkg/domain"
+	"gorm.io/gorm"
+)
+
+type Options struct {
+	Authors   int
+	Books     int
+	Seed      int64
+	BatchSize int
+}
+
+type Counts struct {
+	Authors int
+	Books   int
+}
+
+// Seed inserts generated authors and then books linked to them, in batches of
+// BatchSize rows and within a single transaction. The data is added to what
+// is already stored, and bypasses the history.
+func Seed(ctx context.Context, db *gorm.DB, opts Options) (Counts, error) {
+	counts := Counts{}
+
+	if opts.Authors < 0 || opts.Books < 0 || opts.BatchSize <= 0 {
+		return counts, errors.New("authors and books can not be negative and the batch size must be positive")
+	}
+
+	g := NewGenerator(opts.Seed)
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids := make([]uint, 0, opts.Authors)
+
+		for counts.Authors < opts.Authors {
+			batch := make([]domain.Author, 0, opts.BatchSize)
+			for len(batch) < opts.BatchSize && counts.Authors+len(batch) < opts.Authors {
+				batch = append(batch, g.Author())
+			}
+
+			if err := tx.Create(&batch).Error; err != nil {
+				return err
+			}
+
+			for _, a := range batch {
+				ids = append(ids, a.ID)
+			}
+			counts.Authors += len(batch)
+		}
+
+		for counts.Books < opts.Books {
+			batch := make([]domain.Book, 0, opts.BatchSize)
+			for len(batch) < opts.BatchSize && counts.Books+len(batch) < opts.Books {
+				batch = append(batch, g.Book(ids))
+			}
+
+			if err := tx.Omit("Authors.*").Create(&batch).Error; err != nil {
+				return err
+			}
+			counts.Books += len(batch)
+		}
+
+		return nil
+	})
+
+	return counts, err
+}
@@ -0,0 +1,60 @@
+package useed
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/domain"
+	"github.com/stretchr/testify/suite"
+)
+
+type SeedIntegrationSuite struct {
+	suite.Suite
+
+	ctx     context.Context
+	manager database.DBManager
+}
+
+func (s *SeedIntegrationSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.manager = database.NewDbManager(fmt.Sprintf("file:%s?mode=memory&cache=shared", s.T().Name()))
+	s.Require().NoError(s.manager.InitDb())
+	database.Migrate(s.manager.GetDB())
+}
+
+func (s *SeedIntegrationSuite) TearDownTest() {
+	s.Require().NoError(s.manager.Close())
+}
+
+func (s *SeedIntegrationSuite) TestSeedShouldInsertLinkedBooksInBatches() {
+	// act
+	counts, err := Seed(s.ctx, s.manager.GetDB(), Options{Authors: 25, Books: 120, Seed: 7, BatchSize: 50})
+
+	// assert
+	s.Require().NoError(err)
+	s.Assert().Equal(Counts{Authors: 25, Books: 120}, counts)
+
+	var links, orphans int64
+	s.manager.GetDB().Table("author_books").Count(&links)
+	s.manager.GetDB().Model(&domain.Book{}).Where("id NOT IN (SELECT book_id FROM author_books)").Count(&orphans)
+	s.Assert().Greater(links, int64(120))
+	s.Assert().Zero(orphans)
+
+	books, err := database.NewBooksRepository(s.manager).GetAll(s.ctx, database.GetAllRequest{Limit: 10})
+	s.Require().NoError(err)
+	s.Assert().Len(books, 10)
+}
+
+func (s *SeedIntegrationSuite) TestSeedShouldRejectInvalidOptions() {
+	// act
+	_, err := Seed(s.ctx, s.manager.GetDB(), Options{Authors: 1, Books: 1, BatchSize: 0})
+
+	// assert
+	s.Assert().Error(err)
+}
+
+func TestIntegrationSeedSuite(t *testing.T) {
+	suite.Run(t, new(SeedIntegrationSuite))
+}
@@ -0,0 +1,61 @@
+package uweb
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+
+	"github.com/jedielson/bookstore/pkg/uctx"
+)
+
+const (
+	RequestIDHeader = "X-Request-Id"
+	ActorHeader     = "X-Actor"
+
+	ReadYourWritesHeader = "X-Read-Your-Writes"
+)
+
+const maxRequestIDLength = 64
+
+// RequestContext carries the request id and the actor of every request in its
+// context. A request id is generated when the client does not send one. A
+// true X-Read-Your-Writes header sends the reads of the request to the
+// primary database.
+func RequestContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		id := r.Header.Get(RequestIDHeader)
+		if len(id) == 0 || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := uctx.WithRequestID(r.Context(), id)
+		ctx = uctx.WithActor(ctx, r.Header.Get(ActorHeader))
+
+		if ryw, _ := strconv.ParseBool(r.Header.Get(ReadYourWritesHeader)); ryw {
+			ctx = uctx.WithReadYourWrites(ctx)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newRequestID() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(bytes)
+}
+
+// StrictPreconditions makes the writes that honour If-Match, that is PUT, PATCH
+// and DELETE, fail with 428 Precondition Required when they do not send it.
+func StrictPreconditions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(uctx.WithStrictPreconditions(r.Context())))
+	})
+}
@@ -0,0 +1,79 @@
+package uweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"github.com/stretchr/testify/suite"
+)
+
+type MiddlewareSuite struct {
+	suite.Suite
+
+	res *httptest.ResponseRecorder
+
+	actor          string
+	requestID      string
+	readYourWrites bool
+	handler        http.Handler
+}
+
+func (s *MiddlewareSuite) SetupTest() {
+	s.res = httptest.NewRecorder()
+	s.handler = RequestContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.actor = uctx.Actor(r.Context())
+		s.requestID = uctx.RequestID(r.Context())
+		s.readYourWrites = uctx.ReadYourWrites(r.Context())
+	}))
+}
+
+func (s *MiddlewareSuite) TestShouldCarryHeadersInContext() {
+	// arrange
+	req := httptest.NewRequest(http.MethodGet, "/books", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	req.Header.Set(ActorHeader, "librarian")
+
+	// act
+	s.handler.ServeHTTP(s.res, req)
+
+	// assert
+	s.Assert().Equal("librarian", s.actor)
+	s.Assert().Equal("req-1", s.requestID)
+	s.Assert().Equal("req-1", s.res.Header().Get(RequestIDHeader))
+}
+
+func (s *MiddlewareSuite) TestShouldReadYourWritesOnlyWhenAsked() {
+	for header, expected := range map[string]bool{"": false, "false": false, "maybe": false, "true": true, "1": true} {
+		// arrange
+		req := httptest.NewRequest(http.MethodGet, "/books", nil)
+		req.Header.Set(ReadYourWritesHeader, header)
+
+		// act
+		s.handler.ServeHTTP(s.res, req)
+
+		// assert
+		s.Assert().Equal(expected, s.readYourWrites, header)
+	}
+}
+
+func (s *MiddlewareSuite) TestShouldGenerateRequestIdIfMissingOrTooLong() {
+	for _, id := range []string{"", strings.Repeat("x", 65)} {
+		// arrange
+		req := httptest.NewRequest(http.MethodGet, "/books", nil)
+		req.Header.Set(RequestIDHeader, id)
+
+		// act
+		s.handler.ServeHTTP(s.res, req)
+
+		// assert
+		s.Assert().Len(s.requestID, 32)
+		s.Assert().Equal(uctx.Anonymous, s.actor)
+	}
+}
+
+func TestMiddlewareSuite(t *testing.T) {
+	suite.Run(t, new(MiddlewareSuite))
+}
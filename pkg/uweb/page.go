@@ -0,0 +1,74 @@
+package uweb
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const LinkHeader = "Link"
+
+// Page is the envelope of a listing. Offset is set for pages asked for by
+// offset and Cursor for pages asked for after a cursor. NextCursor, also sent
+// in the X-Next-Cursor header, is set when a full page may be followed by
+// more rows. Pages after a cursor only walk forward: they link to the next page
+// but never to the previous one. Total is only counted on demand.
+type Page struct {
+	Data       interface{} `json:"data"`
+	Total      *int64      `json:"total,omitempty"`
+	Limit      int         `json:"limit"`
+	Offset     *int        `json:"offset,omitempty"`
+	Cursor     string      `json:"cursor,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Links      PageLinks   `json:"links"`
+}
+
+type PageLinks struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// ToPage writes page as json, with its links in an RFC 8288 Link header too.
+func ToPage(w http.ResponseWriter, page Page) {
+	var links []string
+	if len(page.Links.Next) > 0 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, page.Links.Next))
+	}
+	if len(page.Links.Prev) > 0 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, page.Links.Prev))
+	}
+	if len(links) > 0 {
+		w.Header().Set(LinkHeader, strings.Join(links, ", "))
+	}
+
+	ToJson(w, page)
+}
+
+// PageLink returns the path and query of r with the query key set to value,
+// keeping the filters of the listing. A link after a cursor drops the offset,
+// which the cursor replaces.
+func PageLink(r *http.Request, key string, value string) string {
+	query := r.URL.Query()
+	if key == "after" {
+		query.Del("offset")
+	}
+	query.Set(key, value)
+	return r.URL.Path + "?" + query.Encode()
+}
+
+// BindTotal reads the total query, which asks to count the rows of a listing
+// over every page.
+func BindTotal(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("total")
+	if len(value) == 0 {
+		return false, nil
+	}
+
+	total, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, InvalidParameter("total", "total must be true or false")
+	}
+
+	return total, nil
+}
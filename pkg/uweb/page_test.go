@@ -0,0 +1,101 @@
+package uweb
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PageSuite struct {
+	suite.Suite
+}
+
+func (s *PageSuite) TestToPageShouldWriteTheLinksInTheBodyAndHeader() {
+	// arrange
+	res := httptest.NewRecorder()
+	offset := 10
+	page := Page{Data: []string{"a"}, Limit: 10, Offset: &offset, Links: PageLinks{Next: "/books?offset=20", Prev: "/books?offset=0"}}
+
+	// act
+	ToPage(res, page)
+
+	// assert
+	s.Assert().Equal(http.StatusOK, res.Code)
+	s.Assert().Equal(`</books?offset=20>; rel="next", </books?offset=0>; rel="prev"`, res.Header().Get(LinkHeader))
+	s.Assert().JSONEq(`{"data": ["a"], "limit": 10, "offset": 10, "links": {"next": "/books?offset=20", "prev": "/books?offset=0"}}`, res.Body.String())
+}
+
+func (s *PageSuite) TestToPageShouldLeaveOutTheLinkHeaderOfASinglePage() {
+	// arrange
+	res := httptest.NewRecorder()
+	total := int64(1)
+
+	// act
+	ToPage(res, Page{Data: []string{"a"}, Limit: 10, Total: &total})
+
+	// assert
+	var body map[string]interface{}
+	s.Require().NoError(json.Unmarshal(res.Body.Bytes(), &body))
+
+	s.Assert().Empty(res.Header().Get(LinkHeader))
+	s.Assert().Equal(float64(1), body["total"])
+	s.Assert().NotContains(body, "offset")
+}
+
+func (s *PageSuite) TestPageLinkShouldKeepTheQuery() {
+	// arrange
+	req := httptest.NewRequest(http.MethodGet, "/books?name=Go&offset=10&limit=5", nil)
+
+	// act
+	link := PageLink(req, "offset", "15")
+
+	// assert
+	s.Assert().Equal("/books?limit=5&name=Go&offset=15", link)
+}
+
+func (s *PageSuite) TestPageLinkAfterACursorShouldDropTheOffset() {
+	// arrange
+	req := httptest.NewRequest(http.MethodGet, "/books?name=Go&offset=10&after=abc&limit=5", nil)
+
+	// act
+	link := PageLink(req, "after", "def")
+
+	// assert
+	s.Assert().Equal("/books?after=def&limit=5&name=Go", link)
+}
+
+var testsBindTotal = []struct {
+	query string
+	total bool
+	fails bool
+}{
+	{query: "", total: false},
+	{query: "?total=true", total: true},
+	{query: "?total=false", total: false},
+	{query: "?total=maybe", fails: true},
+}
+
+func (s *PageSuite) TestBindTotal() {
+	for _, t := range testsBindTotal {
+		// arrange
+		req := httptest.NewRequest(http.MethodGet, "/books"+t.query, nil)
+
+		// act
+		total, err := BindTotal(req)
+
+		// assert
+		s.Assert().Equal(t.total, total, t.query)
+		if t.fails {
+			s.Assert().Equal(http.StatusBadRequest, AsError(err).Status, t.query)
+		} else {
+			s.Assert().NoError(err, t.query)
+		}
+	}
+}
+
+func TestPageSuite(t *testing.T) {
+	suite.Run(t, new(PageSuite))
+}
@@ -0,0 +1,355 @@
+package uweb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+
+	AcceptPatchHeader = "Accept-Patch"
+)
+
+// AcceptPatch lists the patch formats PATCH understands, as advertised in the
+// Accept-Patch header.
+const AcceptPatch = MergePatchContentType + ", " + JSONPatchContentType
+
+const (
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodePatchConflict        = "patch_conflict"
+)
+
+// ApplyPatch applies the patch in the body of r to doc, a json document, with
+// the format given by its Content-Type: a JSON Merge Patch (RFC 7396) or a
+// JSON Patch (RFC 6902). It returns the patched document.
+func ApplyPatch(r *http.Request, doc []byte) ([]byte, error) {
+	defer r.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != MergePatchContentType && mediaType != JSONPatchContentType {
+		return nil, NewError(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "the patch must be sent as "+AcceptPatch)
+	}
+
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	var patched interface{}
+	if mediaType == MergePatchContentType {
+		var patch interface{}
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			return nil, invalidPayload(err)
+		}
+
+		patched = mergePatch(target, patch)
+	} else {
+		var ops []patchOperation
+		if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+			return nil, invalidPayload(err)
+		}
+
+		var err error
+		if patched, err = jsonPatch(target, ops); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(patched)
+}
+
+// mergePatch merges patch into target: members of an object patch replace
+// those of the target, recursively, and null members remove them. Any other
+// patch replaces the target as a whole.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+
+	for name, value := range members {
+		if value == nil {
+			delete(object, name)
+		} else {
+			object[name] = mergePatch(object[name], value)
+		}
+	}
+
+	return object
+}
+
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// jsonPatch applies the operations in order, and none of them when one fails.
+func jsonPatch(doc interface{}, ops []patchOperation) (interface{}, error) {
+	for i, op := range ops {
+		var err error
+		if doc, err = op.apply(doc); err != nil {
+			if e, ok := err.(*Error); ok {
+				e.Detail = fmt.Sprintf("operation %d: %s", i, e.Detail)
+			}
+			return nil, err
+		}
+	}
+
+	return doc, nil
+}
+
+func (op patchOperation) apply(doc interface{}) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		value, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+
+	case "replace":
+		value, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		if doc, _, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		var value interface{}
+		if op.Op == "move" {
+			doc, value, err = remove(doc, from)
+		} else {
+			value, err = get(doc, from)
+			value = clone(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "test":
+		value, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, patchConflict("%s does not hold the tested value", op.Path)
+		}
+		return doc, nil
+
+	default:
+		return nil, invalidPatch("unknown operation %q", op.Op)
+	}
+}
+
+func (op patchOperation) value() (interface{}, error) {
+	if len(op.Value) == 0 {
+		return nil, invalidPatch("%s needs a value", op.Op)
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(op.Value))
+	if err := decoder.Decode(&value); err != nil {
+		return nil, invalidPatch("the value of %s is invalid", op.Op)
+	}
+
+	return value, nil
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if len(pointer) == 0 {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, invalidPatch("%q is not a JSON Pointer", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return tokens, nil
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, patchConflict("%s does not exist", token)
+			}
+			doc = value
+		case []interface{}:
+			i, err := index(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, patchConflict("%s does not exist", token)
+		}
+	}
+
+	return doc, nil
+}
+
+// add sets the member, or inserts the element, at path and returns the
+// document, which is replaced when path is the root.
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	token := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[token] = value
+		return doc, nil
+	case []interface{}:
+		i := len(node)
+		if token != "-" {
+			if i, err = index(token, len(node)); err != nil {
+				return nil, err
+			}
+		}
+		node = append(node, nil)
+		copy(node[i+1:], node[i:])
+		node[i] = value
+		return set(doc, path[:len(path)-1], node)
+	default:
+		return nil, patchConflict("%s can not hold %s", strings.Join(path[:len(path)-1], "/"), token)
+	}
+}
+
+// set replaces the value at path, which exists, and returns the document.
+func set(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	token := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[token] = value
+	case []interface{}:
+		i, err := index(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = value
+	}
+
+	return doc, nil
+}
+
+// remove takes out the member or element at path and returns the document
+// and the value removed.
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		value, ok := node[token]
+		if !ok {
+			return nil, nil, patchConflict("%s does not exist", token)
+		}
+		delete(node, token)
+		return doc, value, nil
+	case []interface{}:
+		i, err := index(token, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := node[i]
+		node = append(node[:i:i], node[i+1:]...)
+		doc, err = set(doc, path[:len(path)-1], node)
+		return doc, value, err
+	default:
+		return nil, nil, patchConflict("%s does not exist", token)
+	}
+}
+
+func index(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max || (len(token) > 1 && token[0] == '0') {
+		return 0, patchConflict("%s is not an index of the array", token)
+	}
+
+	return i, nil
+}
+
+func clone(value interface{}) interface{} {
+	data, _ := json.Marshal(value)
+
+	var copied interface{}
+	_ = json.Unmarshal(data, &copied)
+	return copied
+}
+
+func invalidPatch(format string, args ...interface{}) *Error {
+	return NewError(http.StatusBadRequest, CodeInvalidPayload, fmt.Sprintf(format, args...))
+}
+
+// patchConflict reports a patch that can not be applied to the current state
+// of the resource, as RFC 5789 suggests.
+func patchConflict(format string, args ...interface{}) *Error {
+	return NewError(http.StatusConflict, CodePatchConflict, fmt.Sprintf(format, args...))
+}
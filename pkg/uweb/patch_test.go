@@ -0,0 +1,123 @@
+package uweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PatchSuite struct {
+	suite.Suite
+}
+
+func patchRequest(contentType string, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPatch, "/books/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
+
+var testsMergePatch = []struct {
+	doc      string
+	patch    string
+	expected string
+}{
+	{doc: `{"a": "b"}`, patch: `{"a": "c"}`, expected: `{"a": "c"}`},
+	{doc: `{"a": "b"}`, patch: `{"b": "c"}`, expected: `{"a": "b", "b": "c"}`},
+	{doc: `{"a": "b", "b": "c"}`, patch: `{"a": null}`, expected: `{"b": "c"}`},
+	{doc: `{"a": ["b"]}`, patch: `{"a": ["c", "d"]}`, expected: `{"a": ["c", "d"]}`},
+	{doc: `{"a": {"b": "c", "d": "e"}}`, patch: `{"a": {"b": "f", "d": null}}`, expected: `{"a": {"b": "f"}}`},
+	{doc: `{"a": "b"}`, patch: `{"a": {"c": null}}`, expected: `{"a": {}}`},
+	{doc: `{"a": "b"}`, patch: `["c"]`, expected: `["c"]`},
+}
+
+func (s *PatchSuite) TestApplyMergePatch() {
+	for _, t := range testsMergePatch {
+		// arrange
+		req := patchRequest(MergePatchContentType, t.patch)
+
+		// act
+		patched, err := ApplyPatch(req, []byte(t.doc))
+
+		// assert
+		s.Require().NoError(err, t.patch)
+		s.Assert().JSONEq(t.expected, string(patched), t.patch)
+	}
+}
+
+var testsJSONPatch = []struct {
+	doc      string
+	patch    string
+	expected string
+}{
+	{doc: `{"a": "b"}`, patch: `[{"op": "replace", "path": "/a", "value": "c"}]`, expected: `{"a": "c"}`},
+	{doc: `{"a": "b"}`, patch: `[{"op": "add", "path": "/c", "value": 1}]`, expected: `{"a": "b", "c": 1}`},
+	{doc: `{"a": "b", "c": 1}`, patch: `[{"op": "remove", "path": "/c"}]`, expected: `{"a": "b"}`},
+	{doc: `{"a": ["b", "d"]}`, patch: `[{"op": "add", "path": "/a/1", "value": "c"}]`, expected: `{"a": ["b", "c", "d"]}`},
+	{doc: `{"a": ["b"]}`, patch: `[{"op": "add", "path": "/a/-", "value": "c"}]`, expected: `{"a": ["b", "c"]}`},
+	{doc: `{"a": ["b", "c"]}`, patch: `[{"op": "remove", "path": "/a/0"}]`, expected: `{"a": ["c"]}`},
+	{doc: `{"a": [["b"]]}`, patch: `[{"op": "add", "path": "/a/0/-", "value": "c"}]`, expected: `{"a": [["b", "c"]]}`},
+	{doc: `{"a": "b"}`, patch: `[{"op": "move", "from": "/a", "path": "/c"}]`, expected: `{"c": "b"}`},
+	{doc: `{"a": {"b": 1}}`, patch: `[{"op": "copy", "from": "/a", "path": "/c"}]`, expected: `{"a": {"b": 1}, "c": {"b": 1}}`},
+	{doc: `{"a/b": 1, "c~d": 2}`, patch: `[{"op": "replace", "path": "/a~1b", "value": 3}, {"op": "remove", "path": "/c~0d"}]`, expected: `{"a/b": 3}`},
+	{doc: `{"a": 1}`, patch: `[{"op": "test", "path": "/a", "value": 1}, {"op": "replace", "path": "/a", "value": 2}]`, expected: `{"a": 2}`},
+}
+
+func (s *PatchSuite) TestApplyJSONPatch() {
+	for _, t := range testsJSONPatch {
+		// arrange
+		req := patchRequest(JSONPatchContentType, t.patch)
+
+		// act
+		patched, err := ApplyPatch(req, []byte(t.doc))
+
+		// assert
+		s.Require().NoError(err, t.patch)
+		s.Assert().JSONEq(t.expected, string(patched), t.patch)
+	}
+}
+
+var testsJSONPatchErrors = []struct {
+	patch  string
+	status int
+}{
+	{patch: `[{"op": "test", "path": "/a", "value": 2}]`, status: http.StatusConflict},
+	{patch: `[{"op": "replace", "path": "/b", "value": 2}]`, status: http.StatusConflict},
+	{patch: `[{"op": "remove", "path": "/a/0"}]`, status: http.StatusConflict},
+	{patch: `[{"op": "add", "path": "/b"}]`, status: http.StatusBadRequest},
+	{patch: `[{"op": "rename", "path": "/a"}]`, status: http.StatusBadRequest},
+	{patch: `[{"op": "remove", "path": "a"}]`, status: http.StatusBadRequest},
+	{patch: `{"op": "remove", "path": "/a"}`, status: http.StatusBadRequest},
+}
+
+func (s *PatchSuite) TestApplyJSONPatchShouldFailWithoutApplyingAnything() {
+	for _, t := range testsJSONPatchErrors {
+		// arrange
+		req := patchRequest(JSONPatchContentType, t.patch)
+
+		// act
+		patched, err := ApplyPatch(req, []byte(`{"a": 1}`))
+
+		// assert
+		s.Assert().Nil(patched, t.patch)
+		s.Assert().Equal(t.status, AsError(err).Status, t.patch)
+	}
+}
+
+func (s *PatchSuite) TestApplyPatchShouldRejectOtherMediaTypes() {
+	// arrange
+	req := patchRequest("application/json", `{"a": 2}`)
+
+	// act
+	_, err := ApplyPatch(req, []byte(`{"a": 1}`))
+
+	// assert
+	s.Assert().Equal(http.StatusUnsupportedMediaType, AsError(err).Status)
+	s.Assert().Equal(CodeUnsupportedMediaType, AsError(err).Code)
+}
+
+func TestPatchSuite(t *testing.T) {
+	suite.Run(t, new(PatchSuite))
+}
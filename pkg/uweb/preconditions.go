@@ -0,0 +1,53 @@
+package uweb
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/uctx"
+)
+
+const (
+	ETagHeader    = "ETag"
+	IfMatchHeader = "If-Match"
+)
+
+var ErrPreconditionRequired = errors.New("If-Match header is required")
+
+func ETag(version uint) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+func SetETag(w http.ResponseWriter, version uint) {
+	w.Header().Set(ETagHeader, ETag(version))
+}
+
+// BindIfMatch reads the version a write is conditioned on. It returns 0 when
+// any version is accepted, that is for "*" or for a missing header outside of
+// strict mode. If-Match compares strongly (RFC 9110), so a weak W/ validator
+// never matches.
+func BindIfMatch(r *http.Request) (uint, error) {
+	value := strings.TrimSpace(r.Header.Get(IfMatchHeader))
+
+	if len(value) == 0 {
+		if uctx.StrictPreconditions(r.Context()) {
+			return 0, ErrPreconditionRequired
+		}
+		return 0, nil
+	}
+
+	if value == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.ParseUint(strings.Trim(value, `"`), 10, 64)
+	if err != nil || version == 0 {
+		return 0, database.ErrVersionMismatch
+	}
+
+	return uint(version), nil
+}
@@ -0,0 +1,52 @@
+package uweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"github.com/stretchr/testify/suite"
+)
+
+type PreconditionsSuite struct {
+	suite.Suite
+}
+
+var testsIfMatch = []struct {
+	ifMatch  string
+	strict   bool
+	expected uint
+	err      error
+}{
+	{ifMatch: "", strict: false, expected: 0, err: nil},
+	{ifMatch: "", strict: true, expected: 0, err: ErrPreconditionRequired},
+	{ifMatch: "*", strict: true, expected: 0, err: nil},
+	{ifMatch: `"3"`, strict: true, expected: 3, err: nil},
+	{ifMatch: `W/"3"`, strict: false, expected: 0, err: database.ErrVersionMismatch},
+	{ifMatch: `"abc"`, strict: false, expected: 0, err: database.ErrVersionMismatch},
+	{ifMatch: `"0"`, strict: false, expected: 0, err: database.ErrVersionMismatch},
+}
+
+func (s *PreconditionsSuite) TestBindIfMatch() {
+	for _, n := range testsIfMatch {
+		// arrange
+		req := httptest.NewRequest(http.MethodPut, "/books/1", nil)
+		req.Header.Set(IfMatchHeader, n.ifMatch)
+		if n.strict {
+			req = req.WithContext(uctx.WithStrictPreconditions(req.Context()))
+		}
+
+		// act
+		version, err := BindIfMatch(req)
+
+		// assert
+		s.Assert().Equal(n.expected, version, n.ifMatch)
+		s.Assert().Equal(n.err, err, n.ifMatch)
+	}
+}
+
+func TestPreconditionsSuite(t *testing.T) {
+	suite.Run(t, new(PreconditionsSuite))
+}
@@ -0,0 +1,169 @@
+package uweb
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"gorm.io/gorm"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypeBase prefixes the code of a problem to build its type.
+const ProblemTypeBase = "urn:bookstore:problem:"
+
+// The codes of the problems are stable: clients may rely on them, unlike on
+// titles and details.
+const (
+	CodeInvalidParameter     = "invalid_parameter"
+	CodeInvalidPayload       = "invalid_payload"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeInvalidTenant        = "invalid_tenant"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeAuthorNotFound       = "author_not_found"
+	CodeAuthorHasBooks       = "author_has_books"
+	CodeMergeIntoItself      = "merge_into_itself"
+	CodeVersionMismatch      = "version_mismatch"
+	CodePreconditionRequired = "precondition_required"
+	CodeInternal             = "internal_error"
+)
+
+// Problem is an RFC 7807 problem detail, extended with a stable code, the
+// request id and the invalid fields.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError tells why the value of a field of the request is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is an error rendered as a problem with its status and code.
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+func NewError(status int, code string, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// InvalidParameter reports a path or query parameter that can not be used.
+func InvalidParameter(name string, message string) *Error {
+	return &Error{
+		Status: http.StatusBadRequest,
+		Code:   CodeInvalidParameter,
+		Detail: message,
+		Fields: []FieldError{{Field: name, Code: "invalid", Message: message}},
+	}
+}
+
+// ValidationFailed reports a payload whose fields break the rules of the
+// resource.
+func ValidationFailed(fields ...FieldError) *Error {
+	return &Error{
+		Status: http.StatusUnprocessableEntity,
+		Code:   CodeValidationFailed,
+		Detail: "the request has invalid fields",
+		Fields: fields,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// AsError returns the Error err is rendered as. Errors that are not known are
+// internal errors, whose detail is not disclosed.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	switch {
+	case errors.Is(err, ErrPreconditionRequired):
+		return NewError(http.StatusPreconditionRequired, CodePreconditionRequired, err.Error())
+	case errors.Is(err, database.ErrVersionMismatch):
+		return NewError(http.StatusPreconditionFailed, CodeVersionMismatch, "the resource has changed, read it again before writing it")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NewError(http.StatusNotFound, CodeNotFound, "the resource does not exist")
+	case errors.Is(err, database.ErrAuthorNotFound):
+		return NewError(http.StatusUnprocessableEntity, CodeAuthorNotFound, err.Error())
+	case errors.Is(err, database.ErrAuthorHasBooks):
+		return NewError(http.StatusConflict, CodeAuthorHasBooks, "the author still has books, delete it with cascade=unlink to remove it from them")
+	case errors.Is(err, database.ErrMergeIntoItself):
+		return NewError(http.StatusUnprocessableEntity, CodeMergeIntoItself, err.Error())
+	case errors.Is(err, database.ErrInvalidCursor):
+		return InvalidParameter("after", err.Error())
+	default:
+		return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Detail: "the request could not be processed", Err: err}
+	}
+}
+
+// ToProblem writes err as an application/problem+json response.
+func ToProblem(w http.ResponseWriter, r *http.Request, err error) {
+	e := AsError(err)
+	if e.Status >= http.StatusInternalServerError {
+		log.Printf("%s %s failed: %v", r.Method, r.URL.RequestURI(), err)
+	}
+
+	problem := Problem{
+		Type:      ProblemTypeBase + e.Code,
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Detail,
+		Instance:  r.URL.RequestURI(),
+		Code:      e.Code,
+		RequestID: uctx.RequestID(r.Context()),
+		Errors:    e.Fields,
+	}
+
+	bytes, err := json.Marshal(problem)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(e.Status)
+	_, _ = w.Write(bytes)
+}
+
+// NotFound writes a problem for requests that match no route.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	ToProblem(w, r, NewError(http.StatusNotFound, CodeNotFound, "no resource matches the path"))
+}
+
+// MethodNotAllowed writes a problem for requests whose route does not accept
+// their method.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	ToProblem(w, r, NewError(http.StatusMethodNotAllowed, CodeMethodNotAllowed, r.Method+" is not allowed on this resource"))
+}
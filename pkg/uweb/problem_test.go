@@ -0,0 +1,119 @@
+package uweb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/database"
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type ProblemSuite struct {
+	suite.Suite
+
+	req *http.Request
+	res *httptest.ResponseRecorder
+}
+
+func (s *ProblemSuite) SetupTest() {
+	s.req = httptest.NewRequest(http.MethodGet, "/books/1?expand=authors", nil)
+	s.res = httptest.NewRecorder()
+}
+
+func (s *ProblemSuite) problem() Problem {
+	var problem Problem
+	s.Require().NoError(json.Unmarshal(s.res.Body.Bytes(), &problem))
+	return problem
+}
+
+var testsAsError = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{err: ErrPreconditionRequired, status: http.StatusPreconditionRequired, code: CodePreconditionRequired},
+	{err: database.ErrVersionMismatch, status: http.StatusPreconditionFailed, code: CodeVersionMismatch},
+	{err: gorm.ErrRecordNotFound, status: http.StatusNotFound, code: CodeNotFound},
+	{err: fmt.Errorf("book 1: %w", gorm.ErrRecordNotFound), status: http.StatusNotFound, code: CodeNotFound},
+	{err: database.ErrAuthorNotFound, status: http.StatusUnprocessableEntity, code: CodeAuthorNotFound},
+	{err: database.ErrAuthorHasBooks, status: http.StatusConflict, code: CodeAuthorHasBooks},
+	{err: database.ErrMergeIntoItself, status: http.StatusUnprocessableEntity, code: CodeMergeIntoItself},
+	{err: database.ErrInvalidCursor, status: http.StatusBadRequest, code: CodeInvalidParameter},
+	{err: InvalidParameter("id", "id is invalid"), status: http.StatusBadRequest, code: CodeInvalidParameter},
+	{err: errors.New("database is locked"), status: http.StatusInternalServerError, code: CodeInternal},
+}
+
+func (s *ProblemSuite) TestAsError() {
+	for _, n := range testsAsError {
+		// act
+		e := AsError(n.err)
+
+		// assert
+		s.Assert().Equal(n.status, e.Status, n.err.Error())
+		s.Assert().Equal(n.code, e.Code, n.err.Error())
+	}
+}
+
+func (s *ProblemSuite) TestToProblemShouldWriteProblemDetails() {
+	// arrange
+	s.req = s.req.WithContext(uctx.WithRequestID(s.req.Context(), "request-1"))
+
+	// act
+	ToProblem(s.res, s.req, gorm.ErrRecordNotFound)
+
+	// assert
+	s.Assert().Equal(http.StatusNotFound, s.res.Code)
+	s.Assert().Equal(ProblemContentType, s.res.Header().Get("Content-Type"))
+	s.Assert().Equal(Problem{
+		Type:      ProblemTypeBase + CodeNotFound,
+		Title:     "Not Found",
+		Status:    http.StatusNotFound,
+		Detail:    "the resource does not exist",
+		Instance:  "/books/1?expand=authors",
+		Code:      CodeNotFound,
+		RequestID: "request-1",
+	}, s.problem())
+}
+
+func (s *ProblemSuite) TestToProblemShouldListInvalidFields() {
+	// act
+	ToProblem(s.res, s.req, ValidationFailed(
+		FieldError{Field: "name", Code: "required", Message: "name is required"},
+		FieldError{Field: "publication_year", Code: "range", Message: "publication_year is in the future"},
+	))
+
+	// assert
+	problem := s.problem()
+	s.Assert().Equal(http.StatusUnprocessableEntity, s.res.Code)
+	s.Assert().Equal(CodeValidationFailed, problem.Code)
+	s.Require().Len(problem.Errors, 2)
+	s.Assert().Equal("publication_year", problem.Errors[1].Field)
+}
+
+func (s *ProblemSuite) TestToProblemShouldHideInternalErrors() {
+	// act
+	ToProblem(s.res, s.req, errors.New("no such table: books"))
+
+	// assert
+	s.Assert().Equal(http.StatusInternalServerError, s.res.Code)
+	s.Assert().NotContains(s.res.Body.String(), "no such table")
+}
+
+func (s *ProblemSuite) TestMethodNotAllowedShouldWriteProblem() {
+	// act
+	MethodNotAllowed(s.res, httptest.NewRequest(http.MethodPatch, "/books", nil))
+
+	// assert
+	s.Assert().Equal(http.StatusMethodNotAllowed, s.res.Code)
+	s.Assert().Equal(CodeMethodNotAllowed, s.problem().Code)
+}
+
+func TestProblemSuite(t *testing.T) {
+	suite.Run(t, new(ProblemSuite))
+}
@@ -1,9 +1,10 @@
 package uweb
 
 import (
+	"bytes"
 	"encoding/json"
-	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/jedielson/bookstore/pkg/database"
@@ -17,26 +18,54 @@ const (
 	Query
 )
 
-func BindGetAuthorsRequest(r *http.Request) (string, int, int) {
+func BindGetAuthorsRequest(r *http.Request) database.GetAuthorsRequest {
 
 	name := r.URL.Query().Get("name")
+	query := r.URL.Query().Get("q")
 	offset := FromQuery(r, "offset", 0, ValidateOffsetQuery)
 	limit := FromQuery(r, "limit", 1000, ValidateLimitQuery)
 
-	return name, offset, limit
+	return database.GetAuthorsRequest{
+		Name:   name,
+		Query:  query,
+		Limit:  limit,
+		Offset: offset,
+	}
 }
 
 func BindBookId(r *http.Request, segment URLSegment, err string) (int, error) {
+	return bindId(r, "id", err)
+}
+
+func BindAuthorId(r *http.Request, segment URLSegment, err string) (int, error) {
+	return bindId(r, "id", err)
+}
+
+// BindLinkedAuthorId reads the id of the author in a path below a book, such
+// as /books/{id}/authors/{authorId}.
+func BindLinkedAuthorId(r *http.Request, err string) (int, error) {
+	return bindId(r, "authorId", err)
+}
+
+func bindId(r *http.Request, key string, err string) (int, error) {
 	f := func(i int) bool {
 		return i > 0
 	}
 
-	return FromPath(r, "id", f, errors.New(err))
+	return FromPath(r, key, f, InvalidParameter(key, err))
+}
+
+func BindPageRequest(r *http.Request) database.PageRequest {
+	return database.PageRequest{
+		Limit:  FromQuery(r, "limit", 1000, ValidateLimitQuery),
+		Offset: FromQuery(r, "offset", 0, ValidateOffsetQuery),
+	}
 }
 
 func BindGetBooksRequest(r *http.Request) database.GetAllRequest {
 
 	name := r.URL.Query().Get("name")
+	query := r.URL.Query().Get("q")
 	pubYear := FromQuery(r, "publication_year", 0, func(i int) bool { return i > 1500 && i <= time.Now().Year() })
 	edition := r.URL.Query().Get("edition")
 	author := FromQuery(r, "author", 0, func(i int) bool { return i > 0 })
@@ -44,25 +73,256 @@ func BindGetBooksRequest(r *http.Request) database.GetAllRequest {
 	offset := FromQuery(r, "offset", 0, ValidateOffsetQuery)
 	return database.GetAllRequest{
 		Name:            name,
+		Query:           query,
 		PublicationYear: pubYear,
 		Edition:         edition,
 		Author:          author,
 		Limit:           limit,
 		Offset:          offset,
+		Expand:          BindExpand(r),
+	}
+}
+
+// BindExpand reads the comma separated expand query, e.g. "authors" or
+// "authors.books". Unknown relations are ignored and deeper paths are
+// capped to database.MaxExpandDepth.
+func BindExpand(r *http.Request) database.Expand {
+	expand := database.ExpandNone
+
+	for _, path := range strings.Split(r.URL.Query().Get("expand"), ",") {
+		segments := strings.Split(strings.TrimSpace(path), ".")
+		if len(segments) > database.MaxExpandDepth {
+			segments = segments[:database.MaxExpandDepth]
+		}
+
+		depth := database.ExpandNone
+		for i, segment := range segments {
+			if segment != expandRelations[i] {
+				break
+			}
+			depth++
+		}
+
+		if depth > expand {
+			expand = depth
+		}
 	}
+
+	return expand
+}
+
+var expandRelations = []string{"authors", "books"}
+
+// BookFields are the fields of a book a client writes.
+type BookFields struct {
+	Name            string `validate:"required,length=:255"`
+	Edition         string `validate:"required,length=:255,range=1:"`
+	PublicationYear int    `validate:"required,range=1:,notfuture"`
+}
+
+// Trim removes the spaces around the texts of the book.
+func (f *BookFields) Trim() {
+	f.Name = strings.TrimSpace(f.Name)
+	f.Edition = strings.TrimSpace(f.Edition)
+}
+
+// Book returns a book holding the fields.
+func (f BookFields) Book() domain.Book {
+	return domain.Book{Name: f.Name, Edition: f.Edition, PublicationYear: f.PublicationYear}
+}
+
+// BookRequest is the payload that creates a book with its authors.
+type BookRequest struct {
+	BookFields
+	Authors []AuthorRef `validate:"required"`
+}
+
+// AuthorRef names an existing author by id.
+type AuthorRef struct {
+	ID uint `validate:"required"`
+}
+
+// AuthorRequest is the payload that creates or replaces an author.
+type AuthorRequest struct {
+	Name string `validate:"required,length=:255"`
 }
 
 func BindCreateBookRequest(r *http.Request) (domain.Book, error) {
-	var book domain.Book
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&book); err != nil {
-		return book, errors.New("Invalid request payload")
+	var request BookRequest
+	if err := bindPayload(r, &request); err != nil {
+		return domain.Book{}, err
+	}
+
+	request.Trim()
+	if err := Validate(request); err != nil {
+		return domain.Book{}, err
+	}
+
+	book := request.Book()
+	for _, a := range request.Authors {
+		author := &domain.Author{}
+		author.ID = a.ID
+		book.Authors = append(book.Authors, author)
 	}
 
-	defer r.Body.Close()
 	return book, nil
 }
 
+// BindUpdateBookRequest binds the fields of a book replaced by PUT. Its
+// authors are changed through /books/{id}/authors.
+func BindUpdateBookRequest(r *http.Request) (domain.Book, error) {
+	var fields BookFields
+	if err := bindPayload(r, &fields); err != nil {
+		return domain.Book{}, err
+	}
+
+	fields.Trim()
+	if err := Validate(fields); err != nil {
+		return domain.Book{}, err
+	}
+
+	return fields.Book(), nil
+}
+
+// BindBookPatch applies the patch in the body of r, a merge patch or a JSON
+// Patch, to the fields of book and validates the result. The fields are named
+// as in the representation of the book; others can not be patched.
+func BindBookPatch(r *http.Request, book domain.Book) (domain.Book, error) {
+	doc, err := json.Marshal(BookFields{Name: book.Name, Edition: book.Edition, PublicationYear: book.PublicationYear})
+	if err != nil {
+		return domain.Book{}, err
+	}
+
+	patched, err := ApplyPatch(r, doc)
+	if err != nil {
+		return domain.Book{}, err
+	}
+
+	var fields BookFields
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&fields); err != nil {
+		return domain.Book{}, invalidPayload(err)
+	}
+
+	fields.Trim()
+	if err := Validate(fields); err != nil {
+		return domain.Book{}, err
+	}
+
+	return fields.Book(), nil
+}
+
+func bindPayload(r *http.Request, v interface{}) error {
+	defer r.Body.Close()
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(v); err != nil {
+		return invalidPayload(err)
+	}
+
+	return nil
+}
+
+func invalidPayload(err error) *Error {
+	return &Error{
+		Status: http.StatusBadRequest,
+		Code:   CodeInvalidPayload,
+		Detail: "Invalid request payload",
+		Err:    err,
+	}
+}
+
+func BindAuthorRequest(r *http.Request) (domain.Author, error) {
+	var request AuthorRequest
+	if err := bindPayload(r, &request); err != nil {
+		return domain.Author{}, err
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if err := Validate(request); err != nil {
+		return domain.Author{}, err
+	}
+
+	return domain.Author{Name: request.Name}, nil
+}
+
+// MergeRequest is the payload that merges an author into another one.
+type MergeRequest struct {
+	Into int `json:"into" validate:"required,range=1:"`
+}
+
+// BindMergeRequest reads the id of the author another one is merged into.
+func BindMergeRequest(r *http.Request) (int, error) {
+	var request MergeRequest
+	if err := bindPayload(r, &request); err != nil {
+		return 0, err
+	}
+
+	if err := Validate(request); err != nil {
+		return 0, err
+	}
+
+	return request.Into, nil
+}
+
+// AuthorPatch holds the fields of an author a PATCH sets; nil fields are left
+// as they are.
+type AuthorPatch struct {
+	Name *string
+}
+
+func BindAuthorPatch(r *http.Request) (AuthorPatch, error) {
+	var patch AuthorPatch
+	if err := bindPayload(r, &patch); err != nil {
+		return patch, err
+	}
+
+	return patch, nil
+}
+
+const CascadeUnlink = "unlink"
+
+// BindCascade reads the cascade query of a delete and tells whether the
+// deleted author should be unlinked from its books.
+func BindCascade(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("cascade") {
+	case "":
+		return false, nil
+	case CascadeUnlink:
+		return true, nil
+	default:
+		return false, InvalidParameter("cascade", "cascade is invalid, use "+CascadeUnlink)
+	}
+}
+
+// BindCursor reads the opaque after query. It returns nil when the listing
+// starts from the beginning.
+func BindCursor(r *http.Request) (*database.Cursor, error) {
+	after := r.URL.Query().Get("after")
+	if len(after) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := database.ParseCursor(after)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+// BindSort reads the sort query of a listing, which may only use fields. It
+// returns nil when the listing keeps its default order.
+func BindSort(r *http.Request, fields []string) (database.Sort, error) {
+	sort, err := database.ParseSort(r.URL.Query().Get("sort"), fields)
+	if err != nil {
+		return nil, InvalidParameter("sort", err.Error())
+	}
+
+	return sort, nil
+}
+
 func ValidateLimitQuery(i int) bool {
 	return i < 1000 && i > 0
 }
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +11,7 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/jedielson/bookstore/pkg/database"
 	"github.com/jedielson/bookstore/pkg/domain"
 	"github.com/stretchr/testify/suite"
 )
@@ -49,8 +49,8 @@ var testsNome = []struct {
 func (s *RequestBindingHandlerSuite) TestNameQuery() {
 	for _, n := range testsNome {
 		s.req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/authors?name=%s", n.nome), nil)
-		nome, _, _ := BindGetAuthorsRequest(s.req)
-		s.Assert().Equal(n.expected, nome)
+		request := BindGetAuthorsRequest(s.req)
+		s.Assert().Equal(n.expected, request.Name)
 	}
 }
 
@@ -88,8 +88,8 @@ var testsLimit = []struct {
 func (s *RequestBindingHandlerSuite) TestLimitQuery() {
 	for _, n := range testsLimit {
 		s.req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/authors?%s", n.limit), nil)
-		_, _, limit := BindGetAuthorsRequest(s.req)
-		s.Assert().Equal(n.expected, limit)
+		request := BindGetAuthorsRequest(s.req)
+		s.Assert().Equal(n.expected, request.Limit)
 	}
 }
 
@@ -122,8 +122,8 @@ var testsOffset = []struct {
 func (s *RequestBindingHandlerSuite) TestOffsetQuery() {
 	for _, n := range testsOffset {
 		s.req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/authors?%s", n.offset), nil)
-		_, offset, _ := BindGetAuthorsRequest(s.req)
-		s.Assert().Equal(n.expected, offset)
+		request := BindGetAuthorsRequest(s.req)
+		s.Assert().Equal(n.expected, request.Offset)
 	}
 }
 
@@ -140,17 +140,17 @@ var testsBooksId = []struct {
 	{
 		input:    "",
 		expected: 0,
-		err:      errors.New(erroId),
+		err:      InvalidParameter("id", erroId),
 	},
 	{
 		input:    "-1",
 		expected: 0,
-		err:      errors.New(erroId),
+		err:      InvalidParameter("id", erroId),
 	},
 	{
 		input:    "0",
 		expected: 0,
-		err:      errors.New(erroId),
+		err:      InvalidParameter("id", erroId),
 	},
 }
 
@@ -183,6 +183,22 @@ func (s *RequestBindingHandlerSuite) TestBindGetBooksRequestNameQuery() {
 	}
 }
 
+func (s *RequestBindingHandlerSuite) TestSearchQuery() {
+	for _, n := range testsNome {
+		s.req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/authors?q=%s", n.nome), nil)
+		request := BindGetAuthorsRequest(s.req)
+		s.Assert().Equal(n.expected, request.Query)
+	}
+}
+
+func (s *RequestBindingHandlerSuite) TestBindGetBooksRequestSearchQuery() {
+	for _, n := range testsNome {
+		s.req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/books?q=%s", n.nome), nil)
+		request := BindGetBooksRequest(s.req)
+		s.Assert().Equal(n.expected, request.Query)
+	}
+}
+
 func (s *RequestBindingHandlerSuite) TestBindGetBooksRequestEditionQuery() {
 	for _, n := range testsNome {
 		s.req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/books?edition=%s", n.nome), nil)
@@ -267,12 +283,83 @@ func (s *RequestBindingHandlerSuite) TestBindGetBooksRequestAuthorQuery() {
 	}
 }
 
+var testsExpand = []struct {
+	expand   string
+	expected database.Expand
+}{
+	{
+		expand:   "",
+		expected: database.ExpandNone,
+	},
+	{
+		expand:   "publisher",
+		expected: database.ExpandNone,
+	},
+	{
+		expand:   "authors",
+		expected: database.ExpandAuthors,
+	},
+	{
+		expand:   "authors.books",
+		expected: database.ExpandAuthorsBooks,
+	},
+	{
+		expand:   "authors,authors.books",
+		expected: database.ExpandAuthorsBooks,
+	},
+	{
+		expand:   "authors.books.authors",
+		expected: database.ExpandAuthorsBooks,
+	},
+	{
+		expand:   "authors.publisher",
+		expected: database.ExpandAuthors,
+	},
+}
+
+func (s *RequestBindingHandlerSuite) TestBindGetBooksRequestExpandQuery() {
+	for _, n := range testsExpand {
+		s.req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/books?expand=%s", n.expand), nil)
+		request := BindGetBooksRequest(s.req)
+		s.Assert().Equal(n.expected, request.Expand, n.expand)
+	}
+}
+
+func (s *RequestBindingHandlerSuite) TestBindCursorShouldBeNilIfNotInformed() {
+	s.req = httptest.NewRequest(http.MethodGet, "/books", nil)
+
+	cursor, err := BindCursor(s.req)
+	s.Assert().Nil(cursor)
+	s.Assert().Nil(err)
+}
+
+func (s *RequestBindingHandlerSuite) TestBindCursorShouldParseOpaqueCursor() {
+	expected := database.BookCursor(domain.Book{Name: "Fluent Python"}, nil)
+	expected.ID = 10
+	s.req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/books?after=%s", expected), nil)
+
+	cursor, err := BindCursor(s.req)
+	s.Assert().Equal(&expected, cursor)
+	s.Assert().Nil(err)
+}
+
+func (s *RequestBindingHandlerSuite) TestBindCursorShouldFailIfInvalid() {
+	s.req = httptest.NewRequest(http.MethodGet, "/books?after=notACursor", nil)
+
+	cursor, err := BindCursor(s.req)
+	s.Assert().Nil(cursor)
+	s.Assert().Equal(database.ErrInvalidCursor, err)
+}
+
 func (s *RequestBindingHandlerSuite) TestBindCreateBookRequestBody() {
 
+	author := &domain.Author{}
+	author.ID = 3
 	body := domain.Book{
 		Name:            "Some name",
-		Edition:         "Some edition",
+		Edition:         "2",
 		PublicationYear: 2020,
+		Authors:         []*domain.Author{author},
 	}
 	json, _ := json.Marshal(body)
 	s.req = httptest.NewRequest(http.MethodPost, "/books", bytes.NewBuffer(json))
@@ -283,13 +370,96 @@ func (s *RequestBindingHandlerSuite) TestBindCreateBookRequestBody() {
 	s.Assert().Nil(err)
 }
 
+func (s *RequestBindingHandlerSuite) TestBindCreateBookRequestShouldReportEveryInvalidField() {
+
+	s.req = httptest.NewRequest(http.MethodPost, "/books", bytes.NewBufferString(`{"Name": " ", "Edition": "-1", "PublicationYear": 3000, "Authors": []}`))
+
+	_, err := BindCreateBookRequest(s.req)
+	s.Require().Error(err)
+	s.Assert().Equal(http.StatusUnprocessableEntity, AsError(err).Status)
+	s.Assert().Equal([]FieldError{
+		{Field: "Name", Code: RuleRequired, Message: "Name is required"},
+		{Field: "Edition", Code: RuleRange, Message: "Edition must be at least 1"},
+		{Field: "PublicationYear", Code: RuleNotFuture, Message: "PublicationYear can not be in the future"},
+		{Field: "Authors", Code: RuleRequired, Message: "Authors is required"},
+	}, AsError(err).Fields)
+}
+
+func (s *RequestBindingHandlerSuite) TestBindUpdateBookRequestShouldNotRequireAuthors() {
+
+	s.req = httptest.NewRequest(http.MethodPut, "/books/1", bytes.NewBufferString(`{"Name": " Some name ", "Edition": "2", "PublicationYear": 2020}`))
+
+	book, err := BindUpdateBookRequest(s.req)
+	s.Assert().Nil(err)
+	s.Assert().Equal(domain.Book{Name: "Some name", Edition: "2", PublicationYear: 2020}, book)
+}
+
+func (s *RequestBindingHandlerSuite) TestBindAuthorRequestShouldTrimAndValidateTheName() {
+
+	s.req = httptest.NewRequest(http.MethodPost, "/authors", bytes.NewBufferString(`{"Name": "   "}`))
+
+	_, err := BindAuthorRequest(s.req)
+	s.Require().Error(err)
+	s.Assert().Equal([]FieldError{{Field: "Name", Code: RuleRequired, Message: "Name is required"}}, AsError(err).Fields)
+}
+
 func (s *RequestBindingHandlerSuite) TestBindCreateBookRequestBodyError() {
 
 	s.req = httptest.NewRequest(http.MethodPost, "/books", nil)
 
 	request, err := BindCreateBookRequest(s.req)
 	s.Assert().Equal(domain.Book{}, request)
-	s.Assert().Equal(errors.New("Invalid request payload"), err)
+	s.Assert().Equal(http.StatusBadRequest, AsError(err).Status)
+	s.Assert().Equal(CodeInvalidPayload, AsError(err).Code)
+}
+
+func (s *RequestBindingHandlerSuite) TestBindAuthorPatchShouldTellMissingFields() {
+	s.req = httptest.NewRequest(http.MethodPatch, "/authors/1", bytes.NewBufferString(`{}`))
+
+	patch, err := BindAuthorPatch(s.req)
+	s.Assert().Nil(patch.Name)
+	s.Assert().Nil(err)
+}
+
+func (s *RequestBindingHandlerSuite) TestBindBookPatchShouldKeepTheOtherFields() {
+	s.req = httptest.NewRequest(http.MethodPatch, "/books/1", bytes.NewBufferString(`{"Edition": " 3 "}`))
+	s.req.Header.Set("Content-Type", MergePatchContentType)
+
+	book, err := BindBookPatch(s.req, domain.Book{Name: "Some name", Edition: "2", PublicationYear: 2020, Version: 4})
+	s.Assert().Nil(err)
+	s.Assert().Equal(domain.Book{Name: "Some name", Edition: "3", PublicationYear: 2020}, book)
+}
+
+func (s *RequestBindingHandlerSuite) TestBindBookPatchShouldValidateThePatchedBook() {
+	s.req = httptest.NewRequest(http.MethodPatch, "/books/1", bytes.NewBufferString(`[{"op": "remove", "path": "/Name"}]`))
+	s.req.Header.Set("Content-Type", JSONPatchContentType)
+
+	_, err := BindBookPatch(s.req, domain.Book{Name: "Some name", Edition: "2", PublicationYear: 2020})
+	s.Require().Error(err)
+	s.Assert().Equal([]FieldError{{Field: "Name", Code: RuleRequired, Message: "Name is required"}}, AsError(err).Fields)
+}
+
+func (s *RequestBindingHandlerSuite) TestBindBookPatchShouldRejectOtherFields() {
+	s.req = httptest.NewRequest(http.MethodPatch, "/books/1", bytes.NewBufferString(`{"Version": 7}`))
+	s.req.Header.Set("Content-Type", MergePatchContentType)
+
+	_, err := BindBookPatch(s.req, domain.Book{Name: "Some name", Edition: "2", PublicationYear: 2020})
+	s.Assert().Equal(http.StatusBadRequest, AsError(err).Status)
+}
+
+func (s *RequestBindingHandlerSuite) TestBindCascade() {
+	for query, expected := range map[string]bool{"": false, "?cascade=unlink": true} {
+		s.req = httptest.NewRequest(http.MethodDelete, "/authors/1"+query, nil)
+
+		unlink, err := BindCascade(s.req)
+		s.Assert().Equal(expected, unlink)
+		s.Assert().Nil(err)
+	}
+
+	s.req = httptest.NewRequest(http.MethodDelete, "/authors/1?cascade=delete", nil)
+
+	_, err := BindCascade(s.req)
+	s.Assert().Error(err)
 }
 
 func TestBooksApiHandlerSuite(t *testing.T) {
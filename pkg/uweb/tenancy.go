@@ -0,0 +1,43 @@
+package uweb
+
+import (
+	"net/http"
+
+	"github.com/jedielson/bookstore/pkg/uctx"
+)
+
+const (
+	TenantHeader = "X-Tenant-Id"
+	APIKeyHeader = "X-Api-Key"
+)
+
+// Tenancy carries the tenant of every request in its context. When keys maps
+// API keys to tenants, the tenant is the one of the X-Api-Key header, and
+// requests without a known key fail with 401 Unauthorized. Otherwise the
+// X-Tenant-Id header is trusted, and uctx.DefaultTenant is used without it.
+func Tenancy(keys map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			tenant := r.Header.Get(TenantHeader)
+			if len(keys) > 0 {
+				var ok bool
+				if tenant, ok = keys[r.Header.Get(APIKeyHeader)]; !ok {
+					ToProblem(w, r, NewError(http.StatusUnauthorized, CodeUnauthorized, APIKeyHeader+" is missing or unknown"))
+					return
+				}
+			}
+
+			if len(tenant) == 0 {
+				tenant = uctx.DefaultTenant
+			}
+
+			if !uctx.ValidTenant(tenant) {
+				ToProblem(w, r, NewError(http.StatusBadRequest, CodeInvalidTenant, TenantHeader+" is not a valid tenant"))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(uctx.WithTenant(r.Context(), tenant)))
+		})
+	}
+}
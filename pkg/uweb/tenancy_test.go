@@ -0,0 +1,88 @@
+package uweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jedielson/bookstore/pkg/uctx"
+	"github.com/stretchr/testify/suite"
+)
+
+type TenancySuite struct {
+	suite.Suite
+
+	res    *httptest.ResponseRecorder
+	tenant string
+}
+
+func (s *TenancySuite) SetupTest() {
+	s.res = httptest.NewRecorder()
+	s.tenant = ""
+}
+
+func (s *TenancySuite) serve(keys map[string]string, headers map[string]string) {
+	handler := Tenancy(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.tenant = uctx.Tenant(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/books", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	handler.ServeHTTP(s.res, req)
+}
+
+func (s *TenancySuite) TestShouldUseTenantHeaderWithoutKeys() {
+	// act
+	s.serve(nil, map[string]string{TenantHeader: "library-1"})
+
+	// assert
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal("library-1", s.tenant)
+}
+
+func (s *TenancySuite) TestShouldUseDefaultTenantWithoutHeader() {
+	// act
+	s.serve(nil, nil)
+
+	// assert
+	s.Assert().Equal(uctx.DefaultTenant, s.tenant)
+}
+
+func (s *TenancySuite) TestShouldRejectInvalidTenant() {
+	// act
+	s.serve(nil, map[string]string{TenantHeader: "../other"})
+
+	// assert
+	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
+	s.Assert().Empty(s.tenant)
+}
+
+func (s *TenancySuite) TestShouldResolveTenantFromApiKeyAndIgnoreHeader() {
+	// act
+	s.serve(map[string]string{"secret": "library-1"}, map[string]string{APIKeyHeader: "secret", TenantHeader: "library-2"})
+
+	// assert
+	s.Assert().Equal(http.StatusOK, s.res.Code)
+	s.Assert().Equal("library-1", s.tenant)
+}
+
+func (s *TenancySuite) TestShouldRejectUnknownApiKey() {
+	for _, key := range []string{"", "guess"} {
+		// arrange
+		s.SetupTest()
+
+		// act
+		s.serve(map[string]string{"secret": "library-1"}, map[string]string{APIKeyHeader: key, TenantHeader: "library-1"})
+
+		// assert
+		s.Assert().Equal(http.StatusUnauthorized, s.res.Code)
+		s.Assert().Empty(s.tenant)
+	}
+}
+
+func TestTenancySuite(t *testing.T) {
+	suite.Run(t, new(TenancySuite))
+}
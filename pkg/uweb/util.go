@@ -8,6 +8,8 @@ import (
 	"github.com/gorilla/mux"
 )
 
+const NextCursorHeader = "X-Next-Cursor"
+
 func FromQuery(r *http.Request, key string, defaultValue int, f func(int) bool) int {
 
 	off := r.URL.Query().Get(key)
@@ -30,13 +32,10 @@ func FromPath(r *http.Request, key string, f func(int) bool, erro error) (int, e
 	return 0, erro
 }
 
-func ToJson(w http.ResponseWriter, i interface{}, e ...error) {
+// ToJson writes i as json. Errors are written with ToProblem.
+func ToJson(w http.ResponseWriter, i interface{}) {
 
 	w.Header().Set("Content-Type", "application/json")
-	if len(e) > 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
 
 	bytes, err := json.Marshal(i)
 
@@ -50,3 +49,19 @@ func ToJson(w http.ResponseWriter, i interface{}, e ...error) {
 		return
 	}
 }
+
+// ToCreated writes i as json with 201 and the location of the created
+// resource.
+func ToCreated(w http.ResponseWriter, location string, i interface{}) {
+	w.Header().Set("Location", location)
+	w.Header().Set("Content-Type", "application/json")
+
+	bytes, err := json.Marshal(i)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write(bytes)
+}
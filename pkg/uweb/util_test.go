@@ -2,7 +2,6 @@ package uweb
 
 import (
 	"context"
-	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -34,17 +33,6 @@ func (s *UWebUtilHandlerSuite) TestContentType() {
 	s.Assert().Equal("application/json", header)
 }
 
-func (s *UWebUtilHandlerSuite) TestIfHasErrorsShoudReturn400() {
-
-	// act
-	ToJson(s.res, nil, errors.New("erro"))
-
-	// assert
-	header := s.res.Header().Get("Content-Type")
-	s.Assert().Equal("application/json", header)
-	s.Assert().Equal(http.StatusBadRequest, s.res.Code)
-}
-
 func (s *UWebUtilHandlerSuite) TestIfNotSerializeShouldReturn500() {
 	// act
 	f := func() bool { return false }
@@ -0,0 +1,211 @@
+package uweb
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidateTag declares the rules of a field of a request, separated by commas:
+//
+//	required    the field is not empty
+//	length=m:n  the field has between m and n characters, or elements
+//	range=m:n   the field, a number or a string holding one, is between m and n
+//	notfuture   the field, a year, is not after the current year
+//
+// Either bound of length and range may be left out. Empty fields only break
+// the required rule. Embedded structs are validated as part of their parent and
+// slices of structs element by element.
+const ValidateTag = "validate"
+
+const (
+	RuleRequired  = "required"
+	RuleLength    = "length"
+	RuleRange     = "range"
+	RuleNotFuture = "notfuture"
+)
+
+// Validate checks v, a struct or a pointer to one, against the rules of its
+// fields. It returns a validation failure listing every broken rule, or nil.
+func Validate(v interface{}) error {
+	fields := validateStruct(reflect.Indirect(reflect.ValueOf(v)), "")
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return ValidationFailed(fields...)
+}
+
+func validateStruct(v reflect.Value, prefix string) []FieldError {
+	var errs []FieldError
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+		if field.Anonymous && value.Kind() == reflect.Struct {
+			errs = append(errs, validateStruct(value, prefix)...)
+			continue
+		}
+
+		name := prefix + fieldName(field)
+
+		for _, rule := range strings.Split(field.Tag.Get(ValidateTag), ",") {
+			if len(rule) == 0 {
+				continue
+			}
+
+			if err, ok := checkRule(name, value, rule); !ok {
+				errs = append(errs, err)
+			}
+		}
+
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct {
+			for j := 0; j < value.Len(); j++ {
+				errs = append(errs, validateStruct(value.Index(j), fmt.Sprintf("%s[%d].", name, j))...)
+			}
+		}
+	}
+
+	return errs
+}
+
+func fieldName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; len(name) > 0 && name != "-" {
+		return name
+	}
+
+	return field.Name
+}
+
+func checkRule(name string, value reflect.Value, rule string) (FieldError, bool) {
+	key, arg := rule, ""
+	if i := strings.Index(rule, "="); i >= 0 {
+		key, arg = rule[:i], rule[i+1:]
+	}
+
+	if empty(value) {
+		if key == RuleRequired {
+			return FieldError{Field: name, Code: RuleRequired, Message: name + " is required"}, false
+		}
+		return FieldError{}, true
+	}
+
+	min, max, bounded := bounds(arg)
+
+	switch key {
+	case RuleRequired:
+		// the field is set
+
+	case RuleLength:
+		if !bounded {
+			panic("uweb: invalid length rule " + rule)
+		}
+
+		n, unit := length(value)
+		if (min != nil && n < *min) || (max != nil && n > *max) {
+			return FieldError{Field: name, Code: RuleLength, Message: name + " must have " + between(min, max, unit)}, false
+		}
+
+	case RuleRange:
+		if !bounded {
+			panic("uweb: invalid range rule " + rule)
+		}
+
+		n, ok := number(value)
+		if !ok {
+			return FieldError{Field: name, Code: RuleRange, Message: name + " must be a whole number"}, false
+		}
+
+		if (min != nil && n < *min) || (max != nil && n > *max) {
+			return FieldError{Field: name, Code: RuleRange, Message: name + " must be " + between(min, max, "")}, false
+		}
+
+	case RuleNotFuture:
+		n, ok := number(value)
+		if ok && n > time.Now().Year() {
+			return FieldError{Field: name, Code: RuleNotFuture, Message: name + " can not be in the future"}, false
+		}
+
+	default:
+		panic("uweb: unknown validation rule " + rule)
+	}
+
+	return FieldError{}, true
+}
+
+// bounds parses the m:n argument of a rule.
+func bounds(arg string) (*int, *int, bool) {
+	parts := strings.Split(arg, ":")
+	if len(parts) != 2 {
+		return nil, nil, false
+	}
+
+	var values [2]*int
+	for i, part := range parts {
+		if len(part) == 0 {
+			continue
+		}
+
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, nil, false
+		}
+		values[i] = &n
+	}
+
+	return values[0], values[1], true
+}
+
+func between(min *int, max *int, unit string) string {
+	var text string
+	switch {
+	case min != nil && max != nil:
+		text = fmt.Sprintf("between %d and %d", *min, *max)
+	case min != nil:
+		text = fmt.Sprintf("at least %d", *min)
+	default:
+		text = fmt.Sprintf("at most %d", *max)
+	}
+
+	if len(unit) > 0 {
+		text += " " + unit
+	}
+
+	return text
+}
+
+// empty tells whether value is zero, or a slice without elements.
+func empty(value reflect.Value) bool {
+	if value.Kind() == reflect.Slice {
+		return value.Len() == 0
+	}
+
+	return value.IsZero()
+}
+
+func length(value reflect.Value) (int, string) {
+	if value.Kind() == reflect.String {
+		return utf8.RuneCountInString(value.String()), "characters"
+	}
+
+	return value.Len(), "elements"
+}
+
+// number reads an integer field, or a string field holding one.
+func number(value reflect.Value) (int, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(value.Uint()), true
+	case reflect.String:
+		n, err := strconv.Atoi(value.String())
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
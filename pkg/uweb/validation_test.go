@@ -0,0 +1,85 @@
+package uweb
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ValidationSuite struct {
+	suite.Suite
+}
+
+type validated struct {
+	Code  string      `json:"code" validate:"required,length=2:4"`
+	Count int         `validate:"range=:10"`
+	Year  int         `validate:"notfuture"`
+	Items []validItem `validate:"length=:2"`
+}
+
+type validItem struct {
+	ID uint `validate:"required"`
+}
+
+func (s *ValidationSuite) TestValidShouldPass() {
+	// act
+	err := Validate(validated{Code: "abc", Count: 10, Year: time.Now().Year(), Items: []validItem{{ID: 1}}})
+
+	// assert
+	s.Assert().NoError(err)
+}
+
+func (s *ValidationSuite) TestEmptyFieldsShouldOnlyBreakRequired() {
+	// act
+	err := Validate(&validated{})
+
+	// assert
+	s.Require().Error(err)
+	s.Assert().Equal([]FieldError{{Field: "code", Code: RuleRequired, Message: "code is required"}}, AsError(err).Fields)
+}
+
+func (s *ValidationSuite) TestEveryBrokenRuleShouldBeReported() {
+	// act
+	err := Validate(validated{
+		Code:  strings.Repeat("é", 5),
+		Count: 11,
+		Year:  time.Now().Year() + 1,
+		Items: []validItem{{ID: 1}, {}, {}},
+	})
+
+	// assert
+	s.Require().Error(err)
+	s.Assert().Equal([]FieldError{
+		{Field: "code", Code: RuleLength, Message: "code must have between 2 and 4 characters"},
+		{Field: "Count", Code: RuleRange, Message: "Count must be at most 10"},
+		{Field: "Year", Code: RuleNotFuture, Message: "Year can not be in the future"},
+		{Field: "Items", Code: RuleLength, Message: "Items must have at most 2 elements"},
+		{Field: "Items[1].ID", Code: RuleRequired, Message: "Items[1].ID is required"},
+		{Field: "Items[2].ID", Code: RuleRequired, Message: "Items[2].ID is required"},
+	}, AsError(err).Fields)
+}
+
+func (s *ValidationSuite) TestRangeShouldRejectTextThatIsNotANumber() {
+	// act
+	err := Validate(struct {
+		Edition string `validate:"range=1:"`
+	}{Edition: "first"})
+
+	// assert
+	s.Require().Error(err)
+	s.Assert().Equal("Edition must be a whole number", AsError(err).Fields[0].Message)
+}
+
+func (s *ValidationSuite) TestUnknownRuleShouldPanic() {
+	s.Assert().Panics(func() {
+		_ = Validate(struct {
+			Name string `validate:"unique"`
+		}{Name: "a"})
+	})
+}
+
+func TestValidationSuite(t *testing.T) {
+	suite.Run(t, new(ValidationSuite))
+}